@@ -11,6 +11,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -23,6 +27,8 @@ var configFilePath string
 var checkTestReadiness bool
 var boolVerbose bool
 var boolDebug bool
+var agentAddress string
+var agentsOverride string
 var configOverrides *perfTestUtils.Config
 
 const (
@@ -30,14 +36,42 @@ const (
 	testingMode = 2
 )
 
+// exitInterrupted is the exit code of a test run stopped early by a signal,
+// exitAborted that of a test run stopped by one of its abort criteria.
+const (
+	exitInterrupted = 3
+	exitAborted     = 4
+)
+
 //----- main ------------------------------------------------------------------
 func main() {
 	log.Debugf("[START]")
 
 	initConfig(os.Args[1:], osFileSystem, os.Exit)
 
+	// An agent runs the test runs sent by a controller.
+	if agentAddress != "" {
+		if err := testStrategies.ServeAgent(agentAddress, configurationSettings.AgentToken); err != nil {
+			log.Error("Agent failed. Error: ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	//Validate config()
 	configurationSettings.PrintAndValidateConfig()
+	if _, err := configurationSettings.TLS.ClientConfig(); err != nil {
+		log.Error("Invalid TLS settings. Error: ", err)
+		os.Exit(1)
+	}
+	// The agents only accept the test runs of a controller with their token.
+	if len(configurationSettings.Agents) > 0 && configurationSettings.AgentToken == "" {
+		log.Error("No agent token is set. Set agentToken, or -agentToken, to the token of the agents.")
+		os.Exit(1)
+	}
+
+	// Stop the test run gracefully on SIGINT/SIGTERM.
+	handleSignals(os.Exit)
 
 	//Generate a test suite based on configuration settings
 	testSuite := new(testStrategies.TestSuite)
@@ -105,6 +139,7 @@ func initConfig(args []string, fs perfTestUtils.FileSystem, exit func(code int))
 	// Global controls outside of Config struct:
 	flag.StringVar(&configFilePath, "configFilePath", "", "The location of the configuration file.")
 	flag.BoolVar(&checkTestReadiness, "checkTestReadiness", false, "Simple check to see if system requires training.")
+	flag.StringVar(&agentAddress, "agent", "", "Run as an agent of a distributed test run, listening on the given address, eg. :9090.")
 
 	// Log level simplified for the end user.
 	flag.BoolVar(&boolVerbose, "v", false, "Set logging verbosity to 'info' from default of 'warn'. Use -vv for debug.")
@@ -120,6 +155,7 @@ func initConfig(args []string, fs perfTestUtils.FileSystem, exit func(code int))
 	flag.StringVar(&configOverrides.APIName, "apiName", "", "Uniqe name of the test scenario. Do not use spaces. (Default_API_NAME)")
 	flag.StringVar(&configOverrides.TargetHost, "host", "", "Remote target host for service calls. (localhost)")
 	flag.StringVar(&configOverrides.TargetPort, "port", "", "Remote target port for service calls. (8080)")
+	flag.StringVar(&configOverrides.TargetScheme, "scheme", "", "Remote target scheme for service calls: http or https. (http)")
 	flag.IntVar(&configOverrides.NumIterations, "i", 0, "Number of iterations. (1000)")
 	flag.Float64Var(&configOverrides.AllowablePeakMemoryVariance, "allowedMemVar", 0.0, "Allowed peak memory variance percent. (15)")
 	flag.Float64Var(&configOverrides.AllowableServiceResponseTimeVariance, "allowedTimeVar", 0.0, "Allowed response time variance percent. (15)")
@@ -131,10 +167,20 @@ func initConfig(args []string, fs perfTestUtils.FileSystem, exit func(code int))
 	flag.StringVar(&configOverrides.TestSuite, "ts", "", "Name of testSuite definition file. [Optional. Leave off for service-based test runs.]")
 	flag.StringVar(&configOverrides.MemoryEndpoint, "mem", "", "Override endpoint in URL for memory metrics. (/debug/vars)")
 	flag.IntVar(&configOverrides.RequestDelay, "d", 0, "Delay between calls in ms. (1)")
+	flag.StringVar(&configOverrides.ThinkTime, "thinkTime", "", "Think time before each call in ms, as a distribution such as normal(500,100), instead of the delay between calls. ()")
 	flag.IntVar(&configOverrides.TPSFreq, "tps", 0, "Delay between TPS reporting log events in sec. (30)")
 	flag.IntVar(&configOverrides.RampUsers, "ru", 0, "Number of users/threads to batch for ramp up. (0)")
 	flag.IntVar(&configOverrides.RampDelay, "rd", 0, "Seconds between user/thread batches for ramp up. (15)")
 	flag.BoolVar(&configOverrides.SkipMemCheck, "skipMemCheck", false, "Skip the Peak Memory check and the final report. (false)")
+	flag.BoolVar(&configOverrides.BaseTTFB, "baseTTFB", false, "Also store the time to first byte of each service in the base statistics of a training run. (false)")
+	flag.StringVar(&configOverrides.Executor, "executor", "", "Load executor: ClosedModel or ArrivalRate. (ClosedModel)")
+	flag.IntVar(&configOverrides.TargetRate, "rate", 0, "Target iterations per second for the ArrivalRate executor. (10)")
+	flag.IntVar(&configOverrides.MaxVirtualUsers, "maxUsers", 0, "Maximum virtual users the ArrivalRate executor may grow to. (100)")
+	flag.StringVar(&configOverrides.Duration, "duration", "", "Run until a wall-clock duration has elapsed, eg. 30m or 1h, instead of for a number of iterations. ()")
+	flag.StringVar(&configOverrides.Pacing.Period, "pacing", "", "Pacing period of the iterations of each user of a suite based test, eg. 10s. ()")
+	flag.StringVar(&configOverrides.Pacing.Mode, "pacingMode", "", "Pacing mode: interval, an iteration every period, or minimum, iterations of at least the period. (interval)")
+	flag.StringVar(&agentsOverride, "agents", "", "Comma separated host:port addresses of the agents to run a suite based test on, instead of running it locally. ()")
+	flag.StringVar(&configOverrides.AgentToken, "agentToken", "", "Shared token the agents require from the controller. Set the same value on the agents and the controller. ()")
 
 	// Parse the args!
 	flag.CommandLine.Parse(args)
@@ -173,6 +219,37 @@ func initConfig(args []string, fs perfTestUtils.FileSystem, exit func(code int))
 	overrideConfigOpts()
 }
 
+//----- handleSignals ---------------------------------------------------------
+// On the first SIGINT or SIGTERM, stop starting new iterations and let the
+// run finish with the results collected so far, waiting up to
+// config.GracePeriod for requests in progress. A second signal exits
+// immediately.
+func handleSignals(exit func(code int)) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Warnf("Received [%v]. Stopping the test run. Waiting up to [%v] for requests in progress. Signal again to exit immediately.",
+			sig,
+			configurationSettings.GracePeriodDuration(),
+		)
+		testStrategies.StopTestRun(fmt.Sprintf("interrupted by %v", sig), configurationSettings.GracePeriodDuration())
+
+		sig = <-signals
+		log.Errorf("Received [%v] again. Exiting immediately without results.", sig)
+		exit(exitInterrupted)
+	}()
+}
+
+//----- stoppedExitCode -------------------------------------------------------
+// Exit code of a test run that stopped early.
+func stoppedExitCode(perfStatsForTest *perfTestUtils.PerfStats) int {
+	if perfStatsForTest.AbortReason != "" {
+		return exitAborted
+	}
+	return exitInterrupted
+}
+
 //----- setLogLevel -----------------------------------------------------------
 // Set log level using a simplified interface for end user. See "Process
 // command line args" in initConfig().
@@ -202,6 +279,9 @@ func overrideConfigOpts() {
 	if configOverrides.TargetPort != "" {
 		configurationSettings.TargetPort = configOverrides.TargetPort
 	}
+	if configOverrides.TargetScheme != "" {
+		configurationSettings.TargetScheme = configOverrides.TargetScheme
+	}
 	if configOverrides.NumIterations != 0 {
 		configurationSettings.NumIterations = configOverrides.NumIterations
 	}
@@ -235,6 +315,9 @@ func overrideConfigOpts() {
 	if configOverrides.RequestDelay != 0 {
 		configurationSettings.RequestDelay = configOverrides.RequestDelay
 	}
+	if configOverrides.ThinkTime != "" {
+		configurationSettings.ThinkTime = configOverrides.ThinkTime
+	}
 	if configOverrides.TPSFreq != 0 {
 		configurationSettings.TPSFreq = configOverrides.TPSFreq
 	}
@@ -247,12 +330,42 @@ func overrideConfigOpts() {
 	if configOverrides.SkipMemCheck {
 		configurationSettings.SkipMemCheck = true
 	}
+	if configOverrides.BaseTTFB {
+		configurationSettings.BaseTTFB = true
+	}
+	if configOverrides.Executor != "" {
+		configurationSettings.Executor = configOverrides.Executor
+	}
+	if configOverrides.TargetRate != 0 {
+		configurationSettings.TargetRate = configOverrides.TargetRate
+	}
+	if configOverrides.MaxVirtualUsers != 0 {
+		configurationSettings.MaxVirtualUsers = configOverrides.MaxVirtualUsers
+	}
+	if configOverrides.Duration != "" {
+		configurationSettings.Duration = configOverrides.Duration
+	}
+	if configOverrides.Pacing.Period != "" {
+		configurationSettings.Pacing.Period = configOverrides.Pacing.Period
+	}
+	if configOverrides.Pacing.Mode != "" {
+		configurationSettings.Pacing.Mode = configOverrides.Pacing.Mode
+	}
+	if agentsOverride != "" {
+		configurationSettings.Agents = strings.Split(agentsOverride, ",")
+	}
+	if configOverrides.AgentToken != "" {
+		configurationSettings.AgentToken = configOverrides.AgentToken
+	}
 }
 
 //----- runInTrainingMode -----------------------------------------------------
 func runInTrainingMode(host string, reBaseAll bool, testSuite *testStrategies.TestSuite) {
 	log.Info("Running performance test in Training mode for host ", host)
 
+	// The setup of the suite is not part of the test run.
+	testStrategies.RunSetups(testSuite, configurationSettings)
+
 	// Start test timer.
 	scenarioTimeStart := time.Now()
 
@@ -262,6 +375,8 @@ func runInTrainingMode(host string, reBaseAll bool, testSuite *testStrategies.Te
 		ServiceResponseTimes: make(map[string]int64),
 		ServiceTransCount:    make(map[string]*uint64),
 		ServiceErrorCount:    make(map[string]*uint64),
+		ServiceTimeoutCount:  make(map[string]*uint64),
+		ServiceRetryCount:    make(map[string]*uint64),
 		ServiceTPS:           make(map[string]float64),
 	}
 
@@ -284,6 +399,13 @@ func runInTrainingMode(host string, reBaseAll bool, testSuite *testStrategies.Te
 	runTests(perfStatsForTest, trainingMode, testSuite, scenarioTimeStart)
 	scenarioTimeElapsed := time.Since(scenarioTimeStart)
 	perfStatsForTest.TestTimeEnd = time.Now()
+	testStrategies.RunTeardowns(testSuite, configurationSettings)
+
+	// Base statistics from a partial run would skew every later test run.
+	if stopReason := testStrategies.TestRunStopReason(); stopReason != "" {
+		log.Errorf("Training mode stopped early (%s). Base statistics were not written.", stopReason)
+		os.Exit(stoppedExitCode(perfStatsForTest))
+	}
 
 	//Generate base statistics output file for this training run.
 	perfTestUtils.GenerateEnvBasePerfOutputFile(perfStatsForTest, basePerfstats, configurationSettings, os.Exit, osFileSystem)
@@ -303,9 +425,11 @@ func runInTestingMode(
 
 	// Start test timer. This will give us a basis for all TPS calculations,
 	// and will enable the engineer to:
-	//     o  Adjust config.NumIterations to control the overall length of the
-	//        test run.
+	//     o  Adjust config.NumIterations, or set config.Duration, to control
+	//        the overall length of the test run.
 	//     o  Set config.ConcurrentUsers to adjust load (see documentation).
+	// The setup and teardown of the suite are outside of the timer.
+	testStrategies.RunSetups(testSuite, configurationSettings)
 	scenarioTimeStart := time.Now()
 
 	// Initialize performance statistics struct.
@@ -314,6 +438,8 @@ func runInTestingMode(
 		ServiceResponseTimes: make(map[string]int64),
 		ServiceTransCount:    make(map[string]*uint64),
 		ServiceErrorCount:    make(map[string]*uint64),
+		ServiceTimeoutCount:  make(map[string]*uint64),
+		ServiceRetryCount:    make(map[string]*uint64),
 		ServiceTPS:           make(map[string]float64),
 	}
 
@@ -323,6 +449,10 @@ func runInTestingMode(
 	// Stop the timer. See comment on scenarioTimeStart above.
 	scenarioTimeElapsed := time.Since(scenarioTimeStart)
 	perfStatsForTest.TestTimeEnd = time.Now()
+	testStrategies.RunTeardowns(testSuite, configurationSettings)
+
+	// A stopped run reports on the data collected until it stopped.
+	perfStatsForTest.StopReason = testStrategies.TestRunStopReason()
 
 	// Save overall TPS.
 	perfStatsForTest.OverAllTPS = perfTestUtils.CalcTps(perfStatsForTest.OverAllTransCount, scenarioTimeElapsed)
@@ -337,6 +467,11 @@ func runInTestingMode(
 		)
 	}
 
+	// Save per-workload TPS.
+	for _, workloadStats := range perfStatsForTest.Workloads {
+		workloadStats.TPS = perfTestUtils.CalcTps(workloadStats.TransCount, scenarioTimeElapsed)
+	}
+
 	// Validate test results
 	assertionFailures := runAssertions(basePerfstats, perfStatsForTest)
 
@@ -345,6 +480,9 @@ func runInTestingMode(
 
 	// Print test results to std out at log level "INFO".
 	log.Info("=================== TEST RESULTS ===================")
+	if perfStatsForTest.StopReason != "" {
+		log.Warnf("PARTIAL RESULTS: the test run stopped early (%s).", perfStatsForTest.StopReason)
+	}
 	if len(assertionFailures) > 0 {
 		log.Info("Number of Failures : ", len(assertionFailures))
 		for _, failure := range assertionFailures {
@@ -357,8 +495,81 @@ func runInTestingMode(
 	log.Infof("Scenario Time:   [%v]", scenarioTimeElapsed)
 	log.Infof("Overall Trans:   [%d]", perfStatsForTest.OverAllTransCount)
 	log.Infof("Overall TPS:     [%f]", perfStatsForTest.OverAllTPS)
+	if testSuite.TestStrategy == testStrategies.SuiteBasedTesting {
+		log.Infof("Iterations:      [%d]", perfStatsForTest.IterationCount)
+		if perfStatsForTest.PacedIterations > 0 {
+			log.Infof("Overrun Iters:   [%d] Pacing=[%s]", perfStatsForTest.OverrunIterations, configurationSettings.Pacing.String())
+		}
+	}
+	log.Infof("Avg Think Time:  [%.2fms]", perfStatsForTest.GetAverageThinkTime())
+	for _, workloadStats := range perfStatsForTest.Workloads {
+		log.Infof("Workload:        [%s] Users=[%d] Iterations=[%d] Trans=[%d] Errors=[%d] TPS=[%f]",
+			workloadStats.Name,
+			workloadStats.ConcurrentUsers,
+			workloadStats.IterationCount,
+			workloadStats.TransCount,
+			workloadStats.ErrorCount,
+			workloadStats.TPS,
+		)
+	}
+	if configurationSettings.Executor == perfTestUtils.ArrivalRateExecutor {
+		log.Infof("Target Rate:     [%d]", configurationSettings.TargetRate)
+		log.Infof("Dropped Iters:   [%d]", perfStatsForTest.DroppedIterations)
+		log.Infof("Late Iters:      [%d]", perfStatsForTest.LateIterations)
+		for serviceName, correctedResponseTime := range perfStatsForTest.ServiceCorrectedResponseTimes {
+			log.Infof("Corrected Time:  [%s] Test=[%v] Corrected=[%v]",
+				serviceName,
+				time.Duration(perfStatsForTest.ServiceResponseTimes[serviceName]),
+				time.Duration(correctedResponseTime),
+			)
+		}
+	}
+	for serviceName, phaseTimes := range perfStatsForTest.ServicePhaseTimes {
+		log.Infof("Phases:          [%s] DNS=[%.3fms] Connect=[%.3fms] TLS=[%.3fms] TTFB=[%.3fms] Download=[%.3fms]",
+			serviceName,
+			phaseTimes.Average(phaseTimes.DNS),
+			phaseTimes.Average(phaseTimes.Connect),
+			phaseTimes.Average(phaseTimes.TLS),
+			phaseTimes.Average(phaseTimes.TTFB),
+			phaseTimes.Average(phaseTimes.Download),
+		)
+	}
+	for _, operationStats := range perfStatsForTest.GetOperationStats() {
+		log.Infof("GraphQL Op:      [%s] TestNames=[%s] Avg=[%.3fms] Trans=[%d] Errors=[%d]",
+			operationStats.Name,
+			strings.Join(operationStats.Services, ","),
+			float64(operationStats.ResponseTime)/float64(time.Millisecond),
+			operationStats.TransCount,
+			operationStats.ErrorCount,
+		)
+	}
+	for transactionName, transactionResponseTime := range perfStatsForTest.TransactionResponseTimes {
+		log.Infof("Transaction:     [%s] Avg=[%v] Trans=[%d] Errors=[%d]",
+			transactionName,
+			time.Duration(transactionResponseTime),
+			*perfStatsForTest.TransactionTransCount[transactionName],
+			*perfStatsForTest.TransactionErrorCount[transactionName],
+		)
+	}
+	for serviceName, failures := range perfStatsForTest.ServiceAssertionFailures {
+		for assertionName, count := range failures {
+			log.Infof("Assertion:       [%s] [%s] Failures=[%d]", serviceName, assertionName, *count)
+		}
+	}
+	for stepName, stepStats := range perfStatsForTest.StepStats {
+		log.Infof("Suite Step:      [%s] Type=[%s] Count=[%d] Passes=[%d] Timeouts=[%d]",
+			stepName,
+			stepStats.Type,
+			stepStats.Count,
+			stepStats.Passes,
+			stepStats.Timeouts,
+		)
+	}
 	log.Info("=====================================================")
 
+	if perfStatsForTest.StopReason != "" {
+		os.Exit(stoppedExitCode(perfStatsForTest))
+	}
 	if len(assertionFailures) > 0 {
 		os.Exit(1)
 	}
@@ -373,6 +584,7 @@ func runTests(perfStatsForTest *perfTestUtils.PerfStats, mode int, testSuite *te
 	// Initialize Memory analysis.
 	var peakMemoryAllocation = new(uint64)
 	memoryAudit := make([]uint64, 0)
+	memorySampleTimes := make([]time.Time, 0)
 	testPartitions := make([]perfTestUtils.TestPartition, 0)
 	counter := 0
 	testPartitions = append(testPartitions, perfTestUtils.TestPartition{Count: counter, TestName: "StartUp"})
@@ -380,16 +592,26 @@ func runTests(perfStatsForTest *perfTestUtils.PerfStats, mode int, testSuite *te
 	// 1. Start go routine to grab memory in use.
 	// Peak memory is stored in peakMemoryAllocation variable.
 	// Ignore if the skipMemCheck config option has been set to true.
+	// The go routine closes memoryPollerDone when it exits, after which the
+	// memory samples can be read.
 	chanQuitPkMem := make(chan bool)
-	if !configurationSettings.SkipMemCheck {
+	memoryPollerDone := make(chan struct{})
+	if configurationSettings.SkipMemCheck {
+		close(memoryPollerDone)
+	} else {
+		// The memory endpoint is polled over the same scheme and TLS
+		// settings as the service calls.
+		tlsConfig, _ := configurationSettings.TLS.ClientConfig()
+		memoryClient := &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}}
 		go func() {
+			defer close(memoryPollerDone)
 			for {
 				select {
 				case <-chanQuitPkMem:
 					return
 				default:
-					memoryStatsURL := "http://" + configurationSettings.TargetHost + ":" + configurationSettings.TargetPort + configurationSettings.MemoryEndpoint
-					resp, err := http.Get(memoryStatsURL)
+					memoryStatsURL := configurationSettings.TargetScheme + "://" + configurationSettings.TargetHost + ":" + configurationSettings.TargetPort + configurationSettings.MemoryEndpoint
+					resp, err := memoryClient.Get(memoryStatsURL)
 					if err != nil {
 						log.Error("Memory analysis unavailable. Failed to retrieve memory Statistics from endpoint ", memoryStatsURL, ". Error: ", err)
 						return
@@ -402,10 +624,11 @@ func runTests(perfStatsForTest *perfTestUtils.PerfStats, mode int, testSuite *te
 							log.Error("Memory analysis unavailable. Failed to unmarshal memory statistics from endpoint: ", memoryStatsURL, ". UnmarsahlErr: ", unmarshalErr)
 							return
 						} else {
-							if m.Memstats.Alloc > *peakMemoryAllocation {
-								*peakMemoryAllocation = m.Memstats.Alloc
+							if m.Memstats.Alloc > atomic.LoadUint64(peakMemoryAllocation) {
+								atomic.StoreUint64(peakMemoryAllocation, m.Memstats.Alloc)
 							}
 							memoryAudit = append(memoryAudit, m.Memstats.Alloc)
+							memorySampleTimes = append(memorySampleTimes, time.Now())
 							counter++
 							time.Sleep(time.Millisecond * 200)
 						}
@@ -419,6 +642,9 @@ func runTests(perfStatsForTest *perfTestUtils.PerfStats, mode int, testSuite *te
 	// some initial memory data before test cases are executed.
 	time.Sleep(time.Second * 1)
 
+	// Watch the abort criteria, if any, for as long as the tests run.
+	stopAbortMonitor := testStrategies.StartAbortMonitor(configurationSettings, perfStatsForTest, peakMemoryAllocation)
+
 	// 2. Execute tests based on strategy defaulting to ServiceBasedTesting.
 	if testSuite.TestStrategy == testStrategies.SuiteBasedTesting {
 		// SuiteBasedTesting strategy runs service requests in the order
@@ -426,16 +652,43 @@ func runTests(perfStatsForTest *perfTestUtils.PerfStats, mode int, testSuite *te
 		// definition is run concurrently across the number of threads defined
 		// by the config.ConcurrentUsers value. Each thread runs the scenario
 		// for config.NumIterations number of times. Usually used for capacity
-		// and longevity test runs against a live back end.
+		// and longevity test runs against a live back end. When
+		// config.LoadProfile is set, the load follows its stages instead.
+		// When config.Workloads is set, the suite of every workload runs at
+		// the same time with the settings of its workload.
 		log.Info("Running Suite Based Testing Strategy. Suite Name: [", testSuite.Name, "]")
 
-		// Execute the suite.
-		allServicesResponseTimesMap := testStrategies.ExecuteTestSuiteWrapper(
-			testSuite,
-			configurationSettings,
-			perfStatsForTest,
-			scenarioTimeStart,
-		)
+		// Execute the suite, or all suites of a mixed workload at once. With
+		// agents, every agent executes it, and memory is still polled here.
+		var allServicesResponseTimesMap map[string][]int64
+		if len(configurationSettings.Agents) > 0 {
+			var err error
+			allServicesResponseTimesMap, err = testStrategies.ExecuteOnAgents(
+				testSuite,
+				configurationSettings,
+				perfStatsForTest,
+				mode,
+			)
+			if err != nil {
+				log.Error("Distributed test run failed. Error: ", err)
+				os.Exit(1)
+			}
+		} else if len(testSuite.Workloads) > 0 {
+			allServicesResponseTimesMap = testStrategies.ExecuteWorkloads(
+				testSuite,
+				configurationSettings,
+				perfStatsForTest,
+				scenarioTimeStart,
+				mode,
+			)
+		} else {
+			allServicesResponseTimesMap = testStrategies.ExecuteTestSuiteWrapper(
+				testSuite,
+				configurationSettings,
+				perfStatsForTest,
+				scenarioTimeStart,
+			)
+		}
 
 		// Collate the service-level response time data.
 		for serviceName, serviceResponseTimes := range allServicesResponseTimesMap {
@@ -455,23 +708,60 @@ func runTests(perfStatsForTest *perfTestUtils.PerfStats, mode int, testSuite *te
 		// number of times in parallel across the number of threads defined by
 		// the config.ConcurrentUsers value. Usually used with mock calls.
 		log.Info("Running Service Based Testing Strategy")
+		if len(configurationSettings.Agents) > 0 {
+			log.Warn("Agents only run suite based tests. Running the service based test locally.")
+		}
+		if configurationSettings.Pacing.Period != "" {
+			log.Warn("Pacing only applies to suite based tests. Ignoring pacing.")
+		}
 
 		// Determine load per concurrent user.
 		loadPerUser := int(configurationSettings.NumIterations / configurationSettings.ConcurrentUsers)
 		remainder := configurationSettings.NumIterations % configurationSettings.ConcurrentUsers
 
+		// Duration based runs split the run time evenly across the test
+		// cases, which run one after the other.
+		runDuration := configurationSettings.RunDuration()
+		serviceTestDuration := time.Duration(0)
+		if runDuration > 0 && len(testSuite.TestDefinitions) > 0 {
+			serviceTestDuration = runDuration / time.Duration(len(testSuite.TestDefinitions))
+		}
+
 		// Set the overall TransCount, which will subsequently be used to
-		// calculate OverallTPS (see runInTestingMode() above).
-		perfStatsForTest.OverAllTransCount = uint64(len(testSuite.TestCases) * configurationSettings.NumIterations)
+		// calculate OverallTPS (see runInTestingMode() above). The
+		// ArrivalRate executor and duration based runs count the requests
+		// they actually send instead, as the number is not known up front.
+		if configurationSettings.Executor != perfTestUtils.ArrivalRateExecutor && runDuration == 0 {
+			perfStatsForTest.OverAllTransCount = uint64(len(testSuite.TestCases) * configurationSettings.NumIterations)
+		}
 
-		log.Infof("ServiceBasedTesting loadPerUser=[%d] remainder=[%d]", loadPerUser, remainder)
+		if runDuration > 0 {
+			log.Infof("ServiceBasedTesting duration per test case=[%v]", serviceTestDuration)
+		} else {
+			log.Infof("ServiceBasedTesting loadPerUser=[%d] remainder=[%d]", loadPerUser, remainder)
+		}
 
 		var index int
 		var testDefinition *testStrategies.TestDefinition
 		for index, testDefinition = range testSuite.TestDefinitions {
+			if testStrategies.TestRunStopReason() != "" {
+				// The remaining test cases do not run once stopped.
+				break
+			}
 			log.Infof("Running Test case [%d] [Name:%s]", index, testDefinition.TestName)
 			testPartitions = append(testPartitions, perfTestUtils.TestPartition{Count: counter, TestName: testDefinition.TestName})
-			averageResponseTime := testStrategies.ExecuteServiceTest(testDefinition, loadPerUser, remainder, configurationSettings, mode)
+			var deadline time.Time
+			if serviceTestDuration > 0 {
+				deadline = time.Now().Add(serviceTestDuration)
+			}
+			var averageResponseTime int64
+			if configurationSettings.Executor == perfTestUtils.ArrivalRateExecutor {
+				averageResponseTime = testStrategies.ExecuteServiceTestAtArrivalRate(testDefinition, deadline, configurationSettings, perfStatsForTest, mode)
+			} else if serviceTestDuration > 0 {
+				averageResponseTime = testStrategies.ExecuteServiceTestForDuration(testDefinition, deadline, configurationSettings, perfStatsForTest, mode)
+			} else {
+				averageResponseTime = testStrategies.ExecuteServiceTest(testDefinition, loadPerUser, remainder, configurationSettings, perfStatsForTest, mode)
+			}
 
 			if averageResponseTime > 0 {
 				perfStatsForTest.ServiceResponseTimes[testDefinition.TestName] = averageResponseTime
@@ -485,15 +775,36 @@ func runTests(perfStatsForTest *perfTestUtils.PerfStats, mode int, testSuite *te
 		}
 	}
 
+	// Collate the response times of scheduled requests measured from their
+	// intended send time. Only the ArrivalRate executor and interval pacing
+	// schedule requests.
+	for serviceName, correctedResponseTimes := range testStrategies.CorrectedSamples(perfStatsForTest) {
+		if perfStatsForTest.ServiceCorrectedResponseTimes == nil {
+			perfStatsForTest.ServiceCorrectedResponseTimes = make(map[string]int64)
+		}
+		perfStatsForTest.ServiceCorrectedResponseTimes[serviceName] = perfTestUtils.CalcAverageResponseTime(correctedResponseTimes, mode)
+	}
+
+	// Collate the response times of the transactions of the suite, if any.
+	for transactionName, transactionResponseTimes := range testStrategies.TransactionSamples(perfStatsForTest) {
+		if perfStatsForTest.TransactionResponseTimes == nil {
+			perfStatsForTest.TransactionResponseTimes = make(map[string]int64)
+		}
+		perfStatsForTest.TransactionResponseTimes[transactionName] = perfTestUtils.CalcAverageResponseTime(transactionResponseTimes, mode)
+	}
+
 	// Kill the peak memory thread to avoid race condition when saving metrics.
+	stopAbortMonitor()
 	close(chanQuitPkMem)
+	<-memoryPollerDone
 
 	if !configurationSettings.SkipMemCheck {
 		// Save the peak memory metrics:
-		perfStatsForTest.PeakMemory = *peakMemoryAllocation
+		perfStatsForTest.PeakMemory = atomic.LoadUint64(peakMemoryAllocation)
 		perfStatsForTest.MemoryAudit = memoryAudit
-		perfStatsForTest.TestPartitions = testPartitions
 	}
+	// The load profile stages are marked among the services, in order.
+	perfStatsForTest.TestPartitions = perfTestUtils.SortTestPartitions(append(testPartitions, perfTestUtils.LoadStagePartitions(perfStatsForTest.LoadStageStarts, memorySampleTimes)...))
 }
 
 //----- runAssertions ---------------------------------------------------------
@@ -524,5 +835,23 @@ func runAssertions(basePerfstats *perfTestUtils.BasePerfStats, perfStats *perfTe
 			assertionFailures = append(assertionFailures, fmt.Sprintf("Service Failure: Service test %-60s response time variance exceeded by %3.2f %1s", serviceName, responseTimeVariancePercentage, "%"))
 		}
 	}
+
+	//Asserts transaction response times are within their threshold, or have not exceeded the allowable variance
+	for transactionName, averageTransactionResponseTime := range perfStats.TransactionResponseTimes {
+		baseResponseTime := basePerfstats.BaseTransactionResponseTimes[transactionName]
+		threshold := perfStats.TransactionThresholds[transactionName]
+		if perfTestUtils.ValidateTransactionResponseTime(configurationSettings.AllowableServiceResponseTimeVariance, averageTransactionResponseTime, baseResponseTime, threshold) {
+			continue
+		}
+		switch {
+		case averageTransactionResponseTime == 0:
+			assertionFailures = append(assertionFailures, fmt.Sprintf("Transaction Failure: Transaction %-60s did not execute correctly. See logs for more details.", transactionName))
+		case threshold > 0:
+			assertionFailures = append(assertionFailures, fmt.Sprintf("Transaction Failure: Transaction %-60s response time %v exceeded threshold %v", transactionName, time.Duration(averageTransactionResponseTime), time.Duration(threshold)))
+		default:
+			responseTimeVariancePercentage := perfTestUtils.CalcAverageResponseVariancePercentage(averageTransactionResponseTime, baseResponseTime)
+			assertionFailures = append(assertionFailures, fmt.Sprintf("Transaction Failure: Transaction %-60s response time variance exceeded by %3.2f %1s", transactionName, responseTimeVariancePercentage, "%"))
+		}
+	}
 	return assertionFailures
 }
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"github.com/xtracdev/automated-perf-test/testStrategies"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 const (
@@ -110,6 +114,7 @@ func TestOverrideConfig(t *testing.T) {
 	configOverrides.APIName = "1"
 	configOverrides.TargetHost = "2"
 	configOverrides.TargetPort = "3"
+	configOverrides.TargetScheme = "https"
 	configOverrides.NumIterations = 4
 	configOverrides.AllowablePeakMemoryVariance = 5.0
 	configOverrides.AllowableServiceResponseTimeVariance = 6.0
@@ -121,15 +126,25 @@ func TestOverrideConfig(t *testing.T) {
 	configOverrides.TestSuite = "12"
 	configOverrides.MemoryEndpoint = "13"
 	configOverrides.RequestDelay = 14
+	configOverrides.ThinkTime = "normal(500,100)"
 	configOverrides.TPSFreq = 15
 	configOverrides.RampUsers = 16
 	configOverrides.RampDelay = 17
+	configOverrides.Executor = "18"
+	configOverrides.TargetRate = 19
+	configOverrides.MaxVirtualUsers = 20
+	configOverrides.Duration = "21m"
+	agentsOverride = "agent1:9090,agent2:9090"
+	configOverrides.AgentToken = "secret"
+	configOverrides.Pacing = perfTestUtils.Pacing{Mode: "minimum", Period: "10s"}
+	configOverrides.BaseTTFB = true
 
 	overrideConfigOpts()
 
 	assert.Equal(t,"1" , configurationSettings.APIName)
 	assert.Equal(t,"2" , configurationSettings.TargetHost)
 	assert.Equal(t,"3" , configurationSettings.TargetPort)
+	assert.Equal(t,"https", configurationSettings.TargetScheme)
 	assert.Equal(t,4   , configurationSettings.NumIterations)
 	assert.Equal(t,5.0 , configurationSettings.AllowablePeakMemoryVariance)
 	assert.Equal(t,6.0 , configurationSettings.AllowableServiceResponseTimeVariance)
@@ -141,9 +156,18 @@ func TestOverrideConfig(t *testing.T) {
 	assert.Equal(t,"12", configurationSettings.TestSuite)
 	assert.Equal(t,"13", configurationSettings.MemoryEndpoint)
 	assert.Equal(t,14  , configurationSettings.RequestDelay)
+	assert.Equal(t,"normal(500,100)", configurationSettings.ThinkTime)
 	assert.Equal(t,15  , configurationSettings.TPSFreq)
 	assert.Equal(t,16  , configurationSettings.RampUsers)
 	assert.Equal(t,17  , configurationSettings.RampDelay)
+	assert.Equal(t,"18", configurationSettings.Executor)
+	assert.Equal(t,19  , configurationSettings.TargetRate)
+	assert.Equal(t,20  , configurationSettings.MaxVirtualUsers)
+	assert.Equal(t,"21m", configurationSettings.Duration)
+	assert.Equal(t,[]string{"agent1:9090", "agent2:9090"}, configurationSettings.Agents)
+	assert.Equal(t,"secret", configurationSettings.AgentToken)
+	assert.Equal(t,perfTestUtils.Pacing{Mode: "minimum", Period: "10s"}, configurationSettings.Pacing)
+	assert.True(t, configurationSettings.BaseTTFB)
 }
 
 func TestInitConfigFileNotFound(t *testing.T) {
@@ -202,5 +226,43 @@ func TestRunAssertions(t *testing.T) {
 	toTest := runAssertions(bs, ps)
 	t.Logf("%v\n", toTest)
 	assert.Equal(t, 3, len(toTest))
+
+	// Transactions are asserted against their threshold, or their base time.
+	bs.BaseServiceResponseTimes = map[string]int64{"s2": 20}
+	bs.BaseTransactionResponseTimes = map[string]int64{"checkout": 100, "browse": 100}
+	ps.PeakMemory = 100
+	ps.TransactionResponseTimes = map[string]int64{"checkout": 200, "browse": 110, "pay": 0, "search": 90}
+	ps.TransactionThresholds = map[string]int64{"checkout": 250, "search": 80}
+	toTest = runAssertions(bs, ps)
+	t.Logf("%v\n", toTest)
+	assert.Equal(t, 2, len(toTest))
+	assert.Contains(t, strings.Join(toTest, "\n"), "pay")
+	assert.Contains(t, strings.Join(toTest, "\n"), "search")
 }
 
+
+func TestHandleSignals(t *testing.T) {
+	configurationSettings = new(perfTestUtils.Config)
+	configurationSettings.SetDefaults()
+	configurationSettings.GracePeriod = "10ms"
+
+	exitCodes := make(chan int, 1)
+	handleSignals(func(code int) { exitCodes <- code })
+	defer signal.Reset(os.Interrupt, syscall.SIGTERM)
+
+	// The first signal stops the test run ...
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+	for i := 0; i < 100 && testStrategies.TestRunStopReason() == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "interrupted by interrupt", testStrategies.TestRunStopReason())
+
+	// ... the second one exits immediately.
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	select {
+	case code := <-exitCodes:
+		assert.Equal(t, exitInterrupted, code)
+	case <-time.After(time.Second):
+		t.Error("Expected an immediate exit on the second signal")
+	}
+}
@@ -7,8 +7,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 type perfStatsModel struct {
@@ -39,9 +40,25 @@ func (p *perfStatsModel) IsTimePass() bool {
 			return false
 		}
 	}
+	for k := range p.PerfStats.TransactionResponseTimes {
+		if !p.IsTransactionTimePass(k) {
+			return false
+		}
+	}
 	return true
 }
 
+// IsTransactionTimePass returns true if the transaction is within its
+// threshold, or within the allowed variance of its base time.
+func (p *perfStatsModel) IsTransactionTimePass(t string) bool {
+	return ValidateTransactionResponseTime(
+		p.Config.AllowableServiceResponseTimeVariance,
+		p.PerfStats.TransactionResponseTimes[t],
+		p.BasePerfStats.BaseTransactionResponseTimes[t],
+		p.PerfStats.TransactionThresholds[t],
+	)
+}
+
 func (p *perfStatsModel) PeakMemoryVariancePercentage() float64 {
 	return float64(CalcPeakMemoryVariancePercentage(p.BasePerfStats.BasePeakMemory, p.PerfStats.PeakMemory))
 }
@@ -78,10 +95,12 @@ func (p *perfStatsModel) JSONTestPartitions() template.JS {
 
 // JSONTimeArray returns series data for the chart in json format suitable
 // to be inserted as javascript within <script> tags. The array is alpha
-// sorted by service name.
+// sorted by service name. Runs with scheduled requests have a third series
+// of corrected response times.
 func (p *perfStatsModel) JSONTimeArray() template.JS {
 	serviceResponseTimesBase := []byte("['Base',")
 	serviceResponseTimesTest := []byte("['Test',")
+	serviceResponseTimesCorrected := []byte("['Corrected',")
 	serviceNames := []byte("['")
 
 	// Sort the keys to get consistent charts between data runs for
@@ -106,6 +125,10 @@ func (p *perfStatsModel) JSONTimeArray() template.JS {
 		serviceResponseTimesTest = append(serviceResponseTimesTest, []byte(strconv.FormatFloat(testTimeMillis, 'f', 3, 64))...)
 		serviceResponseTimesTest = append(serviceResponseTimesTest, []byte(",")...)
 
+		correctedTimeMillis := float64(float32(p.PerfStats.ServiceCorrectedResponseTimes[name]) / float32(1000000))
+		serviceResponseTimesCorrected = append(serviceResponseTimesCorrected, []byte(strconv.FormatFloat(correctedTimeMillis, 'f', 3, 64))...)
+		serviceResponseTimesCorrected = append(serviceResponseTimesCorrected, []byte(",")...)
+
 		if averageServiceResponseTime != 0 {
 			responseTimeVariancePercentage := CalcAverageResponseVariancePercentage(averageServiceResponseTime, p.BasePerfStats.BaseServiceResponseTimes[name])
 			serviceNames = append(serviceNames, []byte(name+" ("+fmt.Sprintf("%3.2f", responseTimeVariancePercentage)+" %)")...)
@@ -120,9 +143,100 @@ func (p *perfStatsModel) JSONTimeArray() template.JS {
 	p.JSONTimeServiceNames = template.JS(serviceNames)
 
 	serviceResponseTimesBase = append(serviceResponseTimesBase, serviceResponseTimesTest...)
+	if len(p.PerfStats.ServiceCorrectedResponseTimes) > 0 {
+		serviceResponseTimesBase = append(serviceResponseTimesBase, []byte(",")...)
+		serviceResponseTimesBase = append(serviceResponseTimesBase, serviceResponseTimesCorrected...)
+		serviceResponseTimesBase = append(serviceResponseTimesBase, []byte("]")...)
+	}
 	return template.JS(serviceResponseTimesBase)
 }
 
+// requestPhases are the phases of the request phase chart, in stacking
+// order.
+var requestPhases = []string{"DNS", "Connect", "TLS", "TTFB", "Download"}
+
+// phaseServiceNames returns the names of the services with phase times,
+// alpha sorted.
+func (p *perfStatsModel) phaseServiceNames() []string {
+	names := make([]string, 0, len(p.PerfStats.ServicePhaseTimes))
+	for name := range p.PerfStats.ServicePhaseTimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JSONPhaseArray returns a series of average times in milliseconds for each
+// request phase, suitable to be inserted as javascript for a stacked chart
+// of the services in JSONPhaseServiceNames order.
+func (p *perfStatsModel) JSONPhaseArray() template.JS {
+	series := make([]byte, 0)
+	for i, phase := range requestPhases {
+		if i > 0 {
+			series = append(series, []byte(",")...)
+		}
+		series = append(series, []byte("['"+phase+"',")...)
+		for _, name := range p.phaseServiceNames() {
+			pt := p.PerfStats.ServicePhaseTimes[name]
+			total := []uint64{pt.DNS, pt.Connect, pt.TLS, pt.TTFB, pt.Download}[i]
+			series = append(series, []byte(strconv.FormatFloat(pt.Average(total), 'f', 3, 64))...)
+			series = append(series, []byte(",")...)
+		}
+		series = append(series, []byte("]")...)
+	}
+	return template.JS(series)
+}
+
+// JSONPhaseGroups returns the request phases as a single group of stacked
+// series.
+func (p *perfStatsModel) JSONPhaseGroups() template.JS {
+	return template.JS("[['" + strings.Join(requestPhases, "','") + "']]")
+}
+
+// JSONPhaseServiceNames returns the categories of the request phase chart.
+func (p *perfStatsModel) JSONPhaseServiceNames() template.JS {
+	return template.JS("['" + strings.Join(p.phaseServiceNames(), "','") + "']")
+}
+
+// transactionNames returns the names of the transactions of the run, alpha
+// sorted.
+func (p *perfStatsModel) transactionNames() []string {
+	names := make([]string, 0, len(p.PerfStats.TransactionResponseTimes))
+	for name := range p.PerfStats.TransactionResponseTimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JSONTransactionArray returns the base and test series of the transaction
+// chart, and a threshold series if any transaction has a threshold, in
+// milliseconds in JSONTransactionNames order.
+func (p *perfStatsModel) JSONTransactionArray() template.JS {
+	base := []byte("['Base',")
+	test := []byte("['Test',")
+	threshold := []byte("['Threshold',")
+	for _, name := range p.transactionNames() {
+		base = append(base, []byte(strconv.FormatFloat(div(p.BasePerfStats.BaseTransactionResponseTimes[name], 1e6), 'f', 3, 64)+",")...)
+		test = append(test, []byte(strconv.FormatFloat(div(p.PerfStats.TransactionResponseTimes[name], 1e6), 'f', 3, 64)+",")...)
+		threshold = append(threshold, []byte(strconv.FormatFloat(div(p.PerfStats.TransactionThresholds[name], 1e6), 'f', 3, 64)+",")...)
+	}
+	series := append(base, []byte("],")...)
+	series = append(series, test...)
+	series = append(series, []byte("]")...)
+	if len(p.PerfStats.TransactionThresholds) > 0 {
+		series = append(series, []byte(",")...)
+		series = append(series, threshold...)
+		series = append(series, []byte("]")...)
+	}
+	return template.JS(series)
+}
+
+// JSONTransactionNames returns the categories of the transaction chart.
+func (p *perfStatsModel) JSONTransactionNames() template.JS {
+	return template.JS("['" + strings.Join(p.transactionNames(), "','") + "']")
+}
+
 // GenerateTemplateReport wraps the generateTemplate() function that creates
 // the final performance report html.
 func GenerateTemplateReport(basePerfstats *BasePerfStats, perfStats *PerfStats, configurationSettings *Config, fs FileSystem, testSuiteName string, testStrategy string) {
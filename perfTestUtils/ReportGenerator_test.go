@@ -1,6 +1,7 @@
 package perfTestUtils
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	"os"
@@ -201,3 +202,363 @@ func TestGenerateTemplateReportErrorCreate(t *testing.T) {
 	}
 	GenerateTemplateReport(bs, ps, c, mockedFs, "TestSuiteName", "ServiceBased")
 }
+
+func TestGenerateTemplateBuiltinWorkloads(t *testing.T) {
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"search": 2e6},
+	}
+	browseCount := uint64(40)
+	browseErrors := uint64(1)
+	browse := NewWorkloadStats("browse", "browse.xml", 80)
+	browse.IterationCount = 20
+	browse.TransCount = 40
+	browse.ServiceResponseTimes["search"] = 2e6
+	browse.ServiceTransCount["search"] = &browseCount
+	browse.ServiceErrorCount["search"] = &browseErrors
+	browse.ServiceConfiguredMix["search"] = 2
+	ps.Workloads = []*WorkloadStats{browse, NewWorkloadStats("admin", "admin.xml", 5)}
+
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 2e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Workload Analysis")
+	assert.Contains(t, report.String(), "<b>browse</b> (browse.xml)")
+	assert.Contains(t, report.String(), "<b>admin</b> (admin.xml)")
+	assert.Contains(t, report.String(), "200.0% / 200.0%")
+}
+
+func TestGenerateTemplateBuiltinPartial(t *testing.T) {
+	ps := &PerfStats{TestTimeStart: time.Now(), StopReason: "interrupted by terminated"}
+	bs := &BasePerfStats{}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "PARTIAL RESULTS: the test run stopped early (interrupted by terminated)")
+}
+
+func TestGenerateTemplateBuiltinAborted(t *testing.T) {
+	ps := &PerfStats{
+		TestTimeStart: time.Now(),
+		StopReason:    "aborted: peak memory above 512MB (peak memory was 600MB)",
+		AbortReason:   "peak memory above 512MB (peak memory was 600MB)",
+	}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+	c.AbortCriteria = []AbortCriterion{{Metric: AbortOnPeakMemory, Threshold: 512}}
+
+	var report bytes.Buffer
+	err := generateTemplate(&BasePerfStats{}, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Abort criteria: peak memory above 512MB")
+	assert.Contains(t, report.String(), "TRIGGERED: peak memory above 512MB (peak memory was 600MB)")
+}
+
+func TestGenerateTemplateBuiltinTimeouts(t *testing.T) {
+	searchCount := uint64(10)
+	searchErrors := uint64(3)
+	searchTimeouts := uint64(2)
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"search": 2e6},
+		ServiceTransCount:    map[string]*uint64{"search": &searchCount},
+		ServiceErrorCount:    map[string]*uint64{"search": &searchErrors},
+		ServiceTimeoutCount:  map[string]*uint64{"search": &searchTimeouts},
+		OverAllErrorCount:    3,
+		OverAllTimeoutCount:  2,
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 2e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Errors [3] Timeouts [2]")
+	assert.Contains(t, report.String(), "3 (2 timeouts)")
+}
+
+func TestGenerateTemplateBuiltinRetries(t *testing.T) {
+	searchRetries := uint64(4)
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"search": 2e6, "checkout": 3e6},
+		ServiceRetryCount:    map[string]*uint64{"search": &searchRetries},
+		OverAllRetryCount:    4,
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 2e6, "checkout": 3e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Retries [4]")
+	assert.Contains(t, report.String(), "<td>4</td>")
+
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "ServiceBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Timeouts [0] Retries [4]")
+}
+
+func TestGenerateTemplateBuiltinThinkTime(t *testing.T) {
+	thinkTime, thinkCount := uint64(1500*time.Millisecond), uint64(3)
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"search": 2e6},
+		ServiceThinkTime:     map[string]*uint64{"search": &thinkTime},
+		ServiceThinkCount:    map[string]*uint64{"search": &thinkCount},
+		OverAllThinkTime:     thinkTime,
+		OverAllThinkCount:    thinkCount,
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 2e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+	c.ThinkTime = "exponential(500)"
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Think time: exponential(500) ms")
+	assert.Contains(t, report.String(), "average 500.00 ms per request")
+	assert.Contains(t, report.String(), "Think Time [500.00]")
+	assert.Contains(t, report.String(), "<td>500.00</td>")
+}
+
+func TestGenerateTemplateBuiltinPacing(t *testing.T) {
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"search": 2e6},
+		PacedIterations:      40,
+		OverrunIterations:    3,
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 2e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+	c.Pacing = Pacing{Mode: PacingInterval, Period: "10s"}
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Pacing: an iteration every 10s per user")
+	assert.Contains(t, report.String(), "3 of 40 iterations overran their pacing window")
+
+	report.Reset()
+	ps.OverrunIterations = 0
+	err = generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "no iteration overran its pacing window")
+}
+
+func TestGenerateTemplateBuiltinPhases(t *testing.T) {
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"search": 2e6, "checkout": 2e6},
+		ServicePhaseTimes: map[string]*PhaseTimes{
+			"search":   {Count: 2, Connect: 2e6, TTFB: 8e6, Download: 1e6},
+			"checkout": {Count: 1, TTFB: 5e6},
+		},
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 2e6, "checkout": 2e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Request Phase Analysis")
+	assert.Contains(t, report.String(), "<td>4.000</td>")
+	assert.Contains(t, report.String(), "['TTFB',5.000,4.000,]")
+	assert.Contains(t, report.String(), "categories: ['checkout','search']")
+	assert.NotContains(t, report.String(), "Base TTFB")
+
+	// The base TTFB is shown when the base file has it.
+	bs.BaseServiceTTFB = map[string]int64{"search": 3e6}
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Base TTFB (Milli)")
+
+	// Without phase times there is no phase analysis.
+	ps.ServicePhaseTimes = nil
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.NotContains(t, report.String(), "Request Phase Analysis")
+}
+
+func TestGenerateTemplateBuiltinOperations(t *testing.T) {
+	transCount, errorCount := uint64(4), uint64(1)
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"getOrder": 12e6, "login": 2e6},
+		ServiceTransCount:    map[string]*uint64{"getOrder": &transCount},
+		ServiceErrorCount:    map[string]*uint64{"getOrder": &errorCount},
+		ServiceOperations:    map[string]string{"getOrder": "GetOrder"},
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"getOrder": 12e6, "login": 2e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "GraphQL Operation Analysis")
+	assert.Contains(t, report.String(), "<td>GetOrder</td>")
+	assert.Contains(t, report.String(), "<td>getOrder</td>")
+	assert.Contains(t, report.String(), "<td>12.000</td>")
+	assert.Contains(t, report.String(), `<td style="color:red">1</td>`)
+
+	// Runs without GraphQL test cases have no operation analysis.
+	ps.ServiceOperations = nil
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.NotContains(t, report.String(), "GraphQL Operation Analysis")
+}
+
+func TestGenerateTemplateBuiltinTransactions(t *testing.T) {
+	transCount, errorCount := uint64(10), uint64(2)
+	ps := &PerfStats{
+		TestTimeStart:            time.Now(),
+		ServiceResponseTimes:     map[string]int64{"cart": 2e6},
+		TransactionResponseTimes: map[string]int64{"checkout": 9e6, "browse": 4e6},
+		TransactionTransCount:    map[string]*uint64{"checkout": &transCount, "browse": &transCount},
+		TransactionErrorCount:    map[string]*uint64{"checkout": &errorCount},
+		TransactionThresholds:    map[string]int64{"checkout": 8e6},
+	}
+	bs := &BasePerfStats{
+		BaseServiceResponseTimes:     map[string]int64{"cart": 2e6},
+		BaseTransactionResponseTimes: map[string]int64{"checkout": 9e6, "browse": 4e6},
+	}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Transaction Response Time Analysis")
+	assert.Contains(t, report.String(), "Think time excluded")
+	assert.Contains(t, report.String(), "['Base',4.000,9.000,],['Test',4.000,9.000,],['Threshold',0.000,8.000,]")
+	assert.Contains(t, report.String(), "categories: ['browse','checkout']")
+	assert.Contains(t, report.String(), `<td style="color:red">9.000</td>`)
+	assert.Contains(t, report.String(), `<td style="color:red">2</td>`)
+
+	// The checkout transaction is over its threshold, so the run fails.
+	model := &perfStatsModel{BasePerfStats: bs, PerfStats: ps, Config: c}
+	assert.False(t, model.IsTimePass())
+	assert.True(t, model.IsTransactionTimePass("browse"))
+	ps.TransactionThresholds = nil
+	assert.True(t, model.IsTimePass())
+
+	// Runs without transactions have no transaction analysis.
+	ps.TransactionResponseTimes = nil
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.NotContains(t, report.String(), "Transaction Response Time Analysis")
+}
+
+func TestGenerateTemplateBuiltinSteps(t *testing.T) {
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"getOrder": 12e6},
+		StepStats: map[string]*StepStats{
+			"waitForOrder": {Type: "until", Count: 4, Passes: 10, Timeouts: 1},
+			"repeat 1-3":   {Type: "repeat", Count: 4, Passes: 8},
+		},
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"getOrder": 12e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Suite Step Analysis")
+	assert.Contains(t, report.String(), "<td>waitForOrder</td>")
+	assert.Contains(t, report.String(), "<td>2.500</td>")
+	assert.Contains(t, report.String(), `<td style="color:red">1</td>`)
+	assert.Contains(t, report.String(), "<td>2.000</td>")
+
+	// Suites without control flow steps have no step analysis.
+	ps.StepStats = nil
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.NotContains(t, report.String(), "Suite Step Analysis")
+}
+
+func TestGenerateTemplateBuiltinAssertions(t *testing.T) {
+	count := func(n uint64) *uint64 { return &n }
+	ps := &PerfStats{
+		TestTimeStart:        time.Now(),
+		ServiceResponseTimes: map[string]int64{"search": 12e6},
+		ServiceAssertionFailures: map[string]map[string]*uint64{
+			"search": {"jmesPath items exists": count(3), "contentType application/json": count(1)},
+		},
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 12e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Assertion Failure Analysis")
+	assert.Contains(t, report.String(), "<td>jmesPath items exists</td>")
+	assert.Contains(t, report.String(), `<td style="color:red">3</td>`)
+
+	// Runs without assertion failures have no assertion analysis.
+	ps.ServiceAssertionFailures = nil
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "SuiteBased")
+	assert.Nil(t, err)
+	assert.NotContains(t, report.String(), "Assertion Failure Analysis")
+}
+
+func TestGenerateTemplateBuiltinCorrectedResponseTimes(t *testing.T) {
+	ps := &PerfStats{
+		TestTimeStart:                 time.Now(),
+		ServiceResponseTimes:          map[string]int64{"search": 2e6},
+		ServiceCorrectedResponseTimes: map[string]int64{"search": 250e6},
+	}
+	bs := &BasePerfStats{BaseServiceResponseTimes: map[string]int64{"search": 2e6}}
+	c := &Config{}
+	c.SetDefaults()
+	c.SkipMemCheck = true
+
+	var report bytes.Buffer
+	err := generateTemplate(bs, ps, c, &report, "", "ServiceBased")
+	assert.Nil(t, err)
+	assert.Contains(t, report.String(), "Corrected TestTime (Milli)")
+	assert.Contains(t, report.String(), "<td>250.000</td>")
+	assert.Contains(t, report.String(), "['Corrected',250.000,]")
+
+	// Runs without a schedule have no corrected response times.
+	ps.ServiceCorrectedResponseTimes = nil
+	report.Reset()
+	err = generateTemplate(bs, ps, c, &report, "", "ServiceBased")
+	assert.Nil(t, err)
+	assert.NotContains(t, report.String(), "Corrected")
+}
@@ -1,9 +1,14 @@
 package perfTestUtils
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"runtime"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -13,6 +18,7 @@ const (
 	defaultAPIName                              = "Default_API_NAME"
 	defaultTargetHost                           = "localhost"
 	defaultTargetPort                           = "8080"
+	defaultTargetScheme                         = "http"
 	defaultNumIterations                        = 1000
 	defaultAllowablePeakMemoryVariance          = float64(15)
 	defaultAllowableServiceResponseTimeVariance = float64(15)
@@ -28,29 +34,149 @@ const (
 	defaultRampUsers                            = 0
 	defaultRampDelay                            = 10
 	defaultSkipMemCheck                         = false
+	defaultExecutor                             = ClosedModelExecutor
+	defaultTargetRate                           = 10
+	defaultMaxVirtualUsers                      = 100
+	defaultDuration                             = ""
+	defaultGracePeriod                          = "30s"
+	defaultAbortWindow                          = "30s"
+	defaultAbortMinRequests                     = 20
+	defaultConnectTimeout                       = "30s"
+	defaultTLSHandshakeTimeout                  = "10s"
+	defaultResponseHeaderTimeout                = "0s"
+	defaultRequestTimeout                       = "60s"
+	defaultMaxIdleConnsPerHost                  = 100
+	defaultConnectionPool                       = SharedConnectionPool
+	defaultCookieScope                          = IterationCookieScope
+	defaultTransactionThinkTime                 = ExcludeThinkTime
+	defaultAuthRefreshBefore                    = "30s"
+	defaultJWTAlgorithm                         = "HS256"
+	defaultJWTLifetime                          = "5m"
+)
+
+// LoadStageRamp, LoadStageStep, LoadStageHold and LoadStageSpike are the valid
+// shapes of a LoadStage. A ramp moves linearly from the level of the previous
+// stage to its target over the stage duration. A step jumps straight to its
+// target. A hold keeps the level of the previous stage, or moves to its target
+// if one is given. A spike jumps to its target for the stage duration, after
+// which the next stage carries on from the level before the spike.
+const (
+	LoadStageRamp  = "ramp"
+	LoadStageStep  = "step"
+	LoadStageHold  = "hold"
+	LoadStageSpike = "spike"
+)
+
+// ClosedModelExecutor and ArrivalRateExecutor are the valid values of
+// Config.Executor. The closed model runs a fixed pool of ConcurrentUsers,
+// each firing its next request only when the previous one has returned. The
+// arrival rate (open model) executor starts iterations at a fixed TargetRate
+// per second, growing the pool of virtual users up to MaxVirtualUsers as
+// needed to hold that rate.
+const (
+	ClosedModelExecutor = "ClosedModel"
+	ArrivalRateExecutor = "ArrivalRate"
+)
+
+// AbortOnErrorRate, AbortOnP95 and AbortOnPeakMemory are the metrics an
+// AbortCriterion can watch. The threshold is a percentage of failed requests,
+// a 95th percentile response time in milliseconds, or a peak memory in MB.
+const (
+	AbortOnErrorRate  = "errorRate"
+	AbortOnP95        = "p95"
+	AbortOnPeakMemory = "peakMemory"
+)
+
+// SharedConnectionPool and PerUserConnectionPool are the valid values of
+// HTTPClientConfig.ConnectionPool. A shared pool lets all virtual users reuse
+// the same connections. A per user pool gives each virtual user connections
+// of its own, the way separate clients would connect.
+const (
+	SharedConnectionPool  = "shared"
+	PerUserConnectionPool = "perUser"
+)
+
+// IterationCookieScope and UserCookieScope are the valid values of
+// Config.CookieScope. Each virtual user of a suite based run has a cookie
+// jar of its own, which is emptied at the start of every iteration, or kept
+// for the whole lifetime of the virtual user.
+const (
+	IterationCookieScope = "iteration"
+	UserCookieScope      = "user"
+)
+
+// ExcludeThinkTime and IncludeThinkTime are the valid values of
+// Config.TransactionThinkTime. The time of a transaction excluding think time
+// is the sum of the response times of its test cases. Including think time,
+// it is the time from the start of the transaction to its end.
+const (
+	ExcludeThinkTime = "exclude"
+	IncludeThinkTime = "include"
+)
+
+// PacingInterval and PacingMinimum are the valid modes of Pacing. Interval
+// pacing starts an iteration of each virtual user every period, on a fixed
+// schedule. Minimum pacing makes each iteration take at least the period.
+// Either way the user waits out whatever is left of the period once its
+// iteration is done.
+const (
+	PacingInterval = "interval"
+	PacingMinimum  = "minimum"
+)
+
+// AuthBasic, AuthOAuth2ClientCredentials, AuthJWT and AuthHMAC are the valid
+// types of an AuthProvider. Basic sends a username and password. OAuth2
+// client credentials fetches a bearer token from a token endpoint, shared by
+// all virtual users and refreshed before it expires. JWT sends a bearer token
+// generated and signed locally. HMAC signs every request with a shared
+// secret.
+const (
+	AuthBasic                   = "basic"
+	AuthOAuth2ClientCredentials = "oauth2ClientCredentials"
+	AuthJWT                     = "jwt"
+	AuthHMAC                    = "hmac"
 )
 
 // Config struct contains all values set by the config.xml file. Most, if not
 // all, can be overridden from command line.
 type Config struct {
-	APIName                              string  `xml:"apiName"`
-	TargetHost                           string  `xml:"targetHost"`
-	TargetPort                           string  `xml:"targetPort"`
-	NumIterations                        int     `xml:"numIterations"`
-	AllowablePeakMemoryVariance          float64 `xml:"allowablePeakMemoryVariance"`
-	AllowableServiceResponseTimeVariance float64 `xml:"allowableServiceResponseTimeVariance"`
-	TestCaseDir                          string  `xml:"testCaseDir"`
-	TestSuiteDir                         string  `xml:"testSuiteDir"`
-	BaseStatsOutputDir                   string  `xml:"baseStatsOutputDir"`
-	ReportOutputDir                      string  `xml:"reportOutputDir"`
-	ConcurrentUsers                      int     `xml:"concurrentUsers"`
-	TestSuite                            string  `xml:"testSuite"`
-	MemoryEndpoint                       string  `xml:"memoryEndpoint"`
-	RequestDelay                         int     `xml:"requestDelay"`
-	TPSFreq                              int     `xml:"TPSFreq"`
-	RampUsers                            int     `xml:"rampUsers"`
-	RampDelay                            int     `xml:"rampDelay"`
-	SkipMemCheck                         bool    `xml:"skipMemCheck"`
+	APIName                              string           `xml:"apiName"`
+	TargetHost                           string           `xml:"targetHost"`
+	TargetPort                           string           `xml:"targetPort"`
+	TargetScheme                         string           `xml:"targetScheme"`
+	NumIterations                        int              `xml:"numIterations"`
+	AllowablePeakMemoryVariance          float64          `xml:"allowablePeakMemoryVariance"`
+	AllowableServiceResponseTimeVariance float64          `xml:"allowableServiceResponseTimeVariance"`
+	TestCaseDir                          string           `xml:"testCaseDir"`
+	TestSuiteDir                         string           `xml:"testSuiteDir"`
+	BaseStatsOutputDir                   string           `xml:"baseStatsOutputDir"`
+	ReportOutputDir                      string           `xml:"reportOutputDir"`
+	ConcurrentUsers                      int              `xml:"concurrentUsers"`
+	TestSuite                            string           `xml:"testSuite"`
+	MemoryEndpoint                       string           `xml:"memoryEndpoint"`
+	RequestDelay                         int              `xml:"requestDelay"`
+	ThinkTime                            string           `xml:"thinkTime"`
+	TPSFreq                              int              `xml:"TPSFreq"`
+	RampUsers                            int              `xml:"rampUsers"`
+	RampDelay                            int              `xml:"rampDelay"`
+	SkipMemCheck                         bool             `xml:"skipMemCheck"`
+	BaseTTFB                             bool             `xml:"baseTTFB"`
+	Executor                             string           `xml:"executor"`
+	TargetRate                           int              `xml:"targetRate"`
+	MaxVirtualUsers                      int              `xml:"maxVirtualUsers"`
+	Duration                             string           `xml:"duration"`
+	LoadProfile                          []LoadStage      `xml:"loadProfile>stage"`
+	Workloads                            []Workload       `xml:"workloads>workload"`
+	Pacing                               Pacing           `xml:"pacing"`
+	GracePeriod                          string           `xml:"gracePeriod"`
+	AbortCriteria                        []AbortCriterion `xml:"abortCriteria>criterion"`
+	HTTPClient                           HTTPClientConfig `xml:"httpClient"`
+	TLS                                  TLSConfig        `xml:"tls"`
+	CookieScope                          string           `xml:"cookieScope"`
+	TransactionThinkTime                 string           `xml:"transactionThinkTime"`
+	AuthProviders                        []AuthProvider   `xml:"authProviders>provider"`
+	Agents                               []string         `xml:"agents>agent"`
+	AgentToken                           string           `xml:"agentToken"`
 
 	//These value can only be set by command line arguments as they control each training and test run.
 	GBS          bool
@@ -69,6 +195,7 @@ func (c *Config) SetDefaults() {
 	c.APIName = defaultAPIName
 	c.TargetHost = defaultTargetHost
 	c.TargetPort = defaultTargetPort
+	c.TargetScheme = defaultTargetScheme
 	c.NumIterations = defaultNumIterations
 	c.AllowablePeakMemoryVariance = defaultAllowablePeakMemoryVariance
 	c.AllowableServiceResponseTimeVariance = defaultAllowableServiceResponseTimeVariance
@@ -84,6 +211,21 @@ func (c *Config) SetDefaults() {
 	c.RampUsers = defaultRampUsers
 	c.RampDelay = defaultRampDelay
 	c.SkipMemCheck = defaultSkipMemCheck
+	c.Executor = defaultExecutor
+	c.TargetRate = defaultTargetRate
+	c.MaxVirtualUsers = defaultMaxVirtualUsers
+	c.Duration = defaultDuration
+	c.GracePeriod = defaultGracePeriod
+	c.HTTPClient = HTTPClientConfig{
+		ConnectTimeout:        defaultConnectTimeout,
+		TLSHandshakeTimeout:   defaultTLSHandshakeTimeout,
+		ResponseHeaderTimeout: defaultResponseHeaderTimeout,
+		RequestTimeout:        defaultRequestTimeout,
+		MaxIdleConnsPerHost:   defaultMaxIdleConnsPerHost,
+		ConnectionPool:        defaultConnectionPool,
+	}
+	c.CookieScope = defaultCookieScope
+	c.TransactionThinkTime = defaultTransactionThinkTime
 
 	c.GBS = false
 	c.ReBaseMemory = false
@@ -102,6 +244,13 @@ func (c *Config) PrintAndValidateConfig() {
 	if strings.TrimSpace(c.TargetPort) == "" {
 		c.TargetPort = defaultTargetPort
 	}
+	c.TargetScheme = strings.ToLower(strings.TrimSpace(c.TargetScheme))
+	if c.TargetScheme != "http" && c.TargetScheme != "https" {
+		if c.TargetScheme != "" {
+			log.Warnf("Invalid targetScheme [%s]. Falling back to %s.", c.TargetScheme, defaultTargetScheme)
+		}
+		c.TargetScheme = defaultTargetScheme
+	}
 	if c.NumIterations < 1 {
 		c.NumIterations = defaultNumIterations
 	}
@@ -129,6 +278,10 @@ func (c *Config) PrintAndValidateConfig() {
 	if c.RequestDelay < 1 {
 		c.RequestDelay = defaultRequestDelay
 	}
+	if _, err := ParseThinkTime(c.ThinkTime); err != nil {
+		log.Warnf("%v. Falling back to the requestDelay.", err)
+		c.ThinkTime = ""
+	}
 	if c.TPSFreq < 1 {
 		c.TPSFreq = defaultTPSFreq
 	}
@@ -141,12 +294,207 @@ func (c *Config) PrintAndValidateConfig() {
 	if c.SkipMemCheck != false && c.SkipMemCheck != true {
 		c.SkipMemCheck = defaultSkipMemCheck
 	}
+	if c.Executor != ClosedModelExecutor && c.Executor != ArrivalRateExecutor {
+		c.Executor = defaultExecutor
+	}
+	if c.TargetRate < 1 {
+		c.TargetRate = defaultTargetRate
+	}
+	if c.MaxVirtualUsers < 1 {
+		c.MaxVirtualUsers = defaultMaxVirtualUsers
+	}
+	if c.MaxVirtualUsers < c.ConcurrentUsers {
+		c.MaxVirtualUsers = c.ConcurrentUsers
+	}
+	if c.Duration != "" && c.RunDuration() == 0 {
+		log.Warnf("Invalid duration [%s]. Falling back to numIterations.", c.Duration)
+		c.Duration = defaultDuration
+	}
+	if c.Pacing.Period != "" || c.Pacing.Mode != "" {
+		if c.Pacing.PeriodDuration() == 0 {
+			log.Warnf("Invalid pacing period [%s]. Iterations will not be paced.", c.Pacing.Period)
+			c.Pacing = Pacing{}
+		} else if c.Pacing.Mode != PacingInterval && c.Pacing.Mode != PacingMinimum {
+			if c.Pacing.Mode != "" {
+				log.Warnf("Invalid pacing mode [%s]. Falling back to %s.", c.Pacing.Mode, PacingInterval)
+			}
+			c.Pacing.Mode = PacingInterval
+		}
+	}
+	if c.Executor == ArrivalRateExecutor && c.Pacing.Period != "" {
+		log.Warn("The ArrivalRate executor starts iterations at the targetRate. Ignoring pacing.")
+		c.Pacing = Pacing{}
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(c.GracePeriod)); err != nil || d < 0 {
+		log.Warnf("Invalid gracePeriod [%s]. Falling back to %s.", c.GracePeriod, defaultGracePeriod)
+		c.GracePeriod = defaultGracePeriod
+	}
+	validStages := make([]LoadStage, 0)
+	for i, stage := range c.LoadProfile {
+		if strings.TrimSpace(stage.Shape) == "" {
+			stage.Shape = LoadStageRamp
+		}
+		if stage.Shape != LoadStageRamp && stage.Shape != LoadStageStep && stage.Shape != LoadStageHold && stage.Shape != LoadStageSpike {
+			log.Warnf("Ignoring load profile stage [%d]: unknown shape [%s].", i, stage.Shape)
+			continue
+		}
+		if stage.StageDuration() == 0 {
+			log.Warnf("Ignoring load profile stage [%d]: invalid duration [%s].", i, stage.Duration)
+			continue
+		}
+		if stage.Users < 0 || stage.Rate < 0 {
+			log.Warnf("Ignoring load profile stage [%d]: negative target.", i)
+			continue
+		}
+		validStages = append(validStages, stage)
+	}
+	// A hold without a target keeps the level of the stages before it, which
+	// is filled in so that the stage shows the level it holds.
+	level := 0
+	for i := range validStages {
+		stage := &validStages[i]
+		target := &stage.Users
+		if c.Executor == ArrivalRateExecutor {
+			target = &stage.Rate
+		}
+		switch stage.Shape {
+		case LoadStageRamp, LoadStageStep:
+			level = *target
+		case LoadStageHold:
+			if *target == 0 {
+				*target = level
+			}
+			level = *target
+		}
+	}
+	c.LoadProfile = validStages
+	validWorkloads := make([]Workload, 0)
+	workloadNames := make(map[string]bool)
+	for i, workload := range c.Workloads {
+		workload.TestSuite = strings.TrimSpace(workload.TestSuite)
+		if workload.TestSuite == "" {
+			log.Warnf("Ignoring workload [%d]: no testSuite.", i)
+			continue
+		}
+		if workload.Name == "" {
+			workload.Name = workload.TestSuite
+		}
+		if workloadNames[workload.Name] {
+			log.Warnf("Ignoring workload [%d]: duplicate name [%s].", i, workload.Name)
+			continue
+		}
+		if workload.Users < 0 || workload.Iterations < 0 || workload.Rate < 0 || workload.RampUsers < 0 || workload.RampDelay < 0 {
+			log.Warnf("Ignoring workload [%s]: negative setting.", workload.Name)
+			continue
+		}
+		if workload.Duration != "" && (&Config{Duration: workload.Duration}).RunDuration() == 0 {
+			log.Warnf("Invalid duration [%s] for workload [%s]. Falling back to the top level run length.", workload.Duration, workload.Name)
+			workload.Duration = ""
+		}
+		workloadNames[workload.Name] = true
+		validWorkloads = append(validWorkloads, workload)
+	}
+	c.Workloads = validWorkloads
+	validCriteria := make([]AbortCriterion, 0)
+	for i, criterion := range c.AbortCriteria {
+		if criterion.Metric != AbortOnErrorRate && criterion.Metric != AbortOnP95 && criterion.Metric != AbortOnPeakMemory {
+			log.Warnf("Ignoring abort criterion [%d]: unknown metric [%s].", i, criterion.Metric)
+			continue
+		}
+		if criterion.Threshold <= 0 {
+			log.Warnf("Ignoring abort criterion [%d]: threshold must be above zero.", i)
+			continue
+		}
+		if criterion.Metric == AbortOnPeakMemory && c.SkipMemCheck {
+			log.Warnf("Ignoring abort criterion [%d]: peak memory is not polled with skipMemCheck.", i)
+			continue
+		}
+		if criterion.WindowDuration() == 0 {
+			criterion.Window = defaultAbortWindow
+		}
+		if criterion.MinRequests < 1 {
+			criterion.MinRequests = defaultAbortMinRequests
+		}
+		validCriteria = append(validCriteria, criterion)
+	}
+	c.AbortCriteria = validCriteria
+	c.HTTPClient.ConnectTimeout = validTimeout("connectTimeout", c.HTTPClient.ConnectTimeout, defaultConnectTimeout)
+	c.HTTPClient.TLSHandshakeTimeout = validTimeout("tlsHandshakeTimeout", c.HTTPClient.TLSHandshakeTimeout, defaultTLSHandshakeTimeout)
+	c.HTTPClient.ResponseHeaderTimeout = validTimeout("responseHeaderTimeout", c.HTTPClient.ResponseHeaderTimeout, defaultResponseHeaderTimeout)
+	c.HTTPClient.RequestTimeout = validTimeout("requestTimeout", c.HTTPClient.RequestTimeout, defaultRequestTimeout)
+	if c.HTTPClient.MaxIdleConnsPerHost < 1 {
+		c.HTTPClient.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if c.HTTPClient.ConnectionPool != SharedConnectionPool && c.HTTPClient.ConnectionPool != PerUserConnectionPool {
+		c.HTTPClient.ConnectionPool = defaultConnectionPool
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		log.Warn("A client certificate needs both tls certFile and keyFile. Ignoring the client certificate.")
+		c.TLS.CertFile = ""
+		c.TLS.KeyFile = ""
+	}
+	if c.CookieScope != IterationCookieScope && c.CookieScope != UserCookieScope {
+		if c.CookieScope != "" {
+			log.Warnf("Invalid cookieScope [%s]. Falling back to %s.", c.CookieScope, defaultCookieScope)
+		}
+		c.CookieScope = defaultCookieScope
+	}
+	if c.TransactionThinkTime != ExcludeThinkTime && c.TransactionThinkTime != IncludeThinkTime {
+		c.TransactionThinkTime = defaultTransactionThinkTime
+	}
+	validProviders := make([]AuthProvider, 0)
+	providerNames := make(map[string]bool)
+	for i, provider := range c.AuthProviders {
+		if strings.TrimSpace(provider.Name) == "" {
+			log.Warnf("Ignoring auth provider [%d]: no name.", i)
+			continue
+		}
+		if providerNames[provider.Name] {
+			log.Warnf("Ignoring auth provider [%d]: duplicate name [%s].", i, provider.Name)
+			continue
+		}
+		if problem := provider.validate(); problem != "" {
+			log.Warnf("Ignoring auth provider [%s]: %s.", provider.Name, problem)
+			continue
+		}
+		if provider.Type == AuthOAuth2ClientCredentials || provider.Type == AuthJWT {
+			if d, err := time.ParseDuration(strings.TrimSpace(provider.RefreshBefore)); err != nil || d < 0 {
+				provider.RefreshBefore = defaultAuthRefreshBefore
+			}
+		}
+		if provider.Type == AuthJWT {
+			if provider.Algorithm == "" {
+				provider.Algorithm = defaultJWTAlgorithm
+			}
+			if d, err := time.ParseDuration(strings.TrimSpace(provider.Lifetime)); err != nil || d <= 0 {
+				provider.Lifetime = defaultJWTLifetime
+			}
+		}
+		providerNames[provider.Name] = true
+		validProviders = append(validProviders, provider)
+	}
+	c.AuthProviders = validProviders
+	if c.TLS.InsecureSkipVerify {
+		log.Warn("TLS certificate verification is disabled (tls insecureSkipVerify).")
+	}
+	validAgents := make([]string, 0)
+	agentAddresses := make(map[string]bool)
+	for _, agent := range c.Agents {
+		agent = strings.TrimSpace(agent)
+		if agent == "" || agentAddresses[agent] {
+			continue
+		}
+		agentAddresses[agent] = true
+		validAgents = append(validAgents, agent)
+	}
+	c.Agents = validAgents
 
 	configOutput := []byte("")
 	configOutput = append(configOutput, []byte("\n============== Configuration Settings =========\n")...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "apiName", c.APIName, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "targetHost", c.TargetHost, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "targetPort", c.TargetPort, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "targetScheme", c.TargetScheme, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "numIterations", c.NumIterations, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "concurrentUsers", c.ConcurrentUsers, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90.2f %2s", "allowablePeakMemoryVariance", c.AllowablePeakMemoryVariance, "\n"))...)
@@ -162,37 +510,481 @@ func (c *Config) PrintAndValidateConfig() {
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90t %2s", "reBaseAll", c.ReBaseAll, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "executionHost", c.ExecutionHost, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "requestDelay", c.RequestDelay, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "thinkTime", c.ThinkTime, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "tpsFreq", c.TPSFreq, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "rampUsers", c.RampUsers, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "rampDelay", c.RampDelay, "\n"))...)
 	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90t %2s", "skipMemCheck", c.SkipMemCheck, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90t %2s", "baseTTFB", c.BaseTTFB, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "executor", c.Executor, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "targetRate", c.TargetRate, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "maxVirtualUsers", c.MaxVirtualUsers, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "duration", c.Duration, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "pacing", c.Pacing.String(), "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "gracePeriod", c.GracePeriod, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "httpClient.connectTimeout", c.HTTPClient.ConnectTimeout, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "httpClient.tlsHandshakeTimeout", c.HTTPClient.TLSHandshakeTimeout, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "httpClient.responseHeaderTimeout", c.HTTPClient.ResponseHeaderTimeout, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "httpClient.requestTimeout", c.HTTPClient.RequestTimeout, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90d %2s", "httpClient.maxIdleConnsPerHost", c.HTTPClient.MaxIdleConnsPerHost, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90t %2s", "httpClient.disableKeepAlives", c.HTTPClient.DisableKeepAlives, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "httpClient.connectionPool", c.HTTPClient.ConnectionPool, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "tls.caFile", c.TLS.CAFile, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "tls.certFile", c.TLS.CertFile, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "tls.keyFile", c.TLS.KeyFile, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "tls.serverName", c.TLS.ServerName, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90t %2s", "tls.insecureSkipVerify", c.TLS.InsecureSkipVerify, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "cookieScope", c.CookieScope, "\n"))...)
+	configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "transactionThinkTime", c.TransactionThinkTime, "\n"))...)
+	for i, provider := range c.AuthProviders {
+		configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", fmt.Sprintf("authProviders.provider[%d]", i), provider.String(), "\n"))...)
+	}
+	for i, agent := range c.Agents {
+		configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", fmt.Sprintf("agents.agent[%d]", i), agent, "\n"))...)
+	}
+	if c.AgentToken != "" {
+		configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", "agentToken", "(set)", "\n"))...)
+	}
+	for i, stage := range c.LoadProfile {
+		configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", fmt.Sprintf("loadProfile.stage[%d]", i), stage.String(), "\n"))...)
+	}
+	for i, criterion := range c.AbortCriteria {
+		configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", fmt.Sprintf("abortCriteria.criterion[%d]", i), criterion.String(), "\n"))...)
+	}
+	for i, workload := range c.Workloads {
+		configOutput = append(configOutput, []byte(fmt.Sprintf("%-45s %-90s %2s", fmt.Sprintf("workloads.workload[%d]", i), workload.String(), "\n"))...)
+	}
 	configOutput = append(configOutput, []byte("\n=================================================\n")...)
 	log.Info(string(configOutput))
 }
 
+// RunDuration returns the wall-clock length of a duration based test run, or
+// zero when the run length is controlled by NumIterations.
+func (c *Config) RunDuration() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(c.Duration))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// ThinkTimeDistribution returns the think time before each request of the
+// test cases that have no preThinkTime. The zero ThinkTime means a random
+// delay of up to RequestDelay milliseconds instead.
+func (c *Config) ThinkTimeDistribution() ThinkTime {
+	tt, err := ParseThinkTime(c.ThinkTime)
+	if err != nil {
+		return ThinkTime{}
+	}
+	return tt
+}
+
+// GracePeriodDuration returns how long an interrupted test run waits for
+// requests in progress before the results are computed without them.
+func (c *Config) GracePeriodDuration() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(c.GracePeriod))
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// HTTPClientConfig controls the HTTP client used to send requests. The
+// timeouts are durations such as "5s"; "0s" means no limit. RequestTimeout
+// covers the whole request, including reading the response body.
+type HTTPClientConfig struct {
+	ConnectTimeout        string `xml:"connectTimeout"`
+	TLSHandshakeTimeout   string `xml:"tlsHandshakeTimeout"`
+	ResponseHeaderTimeout string `xml:"responseHeaderTimeout"`
+	RequestTimeout        string `xml:"requestTimeout"`
+	MaxIdleConnsPerHost   int    `xml:"maxIdleConnsPerHost"`
+	DisableKeepAlives     bool   `xml:"disableKeepAlives"`
+	ConnectionPool        string `xml:"connectionPool"`
+}
+
+// ConnectTimeoutDuration returns the limit on establishing a connection.
+func (h HTTPClientConfig) ConnectTimeoutDuration() time.Duration {
+	return timeoutDuration(h.ConnectTimeout)
+}
+
+// TLSHandshakeTimeoutDuration returns the limit on the TLS handshake.
+func (h HTTPClientConfig) TLSHandshakeTimeoutDuration() time.Duration {
+	return timeoutDuration(h.TLSHandshakeTimeout)
+}
+
+// ResponseHeaderTimeoutDuration returns the limit on waiting for the response
+// headers once the request has been written.
+func (h HTTPClientConfig) ResponseHeaderTimeoutDuration() time.Duration {
+	return timeoutDuration(h.ResponseHeaderTimeout)
+}
+
+// RequestTimeoutDuration returns the limit on the whole request.
+func (h HTTPClientConfig) RequestTimeoutDuration() time.Duration {
+	return timeoutDuration(h.RequestTimeout)
+}
+
+// timeoutDuration returns the timeout, or zero for no limit if it is not a
+// valid duration.
+func timeoutDuration(timeout string) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(timeout))
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// validTimeout returns the timeout if it is a valid duration, or the default
+// otherwise.
+func validTimeout(name string, timeout string, defaultTimeout string) string {
+	if d, err := time.ParseDuration(strings.TrimSpace(timeout)); err != nil || d < 0 {
+		log.Warnf("Invalid httpClient %s [%s]. Falling back to %s.", name, timeout, defaultTimeout)
+		return defaultTimeout
+	}
+	return strings.TrimSpace(timeout)
+}
+
+// TLSConfig holds the TLS settings for https targets: a CA bundle to trust
+// instead of the system roots, a client certificate and key for mutual TLS,
+// the server name sent for SNI and verified against the certificate, and an
+// explicit switch to skip certificate verification.
+type TLSConfig struct {
+	CAFile             string `xml:"caFile"`
+	CertFile           string `xml:"certFile"`
+	KeyFile            string `xml:"keyFile"`
+	ServerName         string `xml:"serverName"`
+	InsecureSkipVerify bool   `xml:"insecureSkipVerify"`
+}
+
+// ClientConfig loads the CA bundle and client certificate, if any, and
+// returns the TLS configuration for the HTTP client.
+func (t TLSConfig) ClientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls caFile: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in tls caFile [%s]", t.CAFile)
+		}
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the tls client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// AuthProvider adds credentials to the requests of the test definitions that
+// refer to it by name. The settings used depend on the type:
+//   - basic: Username and Password.
+//   - oauth2ClientCredentials: TokenURL, ClientID, ClientSecret and an
+//     optional Scope. The token is refreshed RefreshBefore it expires.
+//   - jwt: Algorithm HS256 with Secret, or RS256 with the PEM private key in
+//     KeyFile, plus the Issuer, Subject, Audience and Claims of the token.
+//     A token is valid for Lifetime and replaced RefreshBefore it expires.
+//   - hmac: KeyID and Secret.
+type AuthProvider struct {
+	Name          string      `xml:"name,attr"`
+	Type          string      `xml:"type,attr"`
+	Username      string      `xml:"username"`
+	Password      string      `xml:"password"`
+	TokenURL      string      `xml:"tokenUrl"`
+	ClientID      string      `xml:"clientId"`
+	ClientSecret  string      `xml:"clientSecret"`
+	Scope         string      `xml:"scope"`
+	RefreshBefore string      `xml:"refreshBefore"`
+	Algorithm     string      `xml:"algorithm"`
+	Secret        string      `xml:"secret"`
+	KeyFile       string      `xml:"keyFile"`
+	KeyID         string      `xml:"keyId"`
+	Issuer        string      `xml:"issuer"`
+	Subject       string      `xml:"subject"`
+	Audience      string      `xml:"audience"`
+	Lifetime      string      `xml:"lifetime"`
+	Claims        []AuthClaim `xml:"claims>claim"`
+}
+
+// AuthClaim is an additional string claim of a generated JWT.
+type AuthClaim struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// validate returns the reason the provider cannot be used, or an empty
+// string if it can.
+func (ap AuthProvider) validate() string {
+	switch ap.Type {
+	case AuthBasic:
+		if ap.Username == "" {
+			return "no username"
+		}
+	case AuthOAuth2ClientCredentials:
+		if ap.TokenURL == "" || ap.ClientID == "" {
+			return "tokenUrl and clientId are required"
+		}
+	case AuthJWT:
+		switch ap.Algorithm {
+		case "", "HS256":
+			if ap.Secret == "" {
+				return "HS256 requires a secret"
+			}
+		case "RS256":
+			if ap.KeyFile == "" {
+				return "RS256 requires a keyFile"
+			}
+		default:
+			return fmt.Sprintf("unsupported algorithm [%s]", ap.Algorithm)
+		}
+	case AuthHMAC:
+		if ap.KeyID == "" || ap.Secret == "" {
+			return "keyId and secret are required"
+		}
+	default:
+		return fmt.Sprintf("unknown type [%s]", ap.Type)
+	}
+	return ""
+}
+
+// RefreshBeforeDuration returns how long before expiry a token is replaced.
+func (ap AuthProvider) RefreshBeforeDuration() time.Duration {
+	return timeoutDuration(ap.RefreshBefore)
+}
+
+// LifetimeDuration returns how long a generated JWT is valid for.
+func (ap AuthProvider) LifetimeDuration() time.Duration {
+	return timeoutDuration(ap.Lifetime)
+}
+
+// String returns a human readable summary of the provider, without secrets.
+func (ap AuthProvider) String() string {
+	switch ap.Type {
+	case AuthBasic:
+		return fmt.Sprintf("%s: %s user=%s", ap.Name, ap.Type, ap.Username)
+	case AuthOAuth2ClientCredentials:
+		return fmt.Sprintf("%s: %s tokenUrl=%s clientId=%s", ap.Name, ap.Type, ap.TokenURL, ap.ClientID)
+	case AuthJWT:
+		return fmt.Sprintf("%s: %s %s issuer=%s lifetime=%s", ap.Name, ap.Type, ap.Algorithm, ap.Issuer, ap.Lifetime)
+	case AuthHMAC:
+		return fmt.Sprintf("%s: %s keyId=%s", ap.Name, ap.Type, ap.KeyID)
+	}
+	return ap.Name + ": " + ap.Type
+}
+
+// AbortCriterion stops the test run early when its metric goes above the
+// threshold. Error rate and p95 are measured over the requests completed in
+// the sliding window, once it holds at least MinRequests requests. Peak
+// memory is the highest value seen by the memory poller.
+type AbortCriterion struct {
+	Metric      string  `xml:"metric,attr"`
+	Threshold   float64 `xml:"threshold,attr"`
+	Window      string  `xml:"window,attr"`
+	MinRequests int     `xml:"minRequests,attr"`
+}
+
+// WindowDuration returns the length of the sliding window, or zero if the
+// window is not valid.
+func (ac AbortCriterion) WindowDuration() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(ac.Window))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// String returns a human readable summary of the criterion.
+func (ac AbortCriterion) String() string {
+	switch ac.Metric {
+	case AbortOnErrorRate:
+		return fmt.Sprintf("error rate above %.1f%% over %s", ac.Threshold, ac.Window)
+	case AbortOnP95:
+		return fmt.Sprintf("p95 above %.0fms over %s", ac.Threshold, ac.Window)
+	case AbortOnPeakMemory:
+		return fmt.Sprintf("peak memory above %.0fMB", ac.Threshold)
+	}
+	return ac.Metric
+}
+
+// Pacing spaces out the iterations of each virtual user of a suite based
+// run, so that throughput does not depend on response times. See
+// PacingInterval and PacingMinimum.
+type Pacing struct {
+	Mode   string `xml:"mode,attr"`
+	Period string `xml:"period,attr"`
+}
+
+// PeriodDuration returns the pacing period, or zero if iterations are not
+// paced.
+func (p Pacing) PeriodDuration() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(p.Period))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// String returns a human readable summary of the pacing.
+func (p Pacing) String() string {
+	switch {
+	case p.PeriodDuration() == 0:
+		return ""
+	case p.Mode == PacingMinimum:
+		return fmt.Sprintf("each iteration takes at least %s", p.Period)
+	default:
+		return fmt.Sprintf("an iteration every %s per user", p.Period)
+	}
+}
+
+// LoadStage is a single stage of the load profile. The target is a number
+// of concurrent users for the ClosedModel executor, or a number of
+// iterations started per second for the ArrivalRate executor.
+type LoadStage struct {
+	Name     string `xml:"name,attr"`
+	Shape    string `xml:"shape,attr"`
+	Users    int    `xml:"users,attr"`
+	Rate     int    `xml:"rate,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+// StageDuration returns the length of the stage, or zero if the duration is
+// not valid.
+func (ls LoadStage) StageDuration() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(ls.Duration))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// String returns a human readable summary of the stage.
+func (ls LoadStage) String() string {
+	target := fmt.Sprintf("%d users", ls.Users)
+	if ls.Rate > 0 {
+		target = fmt.Sprintf("%d/s", ls.Rate)
+	}
+	summary := fmt.Sprintf("%s to %s for %s", ls.Shape, target, ls.Duration)
+	if ls.Shape == LoadStageHold {
+		summary = fmt.Sprintf("%s at %s for %s", ls.Shape, target, ls.Duration)
+	}
+	if ls.Name != "" {
+		summary = ls.Name + ": " + summary
+	}
+	return summary
+}
+
+// Workload is one test suite of a mixed workload. All workloads run at the
+// same time against the same target. Settings left at zero (or empty) take
+// the top level value of the configuration.
+type Workload struct {
+	Name       string `xml:"name,attr"`
+	TestSuite  string `xml:"testSuite,attr"`
+	Users      int    `xml:"users,attr"`
+	Iterations int    `xml:"iterations,attr"`
+	Duration   string `xml:"duration,attr"`
+	Rate       int    `xml:"rate,attr"`
+	RampUsers  int    `xml:"rampUsers,attr"`
+	RampDelay  int    `xml:"rampDelay,attr"`
+}
+
+// String returns a human readable summary of the workload.
+func (w Workload) String() string {
+	return fmt.Sprintf("%s: %s users=%d iterations=%d duration=%s rate=%d", w.Name, w.TestSuite, w.Users, w.Iterations, w.Duration, w.Rate)
+}
+
+// WorkloadConfig returns a copy of the configuration for running a single
+// workload. The test suite, users, run length, rate and ramp settings of the
+// workload replace the top level ones. Load profiles do not apply to
+// workloads.
+func (c *Config) WorkloadConfig(w Workload) *Config {
+	wc := *c
+	wc.Workloads = nil
+	wc.LoadProfile = nil
+	wc.TestSuite = w.TestSuite
+	if w.Users > 0 {
+		wc.ConcurrentUsers = w.Users
+	}
+	if w.Iterations > 0 {
+		wc.NumIterations = w.Iterations
+	}
+	if w.Duration != "" {
+		wc.Duration = w.Duration
+	}
+	if w.Rate > 0 {
+		wc.TargetRate = w.Rate
+	}
+	if w.RampUsers > 0 {
+		wc.RampUsers = w.RampUsers
+	}
+	if w.RampDelay > 0 {
+		wc.RampDelay = w.RampDelay
+	}
+	if wc.MaxVirtualUsers < wc.ConcurrentUsers {
+		wc.MaxVirtualUsers = wc.ConcurrentUsers
+	}
+	return &wc
+}
+
 // BasePerfStats struct defines the base performance statistics
 type BasePerfStats struct {
-	GenerationDate           string           `json:"GenerationDate"`
-	ModifiedDate             string           `json:"ModifiedDate"`
-	BasePeakMemory           uint64           `json:"BasePeakMemory"`
-	BaseServiceResponseTimes map[string]int64 `json:"BaseServiceResponseTimes"`
-	MemoryAudit              []uint64         `json:"MemoryAudit"`
+	GenerationDate               string           `json:"GenerationDate"`
+	ModifiedDate                 string           `json:"ModifiedDate"`
+	BasePeakMemory               uint64           `json:"BasePeakMemory"`
+	BaseServiceResponseTimes     map[string]int64 `json:"BaseServiceResponseTimes"`
+	BaseServiceTTFB              map[string]int64 `json:"BaseServiceTTFB,omitempty"`
+	BaseTransactionResponseTimes map[string]int64 `json:"BaseTransactionResponseTimes,omitempty"`
+	MemoryAudit                  []uint64         `json:"MemoryAudit"`
 }
 
 // PerfStats struct defines the performance statistics for this test run
 type PerfStats struct {
-	PeakMemory           uint64
-	ServiceResponseTimes map[string]int64
-	ServiceTransCount    map[string]*uint64
-	ServiceErrorCount    map[string]*uint64
-	ServiceTPS           map[string]float64
-	OverAllTransCount    uint64
-	OverAllErrorCount    uint64
-	OverAllTPS           float64
-	MemoryAudit          []uint64
-	TestPartitions       []TestPartition
-	TestTimeStart        time.Time
-	TestTimeEnd          time.Time
+	PeakMemory                    uint64
+	ServiceResponseTimes          map[string]int64
+	ServiceCorrectedResponseTimes map[string]int64
+	ServiceCorrectedSamples       map[string][]int64
+	ServiceTransCount             map[string]*uint64
+	ServiceErrorCount             map[string]*uint64
+	ServiceTimeoutCount           map[string]*uint64
+	ServiceRetryCount             map[string]*uint64
+	ServiceThinkTime              map[string]*uint64
+	ServiceThinkCount             map[string]*uint64
+	ServicePhaseTimes             map[string]*PhaseTimes
+	ServiceTPS                    map[string]float64
+	ServiceConfiguredMix          map[string]float64
+	ServiceOperations             map[string]string
+	StepStats                     map[string]*StepStats
+	ServiceAssertionFailures      map[string]map[string]*uint64
+	TransactionResponseTimes      map[string]int64
+	TransactionSamples            map[string][]int64
+	TransactionTransCount         map[string]*uint64
+	TransactionErrorCount         map[string]*uint64
+	TransactionThresholds         map[string]int64
+	OverAllTransCount             uint64
+	OverAllErrorCount             uint64
+	OverAllTimeoutCount           uint64
+	OverAllRetryCount             uint64
+	OverAllThinkTime              uint64
+	OverAllThinkCount             uint64
+	OverAllTPS                    float64
+	IterationCount                uint64
+	DroppedIterations             uint64
+	LateIterations                uint64
+	PacedIterations               uint64
+	OverrunIterations             uint64
+	MemoryAudit                   []uint64
+	TestPartitions                []TestPartition
+	LoadStageStarts               []LoadStageStart
+	Workloads                     []*WorkloadStats
+	StopReason                    string
+	AbortReason                   string
+	TestTimeStart                 time.Time
+	TestTimeEnd                   time.Time
 }
 
 // GetTestTimeStart returns the start time of the test in RFC850 format.
@@ -205,12 +997,372 @@ func (ps *PerfStats) GetTestTimeEnd() string {
 	return ps.TestTimeEnd.Format(time.RFC850)
 }
 
+// GetTestDuration returns the wall-clock run time of the test, including any
+// iterations still in progress when a duration based run reached its deadline.
+func (ps *PerfStats) GetTestDuration() string {
+	return ps.TestTimeEnd.Sub(ps.TestTimeStart).String()
+}
+
+// GetServiceMix returns the configured and the realised number of executions
+// of the service per suite iteration as percentages, e.g. "20.0% / 19.4%".
+// Only suite based runs have a mix.
+func (ps *PerfStats) GetServiceMix(serviceName string) string {
+	configured, ok := ps.ServiceConfiguredMix[serviceName]
+	if !ok {
+		return ""
+	}
+	realised := 0.0
+	if transCount := ps.ServiceTransCount[serviceName]; transCount != nil && ps.IterationCount > 0 {
+		realised = float64(*transCount) / float64(ps.IterationCount)
+	}
+	return fmt.Sprintf("%.1f%% / %.1f%%", configured*100, realised*100)
+}
+
+// OperationStats holds the results of the test cases that send the same
+// GraphQL operation. ResponseTime is the average of the response times of
+// the test cases in nanoseconds, weighted by their transaction counts when
+// these are known.
+type OperationStats struct {
+	Name         string
+	Services     []string
+	TransCount   uint64
+	ErrorCount   uint64
+	ResponseTime int64
+}
+
+// GetOperationStats returns the results of the GraphQL test cases grouped by
+// operation name, sorted by name.
+func (ps *PerfStats) GetOperationStats() []*OperationStats {
+	operations := make(map[string]*OperationStats)
+	names := make([]string, 0)
+	for serviceName, operation := range ps.ServiceOperations {
+		op := operations[operation]
+		if op == nil {
+			op = &OperationStats{Name: operation}
+			operations[operation] = op
+			names = append(names, operation)
+		}
+		op.Services = append(op.Services, serviceName)
+	}
+	sort.Strings(names)
+
+	result := make([]*OperationStats, 0, len(names))
+	for _, name := range names {
+		op := operations[name]
+		sort.Strings(op.Services)
+		weightedTotal, weights := 0.0, 0.0
+		for _, serviceName := range op.Services {
+			weight := uint64(1)
+			if transCount := ps.ServiceTransCount[serviceName]; transCount != nil {
+				weight = *transCount
+				op.TransCount += *transCount
+			}
+			if errorCount := ps.ServiceErrorCount[serviceName]; errorCount != nil {
+				op.ErrorCount += *errorCount
+			}
+			weightedTotal += float64(ps.ServiceResponseTimes[serviceName]) * float64(weight)
+			weights += float64(weight)
+		}
+		if weights > 0 {
+			op.ResponseTime = int64(weightedTotal / weights)
+		}
+		result = append(result, op)
+	}
+	return result
+}
+
+// GetAverageThinkTime returns the realised average think time per request,
+// in milliseconds. Think time is not part of any response time.
+func (ps *PerfStats) GetAverageThinkTime() float64 {
+	return averageThinkTime(ps.OverAllThinkTime, ps.OverAllThinkCount)
+}
+
+// GetServiceAverageThinkTime returns the realised average think time around
+// the requests of the service, in milliseconds.
+func (ps *PerfStats) GetServiceAverageThinkTime(serviceName string) float64 {
+	thinkTime, thinkCount := ps.ServiceThinkTime[serviceName], ps.ServiceThinkCount[serviceName]
+	if thinkTime == nil || thinkCount == nil {
+		return 0
+	}
+	return averageThinkTime(*thinkTime, *thinkCount)
+}
+
+// averageThinkTime returns the total think time in nanoseconds over count
+// requests, in milliseconds.
+func averageThinkTime(total uint64, count uint64) float64 {
+	if count == 0 {
+		return 0
+	}
+	return float64(total) / float64(count) / float64(time.Millisecond)
+}
+
+// Merge adds the counters of other, the results of a test run on another
+// load generator, to ps. Response times are averages, so they are not
+// merged: the caller averages the merged response time samples instead.
+// The stop and abort reasons of ps are kept, if set.
+func (ps *PerfStats) Merge(other *PerfStats) {
+	ps.ServiceTransCount = mergeCounts(ps.ServiceTransCount, other.ServiceTransCount)
+	ps.ServiceErrorCount = mergeCounts(ps.ServiceErrorCount, other.ServiceErrorCount)
+	ps.ServiceTimeoutCount = mergeCounts(ps.ServiceTimeoutCount, other.ServiceTimeoutCount)
+	ps.ServiceRetryCount = mergeCounts(ps.ServiceRetryCount, other.ServiceRetryCount)
+	ps.ServiceThinkTime = mergeCounts(ps.ServiceThinkTime, other.ServiceThinkTime)
+	ps.ServiceThinkCount = mergeCounts(ps.ServiceThinkCount, other.ServiceThinkCount)
+	ps.TransactionTransCount = mergeCounts(ps.TransactionTransCount, other.TransactionTransCount)
+	ps.TransactionErrorCount = mergeCounts(ps.TransactionErrorCount, other.TransactionErrorCount)
+	for serviceName, phaseTimes := range other.ServicePhaseTimes {
+		if ps.ServicePhaseTimes == nil {
+			ps.ServicePhaseTimes = make(map[string]*PhaseTimes)
+		}
+		if ps.ServicePhaseTimes[serviceName] == nil {
+			ps.ServicePhaseTimes[serviceName] = new(PhaseTimes)
+		}
+		ps.ServicePhaseTimes[serviceName].Add(phaseTimes)
+	}
+	for serviceName, operation := range other.ServiceOperations {
+		if ps.ServiceOperations == nil {
+			ps.ServiceOperations = make(map[string]string)
+		}
+		ps.ServiceOperations[serviceName] = operation
+	}
+	for serviceName, failures := range other.ServiceAssertionFailures {
+		if ps.ServiceAssertionFailures == nil {
+			ps.ServiceAssertionFailures = make(map[string]map[string]*uint64)
+		}
+		ps.ServiceAssertionFailures[serviceName] = mergeCounts(ps.ServiceAssertionFailures[serviceName], failures)
+	}
+	for stepName, stepStats := range other.StepStats {
+		if ps.StepStats == nil {
+			ps.StepStats = make(map[string]*StepStats)
+		}
+		if ps.StepStats[stepName] == nil {
+			ps.StepStats[stepName] = &StepStats{Type: stepStats.Type}
+		}
+		ps.StepStats[stepName].Add(stepStats)
+	}
+	for transactionName, samples := range other.TransactionSamples {
+		if ps.TransactionSamples == nil {
+			ps.TransactionSamples = make(map[string][]int64)
+		}
+		ps.TransactionSamples[transactionName] = append(ps.TransactionSamples[transactionName], samples...)
+	}
+	for transactionName, threshold := range other.TransactionThresholds {
+		if ps.TransactionThresholds == nil {
+			ps.TransactionThresholds = make(map[string]int64)
+		}
+		ps.TransactionThresholds[transactionName] = threshold
+	}
+	for serviceName, samples := range other.ServiceCorrectedSamples {
+		if ps.ServiceCorrectedSamples == nil {
+			ps.ServiceCorrectedSamples = make(map[string][]int64)
+		}
+		ps.ServiceCorrectedSamples[serviceName] = append(ps.ServiceCorrectedSamples[serviceName], samples...)
+	}
+	ps.OverAllTransCount += other.OverAllTransCount
+	ps.OverAllErrorCount += other.OverAllErrorCount
+	ps.OverAllTimeoutCount += other.OverAllTimeoutCount
+	ps.OverAllRetryCount += other.OverAllRetryCount
+	ps.OverAllThinkTime += other.OverAllThinkTime
+	ps.OverAllThinkCount += other.OverAllThinkCount
+	ps.IterationCount += other.IterationCount
+	ps.DroppedIterations += other.DroppedIterations
+	ps.LateIterations += other.LateIterations
+	ps.PacedIterations += other.PacedIterations
+	ps.OverrunIterations += other.OverrunIterations
+
+	if ps.ServiceConfiguredMix == nil {
+		ps.ServiceConfiguredMix = other.ServiceConfiguredMix
+	}
+	if ps.LoadStageStarts == nil {
+		ps.LoadStageStarts = other.LoadStageStarts
+	}
+	if ps.StopReason == "" {
+		ps.StopReason = other.StopReason
+	}
+	if ps.AbortReason == "" {
+		ps.AbortReason = other.AbortReason
+	}
+
+	for _, otherWorkload := range other.Workloads {
+		var workloadStats *WorkloadStats
+		for _, ws := range ps.Workloads {
+			if ws.Name == otherWorkload.Name {
+				workloadStats = ws
+			}
+		}
+		if workloadStats == nil {
+			workloadStats = NewWorkloadStats(otherWorkload.Name, otherWorkload.TestSuite, 0)
+			workloadStats.ServiceConfiguredMix = otherWorkload.ServiceConfiguredMix
+			ps.Workloads = append(ps.Workloads, workloadStats)
+		}
+		workloadStats.merge(otherWorkload)
+	}
+}
+
+// mergeCounts adds the counters of src to those of dst, and returns dst.
+func mergeCounts(dst map[string]*uint64, src map[string]*uint64) map[string]*uint64 {
+	if dst == nil {
+		dst = make(map[string]*uint64)
+	}
+	for serviceName, count := range src {
+		if count == nil {
+			continue
+		}
+		if dst[serviceName] == nil {
+			dst[serviceName] = new(uint64)
+		}
+		*dst[serviceName] += *count
+	}
+	return dst
+}
+
+// PhaseTimes holds the time spent in each phase of the requests of a
+// service, in nanoseconds, totalled over Count requests. The phases follow
+// each other: DNS lookup, TCP connect, TLS handshake, time to first byte from
+// the moment the connection is ready, and download of the body. Requests on a
+// reused connection spend no time in the first three. The totals are updated
+// concurrently with Add.
+type PhaseTimes struct {
+	Count    uint64
+	DNS      uint64
+	Connect  uint64
+	TLS      uint64
+	TTFB     uint64
+	Download uint64
+}
+
+// Add adds the phase times of other to pt.
+func (pt *PhaseTimes) Add(other *PhaseTimes) {
+	atomic.AddUint64(&pt.Count, other.Count)
+	atomic.AddUint64(&pt.DNS, other.DNS)
+	atomic.AddUint64(&pt.Connect, other.Connect)
+	atomic.AddUint64(&pt.TLS, other.TLS)
+	atomic.AddUint64(&pt.TTFB, other.TTFB)
+	atomic.AddUint64(&pt.Download, other.Download)
+}
+
+// Average returns the average time per request of the given phase total, in
+// milliseconds.
+func (pt *PhaseTimes) Average(total uint64) float64 {
+	if pt == nil || pt.Count == 0 {
+		return 0
+	}
+	return float64(total) / float64(pt.Count) / float64(time.Millisecond)
+}
+
+// AverageTTFB returns the average time to first byte, in nanoseconds.
+func (pt *PhaseTimes) AverageTTFB() int64 {
+	if pt == nil || pt.Count == 0 {
+		return 0
+	}
+	return int64(pt.TTFB / pt.Count)
+}
+
+// StepStats counts the executions of a control flow step of a suite, a
+// repeat, while, until or if, by step name. Passes is the number of passes
+// through the steps of a repeat or loop, or the number of times the condition
+// of an if held. Timeouts is the number of loops that ended on their timeout.
+// The counters are updated concurrently with Add.
+type StepStats struct {
+	Type     string
+	Count    uint64
+	Passes   uint64
+	Timeouts uint64
+}
+
+// Add adds the counters of other to ss.
+func (ss *StepStats) Add(other *StepStats) {
+	atomic.AddUint64(&ss.Count, other.Count)
+	atomic.AddUint64(&ss.Passes, other.Passes)
+	atomic.AddUint64(&ss.Timeouts, other.Timeouts)
+}
+
+// AveragePasses returns the average number of passes per execution of the
+// step.
+func (ss *StepStats) AveragePasses() float64 {
+	if ss == nil || ss.Count == 0 {
+		return 0
+	}
+	return float64(ss.Passes) / float64(ss.Count)
+}
+
+// WorkloadStats holds the results of a single workload of a mixed workload
+// run. The counters are updated concurrently, the same as those of PerfStats.
+type WorkloadStats struct {
+	Name                 string
+	TestSuite            string
+	ConcurrentUsers      int
+	IterationCount       uint64
+	TransCount           uint64
+	ErrorCount           uint64
+	TPS                  float64
+	ServiceResponseTimes map[string]int64
+	ServiceTransCount    map[string]*uint64
+	ServiceErrorCount    map[string]*uint64
+	ServiceConfiguredMix map[string]float64
+}
+
+// NewWorkloadStats returns the initialised statistics of a workload.
+func NewWorkloadStats(name string, testSuite string, concurrentUsers int) *WorkloadStats {
+	return &WorkloadStats{
+		Name:                 name,
+		TestSuite:            testSuite,
+		ConcurrentUsers:      concurrentUsers,
+		ServiceResponseTimes: make(map[string]int64),
+		ServiceTransCount:    make(map[string]*uint64),
+		ServiceErrorCount:    make(map[string]*uint64),
+		ServiceConfiguredMix: make(map[string]float64),
+	}
+}
+
+// GetServiceMix returns the configured and the realised number of executions
+// of the service per iteration of the workload's suite as percentages.
+func (ws *WorkloadStats) GetServiceMix(serviceName string) string {
+	ps := &PerfStats{
+		IterationCount:       ws.IterationCount,
+		ServiceTransCount:    ws.ServiceTransCount,
+		ServiceConfiguredMix: ws.ServiceConfiguredMix,
+	}
+	return ps.GetServiceMix(serviceName)
+}
+
+// merge adds the users and counters of other to ws. The response time of a
+// service is averaged over both, weighted by the transactions of each.
+func (ws *WorkloadStats) merge(other *WorkloadStats) {
+	for serviceName, responseTime := range other.ServiceResponseTimes {
+		var transCount, otherTransCount uint64
+		if count := ws.ServiceTransCount[serviceName]; count != nil {
+			transCount = *count
+		}
+		if count := other.ServiceTransCount[serviceName]; count != nil {
+			otherTransCount = *count
+		}
+		if transCount+otherTransCount == 0 {
+			continue
+		}
+		ws.ServiceResponseTimes[serviceName] = int64((float64(ws.ServiceResponseTimes[serviceName])*float64(transCount) +
+			float64(responseTime)*float64(otherTransCount)) / float64(transCount+otherTransCount))
+	}
+	ws.ConcurrentUsers += other.ConcurrentUsers
+	ws.IterationCount += other.IterationCount
+	ws.TransCount += other.TransCount
+	ws.ErrorCount += other.ErrorCount
+	ws.ServiceTransCount = mergeCounts(ws.ServiceTransCount, other.ServiceTransCount)
+	ws.ServiceErrorCount = mergeCounts(ws.ServiceErrorCount, other.ServiceErrorCount)
+}
+
 // TestPartition struct combines the test name with a count for use on the report.
 type TestPartition struct {
 	Count    int
 	TestName string
 }
 
+// LoadStageStart records the wall-clock time at which a load profile stage
+// began, so that stage boundaries can be marked on the report charts.
+type LoadStageStart struct {
+	Name string
+	Time time.Time
+}
+
 // Entry struct combines a serivce call with memory statistics for use on the report.
 type Entry struct {
 	Cmdline  []string         `json:"cmdline"`
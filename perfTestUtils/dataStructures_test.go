@@ -3,6 +3,7 @@ package perfTestUtils
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestSetDefaults(t *testing.T) {
@@ -11,6 +12,7 @@ func TestSetDefaults(t *testing.T) {
 	assert.Equal(t, defaultAPIName, c.APIName)
 	assert.Equal(t, defaultTargetHost, c.TargetHost)
 	assert.Equal(t, defaultTargetPort, c.TargetPort)
+	assert.Equal(t, defaultTargetScheme, c.TargetScheme)
 	assert.Equal(t, defaultNumIterations, c.NumIterations)
 	assert.Equal(t, defaultAllowablePeakMemoryVariance, c.AllowablePeakMemoryVariance)
 	assert.Equal(t, defaultAllowableServiceResponseTimeVariance, c.AllowableServiceResponseTimeVariance)
@@ -24,6 +26,17 @@ func TestSetDefaults(t *testing.T) {
 	assert.Equal(t, defaultTPSFreq, c.TPSFreq)
 	assert.Equal(t, defaultRampUsers, c.RampUsers)
 	assert.Equal(t, defaultRampDelay, c.RampDelay)
+	assert.Equal(t, defaultExecutor, c.Executor)
+	assert.Equal(t, defaultTargetRate, c.TargetRate)
+	assert.Equal(t, defaultMaxVirtualUsers, c.MaxVirtualUsers)
+	assert.Equal(t, defaultDuration, c.Duration)
+	assert.Equal(t, defaultGracePeriod, c.GracePeriod)
+	assert.Equal(t, defaultRequestTimeout, c.HTTPClient.RequestTimeout)
+	assert.Equal(t, defaultMaxIdleConnsPerHost, c.HTTPClient.MaxIdleConnsPerHost)
+	assert.Equal(t, SharedConnectionPool, c.HTTPClient.ConnectionPool)
+	assert.Equal(t, false, c.HTTPClient.DisableKeepAlives)
+	assert.Equal(t, IterationCookieScope, c.CookieScope)
+	assert.Equal(t, ExcludeThinkTime, c.TransactionThinkTime)
 	assert.Equal(t, false, c.GBS)
 	assert.Equal(t, false, c.ReBaseMemory)
 	assert.Equal(t, false, c.ReBaseAll)
@@ -48,6 +61,13 @@ func TestPrintAndValidate(t *testing.T) {
 	c.TPSFreq = 0
 	c.RampUsers = -3
 	c.RampDelay = 0
+	c.Executor = "Unknown"
+	c.TargetRate = 0
+	c.MaxVirtualUsers = 0
+	c.Duration = "forever"
+	c.GracePeriod = "-1s"
+	c.CookieScope = "forever"
+	c.TransactionThinkTime = "sometimes"
 
 	c.PrintAndValidateConfig()
 
@@ -67,4 +87,474 @@ func TestPrintAndValidate(t *testing.T) {
 	assert.Equal(t, defaultTPSFreq, c.TPSFreq)
 	assert.Equal(t, defaultRampUsers, c.RampUsers)
 	assert.Equal(t, defaultRampDelay, c.RampDelay)
+	assert.Equal(t, defaultExecutor, c.Executor)
+	assert.Equal(t, defaultTargetRate, c.TargetRate)
+	assert.Equal(t, defaultMaxVirtualUsers, c.MaxVirtualUsers)
+	assert.Equal(t, defaultDuration, c.Duration)
+	assert.Equal(t, defaultGracePeriod, c.GracePeriod)
+	assert.Equal(t, defaultCookieScope, c.CookieScope)
+	assert.Equal(t, defaultTransactionThinkTime, c.TransactionThinkTime)
+}
+
+func TestPrintAndValidateMaxVirtualUsers(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.ConcurrentUsers = 250
+
+	c.PrintAndValidateConfig()
+
+	// The pool must be able to hold at least the initial users.
+	assert.Equal(t, 250, c.MaxVirtualUsers)
+}
+
+func TestRunDuration(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	assert.Equal(t, time.Duration(0), c.RunDuration())
+
+	c.Duration = "1h30m"
+	assert.Equal(t, 90*time.Minute, c.RunDuration())
+
+	c.Duration = "-5m"
+	assert.Equal(t, time.Duration(0), c.RunDuration())
+}
+
+func TestGracePeriodDuration(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	assert.Equal(t, 30*time.Second, c.GracePeriodDuration())
+
+	c.GracePeriod = "0s"
+	assert.Equal(t, time.Duration(0), c.GracePeriodDuration())
+}
+
+func TestPrintAndValidateHTTPClient(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.HTTPClient.ConnectTimeout = "soon"
+	c.HTTPClient.TLSHandshakeTimeout = "-1s"
+	c.HTTPClient.ResponseHeaderTimeout = " 5s "
+	c.HTTPClient.RequestTimeout = "0s"
+	c.HTTPClient.MaxIdleConnsPerHost = 0
+	c.HTTPClient.ConnectionPool = "private"
+
+	c.PrintAndValidateConfig()
+
+	assert.Equal(t, defaultConnectTimeout, c.HTTPClient.ConnectTimeout)
+	assert.Equal(t, defaultTLSHandshakeTimeout, c.HTTPClient.TLSHandshakeTimeout)
+	assert.Equal(t, 5*time.Second, c.HTTPClient.ResponseHeaderTimeoutDuration())
+	// Zero means no limit.
+	assert.Equal(t, "0s", c.HTTPClient.RequestTimeout)
+	assert.Equal(t, time.Duration(0), c.HTTPClient.RequestTimeoutDuration())
+	assert.Equal(t, defaultMaxIdleConnsPerHost, c.HTTPClient.MaxIdleConnsPerHost)
+	assert.Equal(t, SharedConnectionPool, c.HTTPClient.ConnectionPool)
+}
+
+func TestPrintAndValidateTLS(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.TargetScheme = " HTTPS "
+	c.TLS.CertFile = "client.pem"
+
+	c.PrintAndValidateConfig()
+
+	assert.Equal(t, "https", c.TargetScheme)
+	// A certificate without a key is ignored.
+	assert.Equal(t, "", c.TLS.CertFile)
+
+	c.TargetScheme = "ftp"
+	c.PrintAndValidateConfig()
+	assert.Equal(t, defaultTargetScheme, c.TargetScheme)
+}
+
+func TestTLSClientConfig(t *testing.T) {
+	tlsConfig, err := TLSConfig{ServerName: "api.example.com", InsecureSkipVerify: true}.ClientConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "api.example.com", tlsConfig.ServerName)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Nil(t, tlsConfig.RootCAs)
+
+	_, err = TLSConfig{CAFile: "missing-ca.pem"}.ClientConfig()
+	assert.NotNil(t, err)
+
+	_, err = TLSConfig{CertFile: "missing-cert.pem", KeyFile: "missing-key.pem"}.ClientConfig()
+	assert.NotNil(t, err)
+
+	// A file that holds no certificates is not a CA bundle.
+	_, err = TLSConfig{CAFile: "dataStructures.go"}.ClientConfig()
+	assert.NotNil(t, err)
+}
+
+func TestPrintAndValidateAuthProviders(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.AuthProviders = []AuthProvider{
+		{Name: "admin", Type: AuthBasic, Username: "alice", Password: "pa55"},
+		{Name: "api", Type: AuthOAuth2ClientCredentials, TokenURL: "https://idp/token", ClientID: "perf", RefreshBefore: "soon"},
+		{Name: "jwt", Type: AuthJWT, Secret: "key"},
+		{Name: "signed", Type: AuthHMAC, KeyID: "key-1", Secret: "shared"},
+		{Type: AuthBasic, Username: "bob"},
+		{Name: "admin", Type: AuthBasic, Username: "carol"},
+		{Name: "saml", Type: "saml"},
+		{Name: "rsa", Type: AuthJWT, Algorithm: "RS256"},
+		{Name: "noSecret", Type: AuthHMAC, KeyID: "key-2"},
+	}
+
+	c.PrintAndValidateConfig()
+
+	// Providers without a name, with a duplicate name, of an unknown type or
+	// missing a required setting are dropped.
+	assert.Equal(t, 4, len(c.AuthProviders))
+	assert.Equal(t, "alice", c.AuthProviders[0].Username)
+	assert.Equal(t, 30*time.Second, c.AuthProviders[1].RefreshBeforeDuration())
+	assert.Equal(t, "HS256", c.AuthProviders[2].Algorithm)
+	assert.Equal(t, 5*time.Minute, c.AuthProviders[2].LifetimeDuration())
+	assert.Equal(t, "api: oauth2ClientCredentials tokenUrl=https://idp/token clientId=perf", c.AuthProviders[1].String())
+	assert.NotContains(t, c.AuthProviders[3].String(), "shared")
+}
+
+func TestGetTestDuration(t *testing.T) {
+	start := time.Now()
+	ps := &PerfStats{TestTimeStart: start, TestTimeEnd: start.Add(90 * time.Second)}
+	assert.Equal(t, "1m30s", ps.GetTestDuration())
+}
+
+func TestGetServiceMix(t *testing.T) {
+	searchCount := uint64(39)
+	ps := &PerfStats{
+		IterationCount:       200,
+		ServiceTransCount:    map[string]*uint64{"search": &searchCount},
+		ServiceConfiguredMix: map[string]float64{"search": 0.2, "checkout": 0.05},
+	}
+	assert.Equal(t, "20.0% / 19.5%", ps.GetServiceMix("search"))
+	assert.Equal(t, "5.0% / 0.0%", ps.GetServiceMix("checkout"))
+	assert.Equal(t, "", ps.GetServiceMix("login"))
+}
+
+func TestWorkloadConfig(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.Duration = "10m"
+	c.LoadProfile = []LoadStage{{Users: 10, Duration: "1m"}}
+	c.Workloads = []Workload{{Name: "browse", TestSuite: "browse.xml"}}
+
+	wc := c.WorkloadConfig(Workload{Name: "browse", TestSuite: "browse.xml", Users: 200, Iterations: 5, RampUsers: 10})
+	assert.Equal(t, "browse.xml", wc.TestSuite)
+	assert.Equal(t, 200, wc.ConcurrentUsers)
+	assert.Equal(t, 5, wc.NumIterations)
+	assert.Equal(t, 10, wc.RampUsers)
+	assert.Equal(t, 200, wc.MaxVirtualUsers)
+	assert.Equal(t, defaultRampDelay, wc.RampDelay)
+	// Settings left at zero take the top level value.
+	assert.Equal(t, "10m", wc.Duration)
+	assert.Equal(t, 0, len(wc.LoadProfile))
+	assert.Equal(t, 0, len(wc.Workloads))
+	// The top level configuration is unchanged.
+	assert.Equal(t, defaultConcurrentUsers, c.ConcurrentUsers)
+	assert.Equal(t, 1, len(c.Workloads))
+}
+
+func TestPrintAndValidateWorkloads(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.Workloads = []Workload{
+		{TestSuite: "browse.xml", Users: 80},
+		{Name: "checkout", TestSuite: " checkout.xml ", Users: 15, Duration: "later"},
+		{Name: "noSuite", Users: 5},
+		{Name: "checkout", TestSuite: "other.xml"},
+		{Name: "admin", TestSuite: "admin.xml", Users: -5},
+	}
+
+	c.PrintAndValidateConfig()
+
+	// Workloads without a suite, with a duplicate name or with negative
+	// settings are dropped. The name defaults to the suite.
+	assert.Equal(t, 2, len(c.Workloads))
+	assert.Equal(t, "browse.xml", c.Workloads[0].Name)
+	assert.Equal(t, "checkout.xml", c.Workloads[1].TestSuite)
+	assert.Equal(t, "", c.Workloads[1].Duration)
+}
+
+func TestPrintAndValidateAbortCriteria(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.AbortCriteria = []AbortCriterion{
+		{Metric: AbortOnErrorRate, Threshold: 20},
+		{Metric: AbortOnP95, Threshold: 800, Window: "1m", MinRequests: 100},
+		{Metric: "latency", Threshold: 5},
+		{Metric: AbortOnPeakMemory, Threshold: 0},
+	}
+
+	c.PrintAndValidateConfig()
+
+	// Unknown metrics and thresholds of zero are dropped. The window and
+	// minimum number of requests have defaults.
+	assert.Equal(t, 2, len(c.AbortCriteria))
+	assert.Equal(t, defaultAbortWindow, c.AbortCriteria[0].Window)
+	assert.Equal(t, defaultAbortMinRequests, c.AbortCriteria[0].MinRequests)
+	assert.Equal(t, time.Minute, c.AbortCriteria[1].WindowDuration())
+	assert.Equal(t, 100, c.AbortCriteria[1].MinRequests)
+	assert.Equal(t, "p95 above 800ms over 1m", c.AbortCriteria[1].String())
+}
+
+func TestPrintAndValidateAbortCriteriaSkipMemCheck(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.AbortCriteria = []AbortCriterion{
+		{Metric: AbortOnPeakMemory, Threshold: 512},
+		{Metric: AbortOnErrorRate, Threshold: 20},
+	}
+
+	c.SkipMemCheck = false
+	c.PrintAndValidateConfig()
+	assert.Equal(t, 2, len(c.AbortCriteria))
+
+	// Peak memory is not polled with skipMemCheck.
+	c.SkipMemCheck = true
+	c.PrintAndValidateConfig()
+	assert.Equal(t, 1, len(c.AbortCriteria))
+	assert.Equal(t, AbortOnErrorRate, c.AbortCriteria[0].Metric)
+}
+
+func TestPrintAndValidateLoadProfile(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.LoadProfile = []LoadStage{
+		{Users: 10, Duration: "1m"},
+		{Shape: "wobble", Users: 10, Duration: "1m"},
+		{Shape: LoadStageHold, Duration: "soon"},
+		{Shape: LoadStageSpike, Users: 100, Duration: "10s"},
+	}
+
+	c.PrintAndValidateConfig()
+
+	// Invalid stages are dropped and the shape defaults to a ramp.
+	assert.Equal(t, 2, len(c.LoadProfile))
+	assert.Equal(t, LoadStageRamp, c.LoadProfile[0].Shape)
+	assert.Equal(t, LoadStageSpike, c.LoadProfile[1].Shape)
+	assert.Equal(t, 10*time.Second, c.LoadProfile[1].StageDuration())
+}
+
+func TestPrintAndValidateLoadProfileHold(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.LoadProfile = []LoadStage{
+		{Users: 10, Duration: "1m"},
+		{Shape: LoadStageSpike, Users: 100, Duration: "10s"},
+		{Shape: LoadStageHold, Duration: "5m"},
+		{Shape: LoadStageHold, Users: 20, Duration: "5m"},
+	}
+
+	c.PrintAndValidateConfig()
+
+	// A hold without a target shows the level it holds, which a spike does
+	// not change.
+	assert.Equal(t, 10, c.LoadProfile[2].Users)
+	assert.Equal(t, "hold at 10 users for 5m", c.LoadProfile[2].String())
+	assert.Equal(t, "hold at 20 users for 5m", c.LoadProfile[3].String())
+	assert.Equal(t, "ramp to 10 users for 1m", c.LoadProfile[0].String())
+}
+
+func TestPrintAndValidateAgents(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.Agents = []string{" localhost:9091", "", "localhost:9092", "localhost:9091"}
+	c.PrintAndValidateConfig()
+	assert.Equal(t, []string{"localhost:9091", "localhost:9092"}, c.Agents)
+}
+
+func TestPrintAndValidateThinkTime(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.ThinkTime = "normal(500,100)"
+	c.PrintAndValidateConfig()
+	assert.Equal(t, "normal(500,100)", c.ThinkTime)
+	assert.Equal(t, ThinkTime{Distribution: ThinkTimeNormal, Mean: 500, StdDev: 100}, c.ThinkTimeDistribution())
+
+	c.ThinkTime = "gamma(2,3)"
+	c.PrintAndValidateConfig()
+	assert.Equal(t, "", c.ThinkTime)
+	assert.True(t, c.ThinkTimeDistribution().IsZero())
+}
+
+func TestPrintAndValidatePacing(t *testing.T) {
+	c := &Config{}
+	c.SetDefaults()
+	c.Pacing = Pacing{Period: "10s"}
+	c.PrintAndValidateConfig()
+	assert.Equal(t, Pacing{Mode: PacingInterval, Period: "10s"}, c.Pacing)
+	assert.Equal(t, 10*time.Second, c.Pacing.PeriodDuration())
+	assert.Equal(t, "an iteration every 10s per user", c.Pacing.String())
+
+	c.Pacing = Pacing{Mode: "sometimes", Period: "5s"}
+	c.PrintAndValidateConfig()
+	assert.Equal(t, PacingInterval, c.Pacing.Mode)
+
+	c.Pacing = Pacing{Mode: PacingMinimum, Period: "5s"}
+	c.PrintAndValidateConfig()
+	assert.Equal(t, "each iteration takes at least 5s", c.Pacing.String())
+
+	c.Pacing = Pacing{Mode: PacingMinimum, Period: "soon"}
+	c.PrintAndValidateConfig()
+	assert.Equal(t, Pacing{}, c.Pacing)
+	assert.Equal(t, "", c.Pacing.String())
+
+	// The ArrivalRate executor has a schedule of its own.
+	c.Pacing = Pacing{Period: "10s"}
+	c.Executor = ArrivalRateExecutor
+	c.PrintAndValidateConfig()
+	assert.Equal(t, Pacing{}, c.Pacing)
+}
+
+func TestPerfStatsAverageThinkTime(t *testing.T) {
+	count := func(n uint64) *uint64 { return &n }
+	ps := &PerfStats{
+		ServiceThinkTime:  map[string]*uint64{"search": count(uint64(3 * time.Second))},
+		ServiceThinkCount: map[string]*uint64{"search": count(4)},
+		OverAllThinkTime:  uint64(3 * time.Second),
+		OverAllThinkCount: 6,
+	}
+	assert.Equal(t, 750.0, ps.GetServiceAverageThinkTime("search"))
+	assert.Equal(t, 0.0, ps.GetServiceAverageThinkTime("checkout"))
+	assert.Equal(t, 500.0, ps.GetAverageThinkTime())
+	assert.Equal(t, 0.0, (&PerfStats{}).GetAverageThinkTime())
+}
+
+func TestPhaseTimes(t *testing.T) {
+	pt := &PhaseTimes{}
+	pt.Add(&PhaseTimes{Count: 1, DNS: uint64(4 * time.Millisecond), TTFB: uint64(10 * time.Millisecond)})
+	pt.Add(&PhaseTimes{Count: 1, TTFB: uint64(20 * time.Millisecond)})
+
+	assert.Equal(t, uint64(2), pt.Count)
+	assert.Equal(t, 2.0, pt.Average(pt.DNS))
+	assert.Equal(t, 15.0, pt.Average(pt.TTFB))
+	assert.Equal(t, int64(15*time.Millisecond), pt.AverageTTFB())
+
+	var none *PhaseTimes
+	assert.Equal(t, 0.0, none.Average(0))
+	assert.Equal(t, int64(0), none.AverageTTFB())
+}
+
+func TestStepStats(t *testing.T) {
+	ss := &StepStats{Type: "repeat"}
+	ss.Add(&StepStats{Count: 1, Passes: 2})
+	ss.Add(&StepStats{Count: 1, Passes: 5, Timeouts: 1})
+
+	assert.Equal(t, StepStats{Type: "repeat", Count: 2, Passes: 7, Timeouts: 1}, *ss)
+	assert.Equal(t, 3.5, ss.AveragePasses())
+
+	var none *StepStats
+	assert.Equal(t, 0.0, none.AveragePasses())
+}
+
+func TestGetOperationStats(t *testing.T) {
+	count := func(n uint64) *uint64 { return &n }
+	ps := &PerfStats{
+		ServiceResponseTimes: map[string]int64{"getOrder": 10e6, "getOrderDetails": 40e6, "addItem": 5e6, "login": 1e6},
+		ServiceTransCount:    map[string]*uint64{"getOrder": count(3), "getOrderDetails": count(1), "addItem": count(2)},
+		ServiceErrorCount:    map[string]*uint64{"getOrder": count(1), "addItem": count(0)},
+		ServiceOperations:    map[string]string{"getOrderDetails": "GetOrder", "getOrder": "GetOrder", "addItem": "AddItem"},
+	}
+
+	operations := ps.GetOperationStats()
+	assert.Equal(t, 2, len(operations))
+	assert.Equal(t, &OperationStats{Name: "AddItem", Services: []string{"addItem"}, TransCount: 2, ResponseTime: 5e6}, operations[0])
+	assert.Equal(t, &OperationStats{Name: "GetOrder", Services: []string{"getOrder", "getOrderDetails"}, TransCount: 4, ErrorCount: 1, ResponseTime: 17500000}, operations[1])
+
+	// Without transaction counts, each test case weighs the same.
+	ps.ServiceTransCount = nil
+	assert.Equal(t, int64(25e6), ps.GetOperationStats()[1].ResponseTime)
+	assert.Equal(t, 0, len((&PerfStats{}).GetOperationStats()))
+}
+
+func TestPerfStatsMerge(t *testing.T) {
+	count := func(n uint64) *uint64 { return &n }
+	ps := &PerfStats{
+		ServiceTransCount: map[string]*uint64{"search": count(10)},
+		ServiceErrorCount: map[string]*uint64{},
+		ServiceAssertionFailures: map[string]map[string]*uint64{
+			"search": {"jmesPath items exists": count(1)},
+		},
+		OverAllTransCount: 10,
+		IterationCount:    5,
+		Workloads: []*WorkloadStats{{
+			Name:                 "browse",
+			ConcurrentUsers:      2,
+			TransCount:           10,
+			ServiceResponseTimes: map[string]int64{"search": 100},
+			ServiceTransCount:    map[string]*uint64{"search": count(10)},
+		}},
+	}
+	other := &PerfStats{
+		ServiceTransCount:       map[string]*uint64{"search": count(30), "checkout": count(2)},
+		ServiceErrorCount:       map[string]*uint64{"checkout": count(1)},
+		ServiceRetryCount:       map[string]*uint64{"search": count(3)},
+		ServiceConfiguredMix:    map[string]float64{"search": 1},
+		ServiceCorrectedSamples: map[string][]int64{"search": {5, 7}},
+		ServiceThinkTime:        map[string]*uint64{"search": count(900)},
+		ServiceThinkCount:       map[string]*uint64{"search": count(30)},
+		ServicePhaseTimes:       map[string]*PhaseTimes{"search": {Count: 30, TTFB: 600}},
+		ServiceOperations:       map[string]string{"search": "Search"},
+		StepStats:               map[string]*StepStats{"waitForOrder": {Type: "until", Count: 2, Passes: 5, Timeouts: 1}},
+		ServiceAssertionFailures: map[string]map[string]*uint64{
+			"search": {"jmesPath items exists": count(4)},
+		},
+		TransactionSamples:    map[string][]int64{"checkout": {30, 0}},
+		TransactionTransCount: map[string]*uint64{"checkout": count(2)},
+		TransactionErrorCount: map[string]*uint64{"checkout": count(1)},
+		TransactionThresholds: map[string]int64{"checkout": 50},
+		OverAllTransCount:     32,
+		OverAllErrorCount:     1,
+		OverAllRetryCount:     3,
+		OverAllThinkTime:      900,
+		OverAllThinkCount:     30,
+		IterationCount:        15,
+		PacedIterations:       15,
+		OverrunIterations:     2,
+		AbortReason:           "errorRate above 5%",
+		Workloads: []*WorkloadStats{{
+			Name:                 "browse",
+			ConcurrentUsers:      2,
+			TransCount:           30,
+			ServiceResponseTimes: map[string]int64{"search": 200},
+			ServiceTransCount:    map[string]*uint64{"search": count(30)},
+		}},
+	}
+
+	ps.Merge(other)
+	assert.Equal(t, uint64(40), *ps.ServiceTransCount["search"])
+	assert.Equal(t, uint64(2), *ps.ServiceTransCount["checkout"])
+	assert.Equal(t, uint64(1), *ps.ServiceErrorCount["checkout"])
+	assert.Equal(t, uint64(3), *ps.ServiceRetryCount["search"])
+	assert.Equal(t, []int64{5, 7}, ps.ServiceCorrectedSamples["search"])
+	assert.Equal(t, uint64(42), ps.OverAllTransCount)
+	assert.Equal(t, uint64(1), ps.OverAllErrorCount)
+	assert.Equal(t, uint64(3), ps.OverAllRetryCount)
+	assert.Equal(t, uint64(900), *ps.ServiceThinkTime["search"])
+	assert.Equal(t, uint64(30), *ps.ServiceThinkCount["search"])
+	assert.Equal(t, uint64(900), ps.OverAllThinkTime)
+	assert.Equal(t, uint64(30), ps.OverAllThinkCount)
+	assert.Equal(t, PhaseTimes{Count: 30, TTFB: 600}, *ps.ServicePhaseTimes["search"])
+	assert.Equal(t, map[string]string{"search": "Search"}, ps.ServiceOperations)
+	assert.Equal(t, StepStats{Type: "until", Count: 2, Passes: 5, Timeouts: 1}, *ps.StepStats["waitForOrder"])
+	assert.Equal(t, uint64(5), *ps.ServiceAssertionFailures["search"]["jmesPath items exists"])
+	assert.Equal(t, []int64{30, 0}, ps.TransactionSamples["checkout"])
+	assert.Equal(t, uint64(2), *ps.TransactionTransCount["checkout"])
+	assert.Equal(t, uint64(1), *ps.TransactionErrorCount["checkout"])
+	assert.Equal(t, int64(50), ps.TransactionThresholds["checkout"])
+	assert.Equal(t, uint64(20), ps.IterationCount)
+	assert.Equal(t, uint64(15), ps.PacedIterations)
+	assert.Equal(t, uint64(2), ps.OverrunIterations)
+	assert.Equal(t, 1.0, ps.ServiceConfiguredMix["search"])
+	assert.Equal(t, "errorRate above 5%", ps.AbortReason)
+
+	// The counters of other are copied, not shared.
+	*ps.ServiceTransCount["checkout"]++
+	assert.Equal(t, uint64(2), *other.ServiceTransCount["checkout"])
+
+	assert.Equal(t, 1, len(ps.Workloads))
+	assert.Equal(t, 4, ps.Workloads[0].ConcurrentUsers)
+	assert.Equal(t, uint64(40), ps.Workloads[0].TransCount)
+	// Weighted by the transactions of each.
+	assert.Equal(t, int64(175), ps.Workloads[0].ServiceResponseTimes["search"])
 }
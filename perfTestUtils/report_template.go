@@ -71,7 +71,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _reportContentTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x58\x5f\x6f\xe3\x36\x12\x7f\xce\x7e\x8a\x81\x9a\x20\x09\xd0\xb5\x9d\xdd\x66\x81\x6a\x65\x03\x4e\x6e\xaf\x4d\x6f\xb3\x35\xea\x5c\x5f\x8a\x3e\xd0\xd2\xc4\xe6\x45\x22\x75\x24\xed\xc4\xf5\xea\xbb\x1f\x48\xfd\x97\x28\xd9\xd9\xec\xe2\x5e\x4a\xc0\x0f\x16\xe7\x1f\x67\x7e\x33\x9c\xe1\x6e\x17\xe0\x3d\x65\x08\x8e\xcf\x99\x42\xa6\x9c\x24\x79\x05\xe0\x05\x74\x03\x7e\x48\xa4\x1c\x3b\x8a\xc7\x57\x44\x38\x93\x57\x50\x59\xde\xea\x22\xdf\x8f\x49\x10\x50\xb6\x74\x26\xbb\xdd\xe0\x9a\xb3\x7b\xba\x1c\x4c\x67\x37\x9f\x48\x84\x49\x02\xae\x0b\xd3\xb5\xe2\x11\x51\x18\xc0\x0c\xc5\x3d\x17\x11\x61\x3e\xc2\x1d\x4a\x05\xbf\x61\xcc\x85\xd2\x44\x67\xbb\xdd\x40\x6f\xcf\x15\x51\x72\xf0\x13\x2a\xbd\x7f\x47\x23\x9c\x2b\x22\x54\x92\x80\xe2\xd0\x45\xf2\x81\x05\x49\x72\xee\x0d\x57\x17\xa5\x8d\xde\x30\xa0\x9b\xca\xdf\xca\x79\x02\xba\xf9\x19\x49\x6a\x72\x79\x26\x4f\x91\x45\x88\x16\x1a\x58\x70\x11\xa0\x18\x3b\x23\x07\x1e\x69\xa0\x56\x63\xe7\xc7\xd1\x49\x85\xd5\x53\xa2\xee\x9b\x9a\x9f\x54\x90\x73\x5d\x6a\x2e\x6f\xf5\xae\xe5\xb7\x9f\xb9\x54\xb0\x66\x01\x0a\x50\x28\x95\x0b\xa5\x23\xef\x88\x58\xa2\xd2\x04\x49\xe2\x36\x3f\xcf\xb8\xf6\x8c\x37\x5c\xbd\x9b\x78\x43\x15\x74\x1b\xd1\x63\xd4\x9b\xcb\x0e\xa3\xe6\x28\x36\xd4\x47\xd9\x30\x2c\x44\x06\x95\x28\x64\x54\xbf\xa1\x8c\x39\x93\xa8\xa3\x21\xbf\x99\x49\x7b\xc4\x7a\xc3\xae\x40\x78\x43\x13\xdc\xae\x4d\x83\x94\xa3\xa3\xdd\x8e\xde\x03\xe3\x0a\x72\x2f\xcf\x1f\x68\x7c\x8b\xd1\xf5\x0a\xfd\x07\x93\x15\x19\x83\x1d\x4b\xc0\x99\x1f\x52\xff\x61\xec\xac\x68\x80\xb7\x18\x71\xb1\x9d\x32\x12\x6e\x25\x95\x67\xe7\xcd\xf4\xf9\x62\xb4\x95\x12\xba\x0e\xdb\x42\xdc\xdb\x96\x27\x53\xeb\x20\x37\xcf\x1b\xae\xde\xf6\x39\x76\x7f\x6c\x40\xaa\x6d\x88\x63\xe7\x71\x45\x15\xbe\x96\x31\xf1\xd1\x65\xfc\x51\x90\xd8\x99\x4c\xc3\x90\x3f\x62\x00\xbf\x13\x41\x4d\xea\x57\x01\x6e\x36\xb5\x2f\x66\x48\x1e\x52\xb3\x0a\xba\xcf\x10\x0b\xca\xd4\x3d\x38\x27\x3f\x0c\xde\xdc\x3b\x49\x72\xb2\x0f\x02\x07\xa0\xc8\x84\x79\x70\x23\x53\x65\x33\x22\x25\x24\x89\x77\xcf\x99\x02\x9f\x87\x5c\x8c\x9d\xa5\x40\x64\xce\x64\x36\x9d\xcf\xbd\xa1\xde\x98\xec\x76\x18\x4a\x6c\x90\x09\x0c\x9c\xc9\x3f\xa7\x37\x1f\x4b\x22\x5d\x88\x7a\x4c\x6c\x23\xb4\x85\x4c\x5b\xdd\xa2\xc1\xd8\x89\x8c\xb5\xd7\x9c\x29\x42\x19\xb6\xaa\x71\xb5\x5c\x1b\x6f\xe6\xa7\xb5\xe0\xa6\x8a\xbc\x22\x7e\xbd\x58\xeb\xc5\x5b\xee\xf7\x5c\xc4\xc5\xbb\x91\x33\xf1\xae\x26\x57\x44\x22\xe8\xa8\x42\xea\x69\xd7\x1b\x5e\xed\x29\x0a\x9e\x0a\xf4\x25\xa2\x39\xcb\x12\x93\xfe\xcb\xc1\x01\x9f\x21\xc2\xe8\x8e\xdf\x5e\xc1\x67\x30\x97\x89\xba\xc5\x28\x49\x6e\xaf\xf6\x8a\x2e\x0c\xbc\xd4\x06\x2e\x26\xe6\x06\xaa\x1b\xb8\x38\xcc\xc0\xd2\xb8\xaf\x6b\xd8\x45\x6a\xd8\x49\x91\x2a\x87\x99\x04\x65\xe5\xaa\xc2\x3a\x49\xb2\xa4\x34\x78\x75\x35\x5c\x33\x88\xa6\x67\x68\xe6\xdb\x0c\x85\x8f\x4c\x91\x25\xd6\x4f\x70\xf2\xdc\x92\x6b\x2d\xb7\x19\xb0\xbb\x60\x7b\xea\xe7\xd0\x3e\xb5\x08\xac\xd2\xad\x88\x50\x16\x1a\xa8\x64\xcb\xe9\x47\xca\xf0\x3a\x25\x6c\x24\x54\xc3\x9c\x7d\x9f\xa4\x2f\x68\xac\xda\xec\x1b\x22\xa0\x50\xf2\xcb\x1c\xc6\xe0\xbf\x1d\x2c\x91\xa1\x20\x0a\xcf\x76\x2d\xfa\x80\x28\xe2\x42\xfb\xbb\x5e\x3e\x0f\xd7\x11\x93\x2e\xfc\xd1\x15\xe4\xdd\xee\x3f\x92\xb3\x5b\x8c\xc0\xd1\xd9\xe0\x40\x23\x45\xb2\xcb\x66\x1d\x50\x95\x24\xdf\x1f\x20\x45\x43\xdf\xa9\xde\xe3\x35\x09\x56\x01\x7f\xb6\xbe\x5a\x34\x49\xfa\x17\x76\x1d\x73\x85\x74\xb9\x52\x2e\x5c\x8e\x46\x87\x88\x0a\x71\x89\x2c\xe8\x12\x26\x57\xfc\xd1\x05\x25\xd6\x68\x3f\x6e\xcc\x25\x55\x94\x33\x17\x4e\x29\x93\xa8\x4e\xed\x64\x66\xaf\x4b\x87\x5e\x84\xf9\x2b\x2e\x5c\x38\x55\x3c\x7e\x2d\xf4\x01\x4e\xad\xb4\x6d\x9f\x59\x8e\xf4\x17\xe7\x51\x97\x32\x64\x3a\x65\x82\xf4\x4c\x87\x08\x03\xb9\x5e\x98\x5c\xd8\xef\xa2\x43\xc4\x91\x27\x2a\xbb\x24\x6d\xfb\x3c\x14\x92\x05\x86\x2e\x9c\x66\x55\xf0\xec\x5f\x57\xe7\x1d\x2e\x6a\x2b\xb5\x7c\x5a\x0a\xda\x19\x74\x78\xea\x35\x84\x32\xec\x4b\xa2\xda\xda\xed\x06\xbf\xcc\x7f\xfd\xa4\xf3\x60\x46\x84\x32\x58\x91\x1d\xc8\xaf\xae\x76\x16\x14\xcb\xce\x5c\xff\x9a\x9c\xbf\xaf\x53\x1d\x9f\x39\xdf\x15\x75\xc4\x39\x1f\x90\x38\x46\x16\x9c\x55\x4a\xcb\x00\x43\x8c\x90\xa9\x06\xa7\x37\x6c\x96\xa6\x4a\x1f\x6b\x8a\xfc\xab\xe7\x35\xac\x59\x27\xff\x7f\xea\x58\xad\x5d\x6a\x66\x12\xe4\xd3\x05\xe8\xf1\xe2\x19\x4d\xeb\xb7\x69\x54\x2d\x23\xcf\xcb\x3a\x56\xfb\xac\x53\xed\x34\xf3\x96\x55\xeb\x4a\x6f\xf6\x4a\x8b\x9a\xf7\xa6\x45\x3b\x5a\xf4\xa1\xa6\x2b\xfd\x36\xed\xa8\x4c\x9d\x60\xeb\x47\x0f\xec\x45\x33\x3c\xd5\x10\x73\x74\x94\x4d\x61\xf8\x5f\x18\xe8\xdc\x9c\x2b\x7d\x9f\x2e\xb7\xe0\xcc\xd7\x54\xa1\xbe\xf5\x02\x27\x49\x0c\xe1\x91\xa7\x44\x1e\xcd\x05\xf1\x1f\x96\x82\xaf\x59\xe0\x7e\xd4\x45\xfa\x27\x41\xb6\xef\x41\xe1\x93\x7a\x4d\x42\xba\x64\xae\x29\xdd\x99\x06\xcd\x19\x68\xd7\xca\x98\xb0\xb1\xf3\x43\x81\x09\xed\xaf\xd7\xe6\x0a\x93\x11\x09\x43\x14\xef\xc1\x06\x93\x5f\x37\x28\xa6\x61\x08\xd7\x7c\xcd\x94\x74\x53\xdf\x96\x82\x9f\x27\xec\x4e\x10\x26\x89\x6f\xea\x0f\xfc\x51\xeb\x2d\x33\x3d\x86\xc2\xe8\x4a\x92\x3f\x5f\xa6\xec\x83\x10\x5c\x74\xa8\x31\x7b\x5f\x47\xcd\xdd\x6c\xde\x71\x94\xd9\xdc\x92\x22\x55\x6d\x29\x24\x53\x1c\xa4\x55\xcc\x52\x45\xfa\xa2\xde\x39\xbd\x34\x06\xc3\xb4\xfd\xff\x44\x22\xec\x6f\xb1\xab\xed\xf9\xdb\x94\x51\xa3\xd0\x54\xa2\xb3\x5b\x1a\x86\xf4\xfc\xd9\x02\xf2\x77\xab\x2f\x16\x70\xb2\xc9\x0a\x4e\xc9\x79\x74\x78\xee\xec\x53\xf2\x26\xb3\xb2\xc0\x5d\xdd\xbe\x16\xbf\x85\xb7\x04\x53\xc3\xc2\x23\x9b\xa2\xd9\xbc\x75\x8e\xc6\x0d\x56\xa8\x32\xf0\xc8\xf0\x21\x08\x5b\x22\x1c\x3f\xe0\xf6\x7b\x38\x5e\xe8\x69\xd3\x1d\xc3\xb1\x65\x7a\xb4\x3f\x52\xe5\xaa\x8e\xc9\x66\xa9\x39\x29\x0b\xf0\x09\x8e\xf7\x3c\x6e\x19\x7d\x15\x66\x15\xcb\x5e\x66\x0d\xf9\x26\x8b\xf0\xfb\x59\x0a\xc7\x37\x39\x71\x0f\x67\xe9\xf6\x97\x9b\xa9\x27\xca\xd4\xbb\xe0\x98\x37\xd8\x7f\xc7\x45\xe5\x35\xa5\x37\xed\xe7\xc7\x17\xa3\xf8\x29\x0f\xee\x51\x3a\x1f\xa7\x82\xaa\x51\xcf\x36\xf4\xcd\x90\x46\xea\x02\xdf\xd5\x87\xcc\x2e\x6a\x1d\x9c\x7e\xe2\x1c\xf4\x86\x74\x54\x98\x58\x2b\x5c\xe5\x04\x6c\x6e\xc8\x0f\xff\x68\x48\x48\x6f\xd0\x1a\xa7\x11\x7b\x3c\xb8\x91\xb9\x87\xb2\xbb\x37\x73\x53\xce\xd3\x33\x62\x93\xcd\xf2\x77\x22\x52\xbb\xd2\x53\x5b\xdb\x83\xba\x21\x06\xf7\xcd\x93\xed\xbf\x0b\x4b\xdf\x2b\xe1\x37\x1c\x94\xef\x60\xe7\x8e\x46\x47\xdb\xb6\x1e\xd3\xca\x32\x5d\xdb\xb0\xd5\xec\x83\x5a\x8a\x3d\x6f\x00\x87\xcc\xff\xc5\xec\xbf\x20\xa2\x7b\xf4\x6f\x2a\x6f\xfc\xb5\x8c\xfb\x7a\xd2\xcf\x45\xee\x1f\xf4\xbf\xe2\x00\xfc\xb2\x27\x83\x62\xc2\xa1\x11\x4e\x85\x20\xdb\xae\xb1\xde\x3e\x18\xab\x6d\x8c\x2e\x68\x5f\xb6\x87\xb9\xbf\x87\xf5\x67\x0f\xeb\x0b\x22\xba\x64\x99\xcb\xb0\xef\x54\x82\x28\xca\x5d\x18\x0d\x2e\xbf\xfc\x30\x2f\x9e\xee\xa7\x9b\xa5\x19\xc3\xa0\xd2\xb7\xcc\xd1\xe7\x2c\x90\x87\x8f\xfb\xb0\x6f\x84\xcf\x50\xe7\xeb\x3a\xc7\xc5\xb6\x03\x07\x7a\x65\x24\x54\x4f\xfc\x15\xa4\x67\xb5\x5a\xb7\x75\xb2\xe7\x25\x0c\x14\xf5\x1f\xfa\x0c\xd1\x4b\x70\x45\x14\xba\xf0\xe3\xa8\x5b\x8e\x5e\xd1\x3a\x54\x34\xa4\x0c\x5d\xb8\x27\xa1\x6c\xe3\x21\x5f\x3d\xf6\xe4\xa5\xe1\x8d\xa5\x34\x80\x3d\xc8\xbd\xef\x0a\xc7\x67\xce\x77\x79\xd1\x2a\x5f\x15\xca\x32\x66\x79\x54\xe8\x7c\x50\x28\xfe\x2e\xc4\xa4\xef\xf7\x2a\xaf\xfe\xff\x0b\x00\x00\xff\xff\x89\x9a\xdc\xce\x53\x1e\x00\x00")
+var _reportContentTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xec\x3c\x6b\x6f\xe3\x36\xb6\x9f\xd3\x5f\x71\xa0\x66\x90\x04\x48\x9d\x4c\x67\xa7\xc0\xba\x4e\x80\xcc\xa3\xb3\xb3\x37\x69\x73\xe3\xb4\xf7\x43\xd1\x0f\x8c\x74\x6c\xf3\x46\x26\x55\x92\x4e\xe2\x75\xfd\xdf\x17\x7c\x48\xa2\x9e\x96\xed\x78\x77\x02\x4c\x80\xc1\xc0\x12\xcf\x83\xe7\x1c\x1e\x9e\x07\xa9\xc5\x22\xc2\x11\x65\x08\x41\xc8\x99\x42\xa6\x82\xe5\xf2\x1b\x80\x41\x44\x1f\x20\x8c\x89\x94\x67\x81\xe2\xc9\x3b\x22\x82\xf3\x6f\xc0\xfb\x1b\x4c\x5e\xa7\xef\x13\x12\x45\x94\x8d\x83\xf3\xc5\xa2\xf7\x9e\xb3\x11\x1d\xf7\x2e\xae\x3f\xff\x4c\xa6\xb8\x5c\x42\xbf\x0f\x17\x33\xc5\xa7\x44\x61\x04\xd7\x28\x46\x5c\x4c\x09\x0b\x11\x6e\x51\x2a\xb8\xc1\x84\x0b\xa5\x07\x1d\x2e\x16\x3d\xfd\x7a\xa8\x88\x92\xbd\x4f\xa8\xf4\xfb\x5b\x3a\xc5\xa1\x22\x42\x2d\x97\xa0\x38\x34\x0d\xf9\xc8\xa2\xe5\xf2\x68\xb1\xa0\x23\xf0\x06\x0c\x15\x4f\x6e\x90\x48\xce\x2c\x1b\xd7\x17\x37\xb7\x9f\x2f\x2e\x17\x0b\xd4\xc3\x07\x27\x93\xd7\xf9\x8c\x06\x27\x11\x7d\xc8\x7f\xb6\xa1\xca\x61\x3c\x11\x45\xf4\xe1\x1f\x48\xac\x14\x4a\x62\x7a\x53\x16\x13\x48\x35\x8f\xf1\x2c\x08\x79\xcc\x45\x5f\x60\x14\x9c\x3b\xd6\xe0\xe6\xe3\xf0\xd7\xcb\xdb\x61\x1f\xd4\x04\x41\x69\x01\x89\x19\x03\xa9\x78\x92\x60\x04\x48\x44\x3c\x2f\x09\xca\xe7\xec\xa8\x07\x3f\xd1\xf1\x4c\xa0\x84\x90\x3f\xa0\x00\xce\xe2\xb9\x41\x25\xf0\xcf\x19\x4a\xa5\x9f\x4f\x93\x18\xb5\x2a\xee\x70\xc4\x05\x02\x55\x29\xfa\xde\xe0\x64\xf2\xa6\x45\x22\x46\x6a\x6b\x4c\x7e\xa0\xc8\x5d\x8c\x35\x63\xe0\x8e\x8b\x08\xc5\x59\x70\x1a\xc0\x23\x8d\xd4\xe4\x2c\xf8\xfb\xe9\x2b\x0f\x74\xa0\x44\x51\x88\xfe\xdf\x40\x45\x29\xd4\x5b\x0d\x35\x98\xfc\x50\xb1\xc3\x7f\x70\xa9\x60\xc6\x22\x14\x46\x88\x7d\xc8\x0d\xf3\x96\x88\x31\x2a\x3d\x60\xb9\xec\x97\x1f\x5f\x73\x6d\x69\x83\x93\xc9\x0f\xe7\x83\x13\x15\x35\x33\xd1\xc2\xd4\xf7\x6f\x1b\x98\x1a\xa2\x78\xa0\x21\xca\x12\x63\x31\xb2\x82\xa5\xd9\x51\x37\x28\x13\xce\x24\x6a\xeb\x96\x3b\x63\x69\x05\xda\xc1\x49\x93\x22\xb6\xd6\xd0\xcd\x8c\x41\x8c\x6c\xac\x26\x7d\xb7\xd8\x9c\x22\x3e\xcc\x04\x51\x54\x5b\xf3\x62\x51\xf7\x0c\x63\x89\xfe\xbb\x9f\x67\xd3\xcf\x0a\xed\x7b\xb9\x5c\x02\xcd\x7e\xe4\xeb\x7c\x17\xa2\x33\x4c\xe3\x9f\xd0\xd3\x3e\x68\xa8\x04\x51\x38\x9e\x43\x30\x9c\x51\x85\xef\x88\xc4\x28\x58\x2e\x73\xc6\xf2\x75\xd7\x2f\x3a\xb1\x6c\xc8\x7b\x3e\x63\x6a\xb9\xdc\x2d\xd3\x5a\xea\x8a\x4e\xb1\x5f\xeb\x49\x73\x31\x6f\x6c\x17\x65\xbf\x79\x4d\x42\x8c\x7c\xfd\x6c\x66\x4d\x21\x8f\x65\x42\xd8\x59\xf0\xa6\x7e\x5e\xd7\x24\xa4\x6c\xec\x2f\x73\xfb\xa4\x37\x54\x82\xb2\xb1\x75\xff\x65\xde\x7e\x79\x40\x21\x66\xcc\xe7\x6e\x30\xe2\x4c\x81\xf1\xcb\x67\x81\x71\xcc\x8b\x45\x3b\x04\xf0\x51\x51\x96\x95\x19\x7b\x16\x09\xda\x29\x0b\xc2\x40\x4d\x90\x0a\x48\x0c\x8f\xf0\x48\x59\xc4\x1f\x07\x27\x9a\xf6\x79\x6a\xe0\x8c\xe7\x70\x19\x18\x55\xb2\x08\xd4\xcd\x5a\xda\xd4\x55\x74\xea\x6d\xaa\xd4\x93\xbf\x88\xe3\xdb\x09\x65\xf7\xda\x2b\xed\x4a\x97\x86\x40\x66\xa5\x9e\x67\xf0\x28\x2f\x16\x35\x0f\x61\x2a\x53\xe9\x09\xc2\x22\x3e\x85\x08\x63\x32\xd7\x1a\x9a\x25\x2e\x7a\x70\x50\x37\x76\x3b\xfc\xa0\xdf\xa7\x80\x5a\x10\xc7\xc0\x05\x10\x09\x12\x15\x24\xce\x47\x43\x48\x24\x6a\xfb\x21\x0f\x28\xc8\x18\x2b\x4b\xe7\xc2\x3e\xcf\x38\x81\xbf\x20\x11\x94\xa9\x11\x04\xaf\x7a\xdf\x8f\x02\x43\xc0\xa0\x73\xbb\x30\x1c\x32\xae\x20\x21\x42\x69\xde\xec\xf6\x6c\xbd\xbd\x99\xb6\x3c\xda\xa1\x3e\x9d\x00\x2e\x39\x89\xae\x05\x1f\xd1\x78\x67\x7a\xd4\x24\x20\xb1\x34\xb4\x26\x05\x61\x63\x84\x7d\x7a\x0c\xfb\x52\x69\x39\xf6\xcf\xea\xd9\x31\x8c\xee\xd3\xe5\x12\xfe\x4a\x27\xb4\x58\x58\x98\x6c\x3d\xef\xde\xf0\xd3\x38\xf6\x8e\x0b\xf5\x5e\xe8\xb5\x48\xc9\xae\x44\x65\x88\x40\xe8\xa8\x94\x84\xe5\x1e\x73\xe6\x0b\xac\xc4\x56\xbd\xc8\x32\xc8\xb2\xd8\xca\x4b\xdb\x60\xf3\x23\xe5\xaa\x1f\xbc\xbd\xf9\xfc\xe9\xd3\xc7\x9b\x8f\x1f\x4a\x5b\x47\x01\x34\xf7\x61\xbb\x55\x0e\xfe\x99\x09\xe2\xe3\x13\x86\x33\xc5\x05\x04\x17\x42\xd0\x07\x12\xdf\x10\x85\xc1\xa6\x8a\x5a\x15\xb5\xa4\xd4\xfa\xe0\x51\x03\xa2\x2a\xf1\xa5\x7e\x5e\x70\xfd\x27\x12\x43\x38\x9c\x92\x27\x6f\xe8\x15\x79\xfa\x8d\x0a\x35\x23\xf1\xaf\x12\x85\xde\x2a\x66\xfa\xff\xa3\x1d\x6d\xfe\x1f\x84\xcd\x22\x72\xa6\x4a\xba\x74\x03\x0a\x1b\xe2\x6e\x58\xb9\xd4\x62\x6b\xe4\x43\xbf\x5d\x83\x89\xcd\x0c\x69\x70\x62\xd2\x93\x26\x94\x26\xf7\xd9\xdb\x33\xe6\xa6\xbd\x75\xaa\xb3\xe1\x3d\x4d\xae\x70\xfa\x7e\x82\xe1\xfd\xea\x6c\x08\x38\x0b\x63\x1a\xde\x9f\x05\x13\x1a\xe1\x15\x4e\xb9\x98\x5f\x30\x12\xcf\x25\x95\x87\x47\xe5\x4c\x71\xe3\x7c\x69\xa5\x7d\x57\x6d\xbb\x92\x94\x9e\x5b\xee\x20\x65\xcf\x24\x83\x2d\x42\x5f\xad\xe5\x34\xcd\x7d\x9c\x50\x85\xdf\xc9\x84\x84\xd8\x67\xfc\x51\x90\x24\x38\xbf\x88\x63\xfe\x88\x11\xfc\x46\x04\x35\xc5\x00\x3f\x76\x33\x2f\xb5\x2c\xae\x91\xdc\x5b\xb6\xb2\x71\xde\xde\xfa\x37\xbb\xb9\xbe\x5a\x65\x1e\x5d\x83\xf9\xde\x67\x69\x89\x5d\x13\x29\xa1\x14\x0c\x8e\x05\x22\xd3\x79\xfa\x70\x58\x8a\xd4\xaa\xbe\xf2\xa7\x8b\xcf\x97\x9d\x5d\x61\xd5\x7a\x2b\x96\x59\xca\xc4\x8d\xad\xd1\xe8\x2c\x98\x1a\x6e\xdf\x73\xa6\x08\x65\x58\xa9\xcf\xf8\x05\x1c\x23\xcd\x74\xb6\x35\x76\xe3\x5b\x5e\xa6\xbf\x56\x5b\xeb\xe4\x4f\x53\x14\xaf\x7f\x38\x0d\xce\x07\xef\xce\x75\x62\x04\x5a\xab\x60\x25\xdd\x1f\x9c\xbc\x5b\xe1\x5e\x06\x2a\xd2\x41\xb8\x86\xcc\x3d\x84\xfd\x95\x1a\x07\xfc\x05\x53\x9c\xde\xf2\xab\x77\xf0\x17\x98\xf2\x92\xba\xc2\xe9\x72\x79\xf5\x6e\x25\xea\x8c\xc1\xb7\x9a\xc1\xbb\x73\x53\x93\x2a\x32\x78\xd7\x8d\x41\x2f\x03\x78\x56\xc6\x5e\x5b\xc6\x5e\x65\x4b\xa5\x1b\x4b\x90\x7b\x2e\xdf\xac\x97\xcb\x4a\xed\xc9\x99\xa8\x9d\x43\x79\xbd\x5d\xa3\x08\x91\x99\x70\xad\x30\x83\x57\xeb\xba\xe3\x5a\x77\xeb\x0c\xbb\xc9\x6c\x0f\xc2\xd4\xb4\x0f\x6a\x10\xfa\xe3\x26\x44\xa8\x83\x06\x76\xdc\x6a\x39\xb8\xa4\x0c\xdf\xdb\x81\xa5\x05\xd5\xb0\xce\x9a\x1e\xc9\x50\xd0\x44\x55\xc1\x1f\x88\x80\x8c\xc8\x3f\x87\x70\x06\xe1\x9b\xde\x18\x99\xde\xc8\xf0\x70\x51\x19\x1f\x11\xa5\x63\xbd\x5a\xae\x43\x1e\xcf\xa6\x7a\x63\xfc\xbd\x49\xc9\x8b\xc5\xff\x4b\xce\xae\x70\x0a\x81\x5e\x0d\x01\x94\x96\x88\xdb\x6c\x66\x11\x55\xcb\xe5\x71\x07\x2c\xda\xf4\x03\xe8\x35\x60\xa8\x45\xf0\x47\xe5\x69\x0d\x25\x49\xff\x85\x4d\xd3\x9c\x20\x1d\x4f\x54\x1f\xde\x9e\x9e\x76\x41\x15\xe3\x18\x59\xd4\x84\x4c\x4e\xf8\x63\x1f\x94\x98\x61\xfd\x74\x13\x2e\xa9\x8e\x28\xfa\x70\x40\x99\x44\x75\x50\x3f\xcc\xbc\x6b\xa2\xa1\xff\x08\x0b\x27\x3a\x04\x3c\x50\x3c\xf9\x4e\xe8\x09\x1c\xd4\x8e\x5d\x76\x99\xd2\xbf\x38\x9f\x36\x11\x43\xa6\x97\x4c\x64\xe7\xd4\x05\x19\xc8\xd9\x9d\x59\x0b\xab\x45\xd4\x05\x1d\x79\xa2\xb2\x09\xd3\xbc\x4d\x42\x31\xb9\xc3\xb8\x0f\x07\xce\x0b\x1e\xfe\xcf\xbb\xa3\x06\x11\x1d\x77\xe1\x63\x2c\x68\xa3\xd2\xe1\xa9\x95\x11\xca\xb0\x6d\x11\x95\xd6\x42\xef\x9f\xc3\x5f\x7e\xd6\xeb\xe0\x9a\x08\x45\xdb\x2a\x56\xed\xab\xa0\xc5\x04\xaa\x4f\x97\x47\x3f\x16\x47\xed\x1f\x06\xdf\x66\x7e\x24\x38\xea\x91\x24\x41\x16\x1d\x7a\xae\xa5\x87\x31\x4e\x91\xa9\x12\xe4\xe0\xa4\xec\x9a\xbc\x38\xd6\x46\xc2\xeb\x05\xac\xae\x16\xfd\x5f\x8a\x58\x6b\xa3\x54\xc7\x12\xa4\xf5\x71\x30\x15\x97\xee\x41\xeb\x6e\x02\xd5\x9a\xa2\xfd\x76\x11\x6b\x7d\xb5\xde\x8f\x34\xd3\x90\x55\xd3\xb2\x3b\xbb\x17\xa2\x66\x75\xb0\x34\x1c\xcd\xe2\x50\x8d\xe2\x7c\x37\xe1\xa8\xb4\x42\xa8\x8b\x47\x3b\xc6\xa2\xce\x9e\x0a\x16\xb3\xb7\xb7\xb7\x97\x25\xfd\x2d\xb5\x76\x33\x70\x6f\xa0\x44\xaa\xcd\x3b\x12\xde\x8f\x05\x9f\xb1\xa8\x7f\xa9\x9d\xf4\x27\x41\xe6\x3f\x82\xc2\x27\xf5\x1d\x89\xe9\x98\xf5\x8d\xeb\x76\x14\xf6\xf6\x0a\x75\x9a\x4a\xfb\x2f\x9d\x9a\x10\x18\x2a\x8c\x4a\xdd\x99\xb7\xa9\xbc\xff\xe6\xe4\x9c\x99\x94\x16\xf7\x77\x66\x07\x94\x53\x12\xc7\x28\x7e\x84\x3a\x2b\x73\xb5\x55\x30\x4d\x00\xd9\xb7\xaa\xc9\xf9\x5a\x0f\xd9\xad\x20\x4c\x92\xd0\xb8\x2f\xf8\x7d\xb1\xa8\xa9\xe1\xea\x11\xae\xe1\xf0\xc7\x76\xc4\x3e\x0a\xc1\x45\x03\x19\xf3\x2e\x25\xd3\x54\x4e\xa6\x53\xe4\x33\xe5\x46\x81\xfb\xd9\xc4\x77\x61\xf0\x1f\x99\x51\x6f\x25\xad\xeb\x61\x03\xb1\xeb\x61\xcd\xda\x2d\x8b\xeb\x7c\x3b\xea\x37\xa8\x04\xc5\x86\xe9\xea\x97\xf3\xe7\x51\x93\x2d\xaa\x6b\xf9\xc1\xef\xeb\xd7\xaf\x7d\xe2\xd6\x41\xd8\x55\xa9\x8d\x1e\xe8\x08\xb8\x58\xa1\x59\x68\x9f\xdc\x8b\x5e\xbb\x6b\x99\x2c\x6c\xa4\xf0\x82\xcc\x6b\x2b\x5a\x2b\x64\x17\x74\x2c\x8d\xd8\x04\x58\x1f\xdc\x68\x4f\x32\xfd\x04\xf5\x8d\x05\xd4\x7e\x58\x4f\x16\x0e\xaf\x68\x1c\xd3\xa3\xb5\x11\xa4\x67\x39\xaa\x08\xf6\xb2\x3d\xa0\xbb\x5a\x3d\xcb\xf0\xe8\x80\xa2\x4a\xcb\xe8\x0a\x89\x9c\x09\x8c\x60\x24\xf8\xd4\x9e\xb5\xd0\x94\x91\x84\x93\xac\x49\xf3\x48\x24\xc8\x70\x82\xd1\x2c\xc6\x08\x14\x87\x3b\x04\x89\x4c\x19\x66\x33\xb2\xb0\x92\xed\x96\x12\x64\x55\x08\xaf\x1e\x5c\xd8\x50\x37\xfb\x95\x3b\xe0\x2a\x22\xdf\x3b\x49\x67\xee\xbf\xa8\xa4\xbd\x0e\xb0\xb9\x4f\x2f\x71\xb8\x57\x47\xe8\x7a\xd8\x61\xd4\x15\x7d\x82\xc3\xd0\x44\x54\x46\x27\x27\x20\x90\xc4\x54\x62\x74\xd4\x01\xda\x2d\xa8\x15\x23\x53\xc5\xdf\x38\xcc\x59\x33\x4f\x65\xdd\x46\x20\x66\xd1\x14\x8c\xc0\xce\x22\xf7\x9d\xed\x3a\x6e\x08\xa6\xdc\xc2\x75\x2d\x9d\x7b\x9c\x1f\xc3\xfe\x9d\xe9\x29\x9e\xc1\x7e\x4d\x61\xab\xfe\x04\x48\x4a\x6a\x9f\x3c\x8c\x35\x24\x65\x11\x3e\xc1\xfe\x8a\x93\x23\x86\x9e\x07\xac\x12\xd9\x0a\xac\x37\xbd\x32\x88\x08\xdb\x41\x32\x6b\x2a\x43\xe2\x0a\xc8\xdc\x96\xb6\x67\x53\x17\xbb\xac\x74\x21\x30\x07\xc6\x7e\x4d\x02\xcf\x0b\x08\x57\x6a\x38\x7b\x7d\x9a\x3c\xa5\x36\xb2\x67\x4b\x77\x16\x91\x6f\x3c\xee\x85\x0e\x5a\xad\xa6\x5e\xe3\x0f\xc5\xfa\x57\xd3\x68\xad\x9c\xf6\xc1\xb6\x55\xb7\xb6\x23\x4b\xa9\x3c\x52\x35\x69\x96\x4b\x3d\x12\x27\x2a\xcb\x62\xaf\xca\x5f\x4d\x1c\x95\xd9\xb4\xcf\x35\xfe\x69\x27\x78\x5a\xe4\xaa\xe6\x38\x9b\x4e\x39\x3e\x7e\x28\x23\x34\xdb\x6c\x01\xd2\x09\xe3\xb3\x4c\xf5\xea\x92\x99\x8c\x63\x0b\xd3\x52\xb3\x24\x0f\xe3\xdf\x88\xb0\x7c\x59\x5d\xd5\xe6\x5b\x1d\x66\xb6\x3a\xb9\xc8\x2d\x46\x89\xb0\x24\xaf\xf4\x0d\xea\x37\xab\xd4\x54\x08\x8d\xec\x54\xcd\xa9\xbe\xe5\x12\x94\x8b\x26\x8e\xea\x94\x92\x91\x4f\x64\xcd\x34\x6b\x87\x16\x43\x3d\x47\x5f\x7b\xdc\x1a\xab\xef\x64\x62\x79\x8c\x92\x29\xa9\x97\x6b\xea\xb4\x8d\xed\x5a\x5e\x2a\xd1\xa7\xc6\x5a\x0d\x41\x5b\x54\x98\x47\x47\x85\x17\x75\x3b\x6f\xa7\x5c\x76\x45\xf1\xb9\x4b\xe1\x39\x2b\x3a\xdf\x11\xd1\x5c\x73\x2e\x13\x2f\xfd\xac\xa9\x33\xeb\x12\x73\x8a\x72\x75\x85\xf9\x19\x2b\xaf\xdb\xd5\xaa\xb3\xd2\x1a\x9d\xe2\x85\x10\xfa\xcc\x4d\x7d\x3d\xb9\xbe\x22\xab\xe6\x09\xf6\x41\xcb\xf2\xe0\x6b\x95\x78\xfb\x2a\xf1\x1d\x11\x4d\xb8\x4c\xc4\xd4\x36\x2b\xd3\x93\xef\xc3\x69\xef\xed\xe6\x93\xd9\xba\xac\x7c\xf1\x30\x36\xf5\x3f\x3f\x26\x1b\x62\xc8\x59\x24\xbb\xd7\x99\x57\xd6\x8e\x9d\xd5\x85\x7a\x3f\xe0\x62\x7e\x70\xdc\x38\xd2\x0d\xa1\x28\xfb\xbe\xa5\x3b\x07\xa7\xb3\x29\xd9\xd2\x82\x01\x45\xc3\xfb\x36\x46\xf4\x9f\xe0\x8a\x28\xec\xc3\xdf\x4f\x8f\x5b\xc7\x4d\x67\xb1\xa2\xba\xea\xdd\x87\x11\x89\x25\x36\x0e\x6e\xe1\x27\x75\x0d\xdf\xd7\xb8\x86\x06\x25\xb7\x16\xb4\x75\x3d\x3b\x75\x5a\x79\x39\x3b\x77\x63\x35\xd5\xec\xd6\x4a\x76\x31\x0f\xf4\xaa\x5d\xe5\xc8\x69\x45\xad\xfb\xbf\x5e\xcb\xf6\x58\x7f\xd6\x7a\xb6\x77\x84\xd2\x3f\x98\x94\x53\xf3\x0e\x4d\x46\x3d\xb8\x00\x95\xbf\x02\xb3\xfb\x13\x50\x13\x81\x72\xc2\xe3\x08\xa8\x84\x50\x9f\x76\xc1\x08\xc8\x98\x50\x26\x15\x50\xa5\xfd\x9f\x42\x12\xa5\x07\x18\x89\x2b\x93\xa7\xe9\x6b\x6f\x37\x25\x66\x8f\xd1\x67\x2e\x33\xef\xae\xa0\x92\xb3\xfc\x25\xd5\x54\xba\x22\xc8\xcc\x60\x53\x0c\x35\x15\x8d\x2d\xcb\x14\x1b\x56\x29\xea\xcd\xb0\x26\x37\x77\x09\x76\x37\x1f\x93\x66\xac\x69\x42\x9f\xc6\xcd\xd5\xb4\xbe\x09\x49\x21\x91\x6d\xc8\x54\x9b\x12\xd5\xb5\xf2\xd4\x42\xc2\xe5\x7b\x83\xb5\x93\xae\x6e\xa9\x6e\x6b\x36\x51\xf0\x46\xce\xc6\x36\x49\x54\x9b\x12\xd3\xae\x79\x69\x39\x2d\xb5\x4c\xaf\x9d\x55\x96\x32\x12\x83\xa4\x7d\xd2\xe5\xaa\x49\x55\x53\xab\x25\x57\x29\xa0\xd8\xcd\xb1\x59\x77\xb9\x0a\xd7\xc3\xdb\xb9\x16\xfd\x1f\x4f\xb0\xfc\x1d\xe1\x99\x13\xad\x32\xea\x97\x98\x70\xe5\x53\xf8\x9a\x77\x6d\x90\xaa\x7c\x4d\x95\xfe\x03\xa9\x52\x6e\xa3\x5f\x53\xa5\xc3\xe0\xdb\xb2\xdb\xc9\x53\xa6\xaa\x43\x5a\x37\x75\x4a\x37\xa9\x86\x66\xda\x2f\x49\xcd\x45\xbd\x2f\x35\x7b\xfa\x24\x48\x32\xf9\xdf\x4b\xc8\x98\xde\x36\x6b\xba\x41\x39\x8b\x95\x4c\x53\x9a\x14\x7f\x76\x07\x4c\x82\xce\x02\x12\x8c\xe0\x6e\x0e\x3c\xa3\xca\xc8\x14\x75\x12\x95\xdd\xe1\x32\xfb\x36\xda\x73\x11\xa1\x39\xd0\x01\x44\x02\x61\xf6\xd9\x8e\x32\xa3\x8c\x9f\x97\x92\x17\x65\x6a\xeb\x1c\xdc\x97\x5b\xd4\x72\x27\x2d\xe6\x4e\xc7\x8c\xf6\x3a\xa6\x2b\x8d\x83\x1b\xdb\xa8\x8d\x0d\xfe\x6a\xb6\xc2\x93\x52\x9a\xf2\x09\x55\x26\x55\xf3\xa4\x5b\x56\xc1\x93\x9e\xfd\x54\x43\x35\x78\xf7\xae\xa1\x69\x33\x37\x1d\x4b\x9e\xa4\xee\x42\x7a\x17\xcf\x8e\xbd\x7b\x67\x8c\xd8\x3b\x9a\x75\xa7\x73\xd2\xec\x81\x27\x3d\x3f\x0b\x6a\xcf\x24\xd6\xea\xd1\xe4\xb3\xf2\x8f\x39\x95\x1b\x76\x2e\x13\xe2\x49\xcf\x3f\xa5\xd4\x92\xf3\x94\x87\x36\x46\xff\x5b\x06\xc8\x1d\x9c\xb5\xc2\x24\x55\xef\x97\xee\xa4\x8d\x86\x40\x73\xbc\xad\x77\xb6\x57\xef\xec\x2d\xea\xf4\xd2\x6c\x82\x44\x1d\xeb\x43\x39\x31\x1e\xc3\x8c\x29\x1a\x03\x61\x11\xd0\x11\x48\x85\x49\x36\x50\x6a\x26\x7a\xa0\x73\x5c\xcc\x5c\xb2\x7e\x91\xd8\x27\x6a\x22\xf8\x6c\x3c\x01\xe2\x50\x02\x17\x10\x73\x9e\x98\xdb\xc0\xe9\xe1\x10\x0b\xa1\x23\x27\x6a\xef\x64\x8f\x80\x30\x4d\x6a\x82\x71\xb4\x23\xa7\xae\x67\xf1\xa5\xfb\xf3\x37\xce\x2b\x6b\x1d\xaf\xef\x90\xe7\xc9\xfa\x27\x8d\xd6\x2c\x09\x39\x28\xab\xfd\xb5\xc1\x74\x00\xbd\x21\x68\x7a\x30\x6c\xad\x12\x94\xf6\x9d\xe6\x86\x34\x96\xbd\xbb\xbf\xec\x3b\x78\x75\xd6\xe0\xd2\x0d\xea\x9e\x16\x7c\xf3\xdb\x1a\x17\xe7\xbf\xb6\xf2\x68\x7e\xef\x5a\xba\x76\x58\x97\x7a\x94\x65\xc9\x89\xab\xdd\x0b\x97\x86\x3e\x5f\x61\xa2\x73\x90\x7c\x21\x25\x0a\xed\x02\x7e\x22\x34\xd6\xdf\xda\x79\x01\x6e\x38\xe3\x19\x1c\xd3\xdb\x7a\xe3\x74\xee\x95\x0f\x18\x90\x94\x92\x79\x65\xce\x52\xb9\x73\xe1\x36\x46\xb6\x67\xeb\x46\x84\xc6\x12\x38\x0b\x11\x26\xfc\x11\x1f\x50\xc0\x94\x30\xf3\xa9\x06\xaa\xa4\x8f\x44\x8f\xc4\x5d\xf9\xd7\x8c\xce\x17\xef\x64\x4f\x9d\x93\xb5\xa2\xec\xec\x8a\xde\x3a\xe7\x9c\xe9\xbf\xbb\x13\x73\x90\xa9\xa2\xd7\x72\x62\x4e\xe3\xc7\xb0\x3f\x72\xe0\x65\x5f\xd6\xbc\x94\x0a\x88\x32\x05\xe9\xef\x20\x68\xa7\x64\x22\xd0\x51\x71\xf4\x0a\x47\xe8\x98\xa9\x75\x57\x19\xfe\xc6\x53\xe4\x5e\xf1\x78\xb1\xb0\x3c\xac\xf0\x3a\xbb\xf7\x40\xd7\x13\xf2\x72\x9a\x9c\xee\x53\x2b\x60\x98\xde\xd6\xed\x5c\xa4\xa7\x36\x75\xca\xe0\x7f\x51\x85\x32\xeb\x6b\x12\x4d\xa5\x07\xb7\xb7\x3f\xbd\x33\x8d\x4b\xad\x2f\xff\x98\xef\x94\xeb\x72\x49\x1a\xca\x31\xb4\x3d\x4f\x2a\x41\x20\x89\xe6\x3b\x72\x33\x86\xa9\x17\xd3\xaf\x5c\xfb\x00\xf8\x69\x7a\x10\xd7\xa8\x42\xae\x0d\xf8\xe1\xe7\xe1\xfa\xdd\xc5\xd3\x34\x16\x34\x4a\xdc\x18\xfe\xf6\x72\xd8\x7a\xde\xbc\xf1\x74\xae\xb6\xb0\xda\x6a\xc0\x69\xde\xba\xb5\x66\xb8\xdd\xb9\xf0\x94\xcf\x5a\x4c\x5d\x05\xcc\x1f\x59\xcc\x49\x87\x1e\x6e\x63\x4f\x34\x51\xf5\x1e\xbc\xe0\x8a\x36\xef\x60\xee\x27\xaa\x25\xe8\x4c\x54\x1a\x52\x6a\x46\x7a\xda\x5e\xfc\xf3\x81\x6f\x46\x41\x17\xb0\xd4\x54\x36\x00\xd5\x56\xd2\x0a\xe6\xda\xaa\xdd\xac\xa5\xae\x31\xda\x0a\xb9\x51\x5b\xb4\xda\x8f\xac\xcc\x4a\xa3\xde\x40\x1a\x99\x39\xb5\xc1\x7e\x61\x6d\x42\xeb\x82\x9f\xb9\x41\x98\x23\x7d\x81\xad\x41\xb3\x72\xb7\x6b\x0a\xd6\x0f\x30\x45\x72\xd9\x2f\xd0\xf9\x64\x9e\x2d\x97\x5f\xdb\x88\xe5\xbf\x97\xd3\x46\xfc\x72\x3b\x88\xd7\x93\xdc\x5f\x7e\x6d\x21\x1e\x06\xdf\xe6\x8e\x29\x6f\x1e\xfa\xce\xea\xb9\xda\x86\xff\xc7\xc5\xbd\xde\x09\x5e\x42\x1e\x92\xf2\xda\x25\x05\xd9\x22\xd6\x7f\x74\x64\x76\x72\x0b\xde\x85\x64\x29\x8d\x52\x48\xe6\x6b\xa3\xcb\x85\xda\xa0\xa6\x3f\x95\x66\x01\x8b\x45\x46\x25\x6b\x0e\xdd\x9d\xc3\xa1\xff\xdc\xf4\x62\x74\x69\x7d\xb9\x3c\x6a\x6a\x77\xe9\x52\xa8\xf9\xb8\x1d\xfc\xee\x83\xbe\xe7\x2c\x9c\x09\x81\x4c\xb9\x2f\xdf\xfd\xd1\x86\xc0\xfb\x8c\x6e\x01\x4b\xf9\xdb\xb9\xad\x48\xca\xb7\xe2\xbd\x79\xb4\xdd\x86\xf7\xfb\x76\xf9\x5d\xf7\x1c\xb8\x70\xc7\xbd\x0d\xd8\xdd\x33\xf7\xc8\x76\xba\x5f\x5e\xc0\x51\x1b\x5f\xf9\x6a\xae\x5c\x59\x0e\x0a\x57\xd5\xeb\xb2\xbb\xc6\x9b\xec\x1d\x1a\xa6\xd9\xb0\xb6\xd6\xe7\xca\x5b\xa3\x75\x00\xdd\xef\x84\x16\x6e\xff\xd4\x9d\xe3\xcc\x25\xde\x76\xb1\x72\xb3\x3b\x82\x9b\x5f\x03\x2c\x1c\x11\x2c\x73\xd8\x7a\x38\xb0\x1d\xb4\xed\xe8\x5e\x23\xcf\x39\x96\x55\x77\xc4\x9e\xf3\xae\x55\x79\x7f\xc9\x5e\xdc\x89\xf3\xb6\x7f\xdf\xa4\x00\xff\x1e\x00\x43\x27\x8f\xa0\xba\x60\x00\x00")
 
 func reportContentTmplBytes() ([]byte, error) {
 	return bindataRead(
@@ -86,7 +86,7 @@ func reportContentTmpl() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "report/content.tmpl", size: 7763, mode: os.FileMode(420), modTime: time.Unix(1509050255, 0)}
+	info := bindataFileInfo{name: "report/content.tmpl", size: 24762, mode: os.FileMode(420), modTime: time.Unix(1792304768, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
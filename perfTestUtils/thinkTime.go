@@ -0,0 +1,131 @@
+package perfTestUtils
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThinkTimeConstant, ThinkTimeUniform, ThinkTimeNormal, ThinkTimeExponential
+// and ThinkTimeLogNormal are the valid distributions of a ThinkTime. All
+// parameters are in milliseconds.
+const (
+	ThinkTimeConstant    = "constant"
+	ThinkTimeUniform     = "uniform"
+	ThinkTimeNormal      = "normal"
+	ThinkTimeExponential = "exponential"
+	ThinkTimeLogNormal   = "lognormal"
+)
+
+// ThinkTime is a distribution of think times, the pauses of a virtual user
+// between requests. It is written as "constant(500)", "uniform(200,800)",
+// "normal(500,100)", "exponential(500)" or "lognormal(500,100)". A plain
+// number is a constant. The parameters are the value, the min and max, or
+// the mean and standard deviation, in milliseconds. The zero ThinkTime has no
+// pause.
+type ThinkTime struct {
+	Distribution string
+	Min          float64
+	Max          float64
+	Mean         float64
+	StdDev       float64
+}
+
+// ParseThinkTime returns the ThinkTime of a spec such as "normal(500,100)".
+// An empty spec is the zero ThinkTime.
+func ParseThinkTime(spec string) (ThinkTime, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return ThinkTime{}, nil
+	}
+
+	distribution, args := ThinkTimeConstant, spec
+	if open := strings.Index(spec, "("); open >= 0 {
+		if !strings.HasSuffix(spec, ")") {
+			return ThinkTime{}, fmt.Errorf("invalid think time [%s]: missing )", spec)
+		}
+		distribution = strings.ToLower(strings.TrimSpace(spec[:open]))
+		args = spec[open+1 : len(spec)-1]
+	}
+	var params []float64
+	for _, arg := range strings.Split(args, ",") {
+		param, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+		if err != nil || param < 0 {
+			return ThinkTime{}, fmt.Errorf("invalid think time [%s]: [%s] is not a number of milliseconds", spec, strings.TrimSpace(arg))
+		}
+		params = append(params, param)
+	}
+
+	tt := ThinkTime{Distribution: distribution}
+	switch distribution {
+	case ThinkTimeConstant, ThinkTimeExponential:
+		if len(params) != 1 {
+			return ThinkTime{}, fmt.Errorf("invalid think time [%s]: %s takes 1 parameter", spec, distribution)
+		}
+		tt.Mean = params[0]
+	case ThinkTimeUniform:
+		if len(params) != 2 || params[0] > params[1] {
+			return ThinkTime{}, fmt.Errorf("invalid think time [%s]: uniform takes a min and a max", spec)
+		}
+		tt.Min, tt.Max = params[0], params[1]
+	case ThinkTimeNormal, ThinkTimeLogNormal:
+		if len(params) != 2 {
+			return ThinkTime{}, fmt.Errorf("invalid think time [%s]: %s takes a mean and a standard deviation", spec, distribution)
+		}
+		if distribution == ThinkTimeLogNormal && params[0] == 0 {
+			return ThinkTime{}, fmt.Errorf("invalid think time [%s]: the mean of lognormal must be above zero", spec)
+		}
+		tt.Mean, tt.StdDev = params[0], params[1]
+	default:
+		return ThinkTime{}, fmt.Errorf("invalid think time [%s]: unknown distribution [%s]", spec, distribution)
+	}
+	return tt, nil
+}
+
+// IsZero returns true if the ThinkTime has no pause.
+func (tt ThinkTime) IsZero() bool {
+	return tt.Distribution == "" || (tt.Mean == 0 && tt.Max == 0 && tt.StdDev == 0)
+}
+
+// Sample returns a think time drawn from the distribution. Samples of a
+// normal distribution below zero are zero.
+func (tt ThinkTime) Sample() time.Duration {
+	var ms float64
+	switch tt.Distribution {
+	case ThinkTimeConstant:
+		ms = tt.Mean
+	case ThinkTimeUniform:
+		ms = tt.Min + rand.Float64()*(tt.Max-tt.Min)
+	case ThinkTimeNormal:
+		ms = tt.Mean + rand.NormFloat64()*tt.StdDev
+	case ThinkTimeExponential:
+		ms = rand.ExpFloat64() * tt.Mean
+	case ThinkTimeLogNormal:
+		// The mean and standard deviation are those of the think times, not
+		// of their logarithm.
+		sigma2 := math.Log(1 + (tt.StdDev*tt.StdDev)/(tt.Mean*tt.Mean))
+		mu := math.Log(tt.Mean) - sigma2/2
+		ms = math.Exp(mu + rand.NormFloat64()*math.Sqrt(sigma2))
+	}
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// String returns the ThinkTime in the form it is written.
+func (tt ThinkTime) String() string {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	switch tt.Distribution {
+	case ThinkTimeConstant, ThinkTimeExponential:
+		return fmt.Sprintf("%s(%s)", tt.Distribution, format(tt.Mean))
+	case ThinkTimeUniform:
+		return fmt.Sprintf("%s(%s,%s)", tt.Distribution, format(tt.Min), format(tt.Max))
+	case ThinkTimeNormal, ThinkTimeLogNormal:
+		return fmt.Sprintf("%s(%s,%s)", tt.Distribution, format(tt.Mean), format(tt.StdDev))
+	}
+	return ""
+}
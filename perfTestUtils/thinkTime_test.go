@@ -0,0 +1,100 @@
+package perfTestUtils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	valid := map[string]ThinkTime{
+		"":                      {},
+		"250":                   {Distribution: ThinkTimeConstant, Mean: 250},
+		"constant(250)":         {Distribution: ThinkTimeConstant, Mean: 250},
+		" Uniform( 100, 900 ) ": {Distribution: ThinkTimeUniform, Min: 100, Max: 900},
+		"normal(500,100)":       {Distribution: ThinkTimeNormal, Mean: 500, StdDev: 100},
+		"exponential(500)":      {Distribution: ThinkTimeExponential, Mean: 500},
+		"lognormal(500,250.5)":  {Distribution: ThinkTimeLogNormal, Mean: 500, StdDev: 250.5},
+	}
+	for spec, expected := range valid {
+		tt, err := ParseThinkTime(spec)
+		assert.Nil(t, err, spec)
+		assert.Equal(t, expected, tt, spec)
+	}
+
+	invalid := []string{"-1", "abc", "normal(500)", "uniform(900,100)", "exponential(1,2)", "lognormal(0,10)", "gamma(2,3)", "normal(500,100"}
+	for _, spec := range invalid {
+		_, err := ParseThinkTime(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestThinkTimeString(t *testing.T) {
+	for _, spec := range []string{"constant(250)", "uniform(100,900)", "normal(500,100)", "exponential(500)", "lognormal(500,250.5)"} {
+		tt, err := ParseThinkTime(spec)
+		assert.Nil(t, err)
+		assert.Equal(t, spec, tt.String())
+	}
+	assert.Equal(t, "", ThinkTime{}.String())
+}
+
+func TestThinkTimeIsZero(t *testing.T) {
+	assert.True(t, ThinkTime{}.IsZero())
+	assert.True(t, ThinkTime{Distribution: ThinkTimeConstant}.IsZero())
+	assert.False(t, ThinkTime{Distribution: ThinkTimeConstant, Mean: 1}.IsZero())
+	assert.False(t, ThinkTime{Distribution: ThinkTimeUniform, Max: 10}.IsZero())
+	assert.False(t, ThinkTime{Distribution: ThinkTimeNormal, StdDev: 10}.IsZero())
+}
+
+func TestThinkTimeSample(t *testing.T) {
+	const samples = 20000
+	sampleStats := func(tt ThinkTime) (mean float64, stdDev float64, min time.Duration, max time.Duration) {
+		var sum, sumSquares float64
+		min = time.Duration(math.MaxInt64)
+		for i := 0; i < samples; i++ {
+			d := tt.Sample()
+			ms := float64(d) / float64(time.Millisecond)
+			sum += ms
+			sumSquares += ms * ms
+			if d < min {
+				min = d
+			}
+			if d > max {
+				max = d
+			}
+		}
+		mean = sum / samples
+		return mean, math.Sqrt(sumSquares/samples - mean*mean), min, max
+	}
+
+	mean, _, min, max := sampleStats(ThinkTime{Distribution: ThinkTimeConstant, Mean: 250})
+	assert.Equal(t, 250.0, mean)
+	assert.Equal(t, 250*time.Millisecond, min)
+	assert.Equal(t, 250*time.Millisecond, max)
+
+	mean, _, min, max = sampleStats(ThinkTime{Distribution: ThinkTimeUniform, Min: 100, Max: 900})
+	assert.InDelta(t, 500, mean, 15)
+	assert.True(t, min >= 100*time.Millisecond)
+	assert.True(t, max <= 900*time.Millisecond)
+
+	mean, stdDev, _, _ := sampleStats(ThinkTime{Distribution: ThinkTimeNormal, Mean: 500, StdDev: 100})
+	assert.InDelta(t, 500, mean, 5)
+	assert.InDelta(t, 100, stdDev, 5)
+
+	// Samples below zero are zero.
+	_, _, min, _ = sampleStats(ThinkTime{Distribution: ThinkTimeNormal, Mean: 10, StdDev: 100})
+	assert.Equal(t, time.Duration(0), min)
+
+	mean, stdDev, min, _ = sampleStats(ThinkTime{Distribution: ThinkTimeExponential, Mean: 500})
+	assert.InDelta(t, 500, mean, 25)
+	assert.InDelta(t, 500, stdDev, 40)
+	assert.True(t, min >= 0)
+
+	mean, stdDev, min, _ = sampleStats(ThinkTime{Distribution: ThinkTimeLogNormal, Mean: 500, StdDev: 100})
+	assert.InDelta(t, 500, mean, 5)
+	assert.InDelta(t, 100, stdDev, 8)
+	assert.True(t, min > 0)
+
+	assert.Equal(t, time.Duration(0), ThinkTime{}.Sample())
+}
@@ -126,6 +126,38 @@ func validateBasePerfStat(basePerfstats *BasePerfStats, configurationSettings *C
 	return isBasePerfStatsValid
 }
 
+// LoadStagePartitions converts the start times of the load profile stages
+// into test partitions, so stage boundaries can be marked on the memory chart
+// in the same way as service boundaries. The partition count is the index of
+// the first memory sample taken after the stage started. Stages that started
+// after the last memory sample are left out.
+func LoadStagePartitions(loadStageStarts []LoadStageStart, memorySampleTimes []time.Time) []TestPartition {
+	testPartitions := make([]TestPartition, 0)
+	for _, stageStart := range loadStageStarts {
+		count := sort.Search(len(memorySampleTimes), func(i int) bool {
+			return !memorySampleTimes[i].Before(stageStart.Time)
+		})
+		if count == len(memorySampleTimes) {
+			continue
+		}
+		testPartitions = append(testPartitions, TestPartition{Count: count, TestName: "Stage: " + stageStart.Name})
+	}
+	return testPartitions
+}
+
+// SortTestPartitions orders test partitions by their count, keeping the
+// order of partitions with the same count.
+func SortTestPartitions(testPartitions []TestPartition) []TestPartition {
+	sort.Stable(testPartitionsByCount(testPartitions))
+	return testPartitions
+}
+
+type testPartitionsByCount []TestPartition
+
+func (p testPartitionsByCount) Len() int           { return len(p) }
+func (p testPartitionsByCount) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p testPartitionsByCount) Less(i, j int) bool { return p[i].Count < p[j].Count }
+
 //=====================
 //Calc Memory functions
 //=====================
@@ -254,6 +286,24 @@ func ValidateAverageServiceResponseTimeVariance(allowableServiceResponseTimeVari
 	return false
 }
 
+// ValidateTransactionResponseTime returns true if a transaction ran, and its
+// average response time is within its threshold, if it has one, or within the
+// allowable variance of its base response time otherwise. Times are in
+// nanoseconds.
+func ValidateTransactionResponseTime(allowableServiceResponseTimeVariance float64, averageResponseTime int64, baseResponseTime int64, threshold int64) bool {
+	if averageResponseTime == 0 {
+		return false
+	}
+	if threshold > 0 {
+		return averageResponseTime <= threshold
+	}
+	if baseResponseTime == 0 {
+		// Not trained yet.
+		return true
+	}
+	return ValidateAverageServiceResponseTimeVariance(allowableServiceResponseTimeVariance, CalcAverageResponseVariancePercentage(averageResponseTime, baseResponseTime))
+}
+
 //=====================================
 //Response times sort functions
 //=====================================
@@ -264,7 +314,7 @@ func (a RspTimes) Less(i, j int) bool { return a[i] < a[j] }
 //==============================================
 //Generate base environment stats file functions
 //==============================================
-func populateBasePerfStats(perfStatsForTest *PerfStats, basePerfstats *BasePerfStats, reBaseMemory bool) {
+func populateBasePerfStats(perfStatsForTest *PerfStats, basePerfstats *BasePerfStats, reBaseMemory bool, baseTTFB bool) {
 	modified := false
 
 	//Setting memory data
@@ -286,6 +336,30 @@ func populateBasePerfStats(perfStatsForTest *PerfStats, basePerfstats *BasePerfS
 		}
 	}
 
+	//Setting transaction response time data
+	for transactionName, responseTime := range perfStatsForTest.TransactionResponseTimes {
+		if basePerfstats.BaseTransactionResponseTimes == nil {
+			basePerfstats.BaseTransactionResponseTimes = make(map[string]int64)
+		}
+		if basePerfstats.BaseTransactionResponseTimes[transactionName] == 0 && responseTime > 0 {
+			basePerfstats.BaseTransactionResponseTimes[transactionName] = responseTime
+			modified = true
+		}
+	}
+
+	//Setting time to first byte data, if asked for
+	if baseTTFB {
+		for serviceName, phaseTimes := range perfStatsForTest.ServicePhaseTimes {
+			if basePerfstats.BaseServiceTTFB == nil {
+				basePerfstats.BaseServiceTTFB = make(map[string]int64)
+			}
+			if basePerfstats.BaseServiceTTFB[serviceName] == 0 && phaseTimes.AverageTTFB() > 0 {
+				basePerfstats.BaseServiceTTFB[serviceName] = phaseTimes.AverageTTFB()
+				modified = true
+			}
+		}
+	}
+
 	//Setting time stamps
 	currentTime := time.Now().Format(time.RFC850)
 	if basePerfstats.GenerationDate == "" {
@@ -299,7 +373,7 @@ func populateBasePerfStats(perfStatsForTest *PerfStats, basePerfstats *BasePerfS
 // GenerateEnvBasePerfOutputFile writes the basePerfStats file.
 func GenerateEnvBasePerfOutputFile(perfStatsForTest *PerfStats, basePerfstats *BasePerfStats, configurationSettings *Config, exit func(code int), fs FileSystem) {
 	//Set base performance based on training test run
-	populateBasePerfStats(perfStatsForTest, basePerfstats, configurationSettings.ReBaseMemory)
+	populateBasePerfStats(perfStatsForTest, basePerfstats, configurationSettings.ReBaseMemory, configurationSettings.BaseTTFB)
 
 	//Convert base perf stat to Json
 	basePerfstatsJSON, err := json.Marshal(basePerfstats)
@@ -145,13 +145,55 @@ func TestPopulateBasePerfStats(t *testing.T) {
 	psrt["service 2"] = 2e5
 	ps.ServiceResponseTimes = psrt
 
-	populateBasePerfStats(ps, bs, false)
+	populateBasePerfStats(ps, bs, false, false)
 	assert.Equal(t, bs.BasePeakMemory, ps.PeakMemory)
 	assert.Equal(t, bs.MemoryAudit, ps.MemoryAudit)
 	assert.Equal(t, bs.BaseServiceResponseTimes, ps.ServiceResponseTimes)
 	assert.Equal(t, bs.ModifiedDate, bs.GenerationDate)
 }
 
+func TestPopulateBasePerfStatsTTFB(t *testing.T) {
+	ps := &PerfStats{
+		ServiceResponseTimes: map[string]int64{"service 1": 3e6, "service 2": 2e6},
+		ServicePhaseTimes: map[string]*PhaseTimes{
+			"service 1": {Count: 4, TTFB: 8e6},
+			"service 2": {Count: 2, TTFB: 2e6},
+		},
+	}
+
+	// Time to first byte is only kept when asked for.
+	bs := &BasePerfStats{BaseServiceResponseTimes: make(map[string]int64)}
+	populateBasePerfStats(ps, bs, false, false)
+	assert.Nil(t, bs.BaseServiceTTFB)
+
+	bs = &BasePerfStats{
+		BaseServiceResponseTimes: make(map[string]int64),
+		BaseServiceTTFB:          map[string]int64{"service 2": 5e5},
+	}
+	populateBasePerfStats(ps, bs, false, true)
+	assert.Equal(t, int64(2e6), bs.BaseServiceTTFB["service 1"])
+	// Existing base values are kept, the same as response times.
+	assert.Equal(t, int64(5e5), bs.BaseServiceTTFB["service 2"])
+}
+
+func TestPopulateBasePerfStatsTransactions(t *testing.T) {
+	ps := &PerfStats{
+		ServiceResponseTimes:     map[string]int64{"cart": 2e6},
+		TransactionResponseTimes: map[string]int64{"checkout": 9e6, "browse": 4e6, "failing": 0},
+	}
+	bs := &BasePerfStats{
+		BaseServiceResponseTimes:     make(map[string]int64),
+		BaseTransactionResponseTimes: map[string]int64{"browse": 3e6},
+	}
+	populateBasePerfStats(ps, bs, false, false)
+	assert.Equal(t, map[string]int64{"checkout": 9e6, "browse": 3e6}, bs.BaseTransactionResponseTimes)
+
+	// Runs without transactions leave no trace in the base stats.
+	bs = &BasePerfStats{BaseServiceResponseTimes: make(map[string]int64)}
+	populateBasePerfStats(&PerfStats{ServiceResponseTimes: map[string]int64{"cart": 2e6}}, bs, false, false)
+	assert.Nil(t, bs.BaseTransactionResponseTimes)
+}
+
 func TestValidateResponseStatusCode(t *testing.T) {
 	assert.True(t, ValidateResponseStatusCode(http.StatusOK, http.StatusOK, "test"))
 	assert.False(t, ValidateResponseStatusCode(http.StatusOK, http.StatusInternalServerError, "test"))
@@ -175,6 +217,20 @@ func TestValidateAverageServiceResponeTimeVariance(t *testing.T) {
 	assert.False(t, ValidateAverageServiceResponseTimeVariance(15, 16))
 }
 
+func TestValidateTransactionResponseTime(t *testing.T) {
+	// Against the base time, once trained.
+	assert.True(t, ValidateTransactionResponseTime(15, 110, 100, 0))
+	assert.False(t, ValidateTransactionResponseTime(15, 120, 100, 0))
+	assert.True(t, ValidateTransactionResponseTime(15, 120, 0, 0))
+
+	// Against the threshold instead, if any.
+	assert.True(t, ValidateTransactionResponseTime(15, 150, 100, 150))
+	assert.False(t, ValidateTransactionResponseTime(15, 110, 100, 105))
+
+	// A failed transaction never passes.
+	assert.False(t, ValidateTransactionResponseTime(15, 0, 100, 150))
+}
+
 func TestGenerateEnvBasePerfOutputFile(t *testing.T) {
 	ps := &PerfStats{
 		TestTimeStart:   time.Now(),
@@ -252,3 +308,34 @@ func TestValidateBasePerfStat(t *testing.T) {
 	bs.MemoryAudit = []uint64{1, 2, 3}
 	assert.True(t, validateBasePerfStat(bs, c))
 }
+
+func TestLoadStagePartitions(t *testing.T) {
+	start := time.Now()
+	sampleTimes := []time.Time{start, start.Add(time.Second), start.Add(2 * time.Second)}
+	stageStarts := []LoadStageStart{
+		{Name: "ramp", Time: start},
+		{Name: "hold", Time: start.Add(1500 * time.Millisecond)},
+		{Name: "never", Time: start.Add(time.Minute)},
+	}
+
+	partitions := LoadStagePartitions(stageStarts, sampleTimes)
+	assert.Equal(t, []TestPartition{{Count: 0, TestName: "Stage: ramp"}, {Count: 2, TestName: "Stage: hold"}}, partitions)
+}
+
+func TestSortTestPartitions(t *testing.T) {
+	partitions := []TestPartition{
+		{Count: 0, TestName: "StartUp"},
+		{Count: 1, TestName: "search"},
+		{Count: 4, TestName: "checkout"},
+		{Count: 0, TestName: "Stage: ramp"},
+		{Count: 2, TestName: "Stage: hold"},
+	}
+
+	assert.Equal(t, []TestPartition{
+		{Count: 0, TestName: "StartUp"},
+		{Count: 0, TestName: "Stage: ramp"},
+		{Count: 1, TestName: "search"},
+		{Count: 2, TestName: "Stage: hold"},
+		{Count: 4, TestName: "checkout"},
+	}, SortTestPartitions(partitions))
+}
@@ -0,0 +1,182 @@
+package testStrategies
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// abortCheckFreq is how often the abort monitor evaluates the criteria.
+const abortCheckFreq = time.Second
+
+// requestSample is the outcome of a single request: its completion time and
+// response time, or zero for a failed request.
+type requestSample struct {
+	completed    time.Time
+	responseTime int64
+}
+
+// slidingWindow holds the outcome of the requests completed within the
+// longest window of the abort criteria.
+type slidingWindow struct {
+	mu      sync.Mutex
+	length  time.Duration
+	samples []requestSample
+}
+
+// activeWindow records request outcomes while an abort monitor is running.
+// It is nil otherwise, so that runs without abort criteria keep nothing.
+var (
+	activeWindowMu sync.Mutex
+	activeWindow   *slidingWindow
+)
+
+// recordRequestOutcome adds the outcome of a request to the sliding window of
+// the running abort monitor, if any. A response time of zero is a failure.
+func recordRequestOutcome(responseTime int64) {
+	activeWindowMu.Lock()
+	window := activeWindow
+	activeWindowMu.Unlock()
+	if window != nil {
+		window.add(responseTime, time.Now())
+	}
+}
+
+// add records a request outcome and drops samples that fell out of the
+// window.
+func (w *slidingWindow) add(responseTime int64, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples = append(w.samples, requestSample{completed: now, responseTime: responseTime})
+
+	cutoff := now.Add(-w.length)
+	expired := 0
+	for expired < len(w.samples) && w.samples[expired].completed.Before(cutoff) {
+		expired++
+	}
+	if expired > 0 {
+		w.samples = append(w.samples[:0], w.samples[expired:]...)
+	}
+}
+
+// stats returns the number of requests completed within the given window
+// before now, the percentage that failed, and the 95th percentile response
+// time of the successful ones in nanoseconds.
+func (w *slidingWindow) stats(window time.Duration, now time.Time) (int, float64, int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cutoff := now.Add(-window)
+	errors := 0
+	responseTimes := make([]int64, 0, len(w.samples))
+	for _, sample := range w.samples {
+		if sample.completed.Before(cutoff) {
+			continue
+		}
+		if sample.responseTime == 0 {
+			errors++
+		} else {
+			responseTimes = append(responseTimes, sample.responseTime)
+		}
+	}
+
+	count := errors + len(responseTimes)
+	if count == 0 {
+		return 0, 0, 0
+	}
+	p95 := int64(0)
+	if len(responseTimes) > 0 {
+		sort.Sort(perfTestUtils.RspTimes(responseTimes))
+		p95 = responseTimes[(len(responseTimes)*95+99)/100-1]
+	}
+	return count, float64(errors) * 100 / float64(count), p95
+}
+
+// checkAbortCriteria returns a description of the first criterion that is
+// triggered, or an empty string if none is.
+func checkAbortCriteria(criteria []perfTestUtils.AbortCriterion, window *slidingWindow, peakMemory uint64, now time.Time) string {
+	for _, criterion := range criteria {
+		switch criterion.Metric {
+		case perfTestUtils.AbortOnErrorRate, perfTestUtils.AbortOnP95:
+			count, errorRate, p95 := window.stats(criterion.WindowDuration(), now)
+			if count < criterion.MinRequests {
+				continue
+			}
+			if criterion.Metric == perfTestUtils.AbortOnErrorRate && errorRate > criterion.Threshold {
+				return fmt.Sprintf("%s (%.1f%% of %d requests failed)", criterion.String(), errorRate, count)
+			}
+			p95Millis := float64(p95) / float64(time.Millisecond)
+			if criterion.Metric == perfTestUtils.AbortOnP95 && p95Millis > criterion.Threshold {
+				return fmt.Sprintf("%s (p95 was %.0fms over %d requests)", criterion.String(), p95Millis, count)
+			}
+		case perfTestUtils.AbortOnPeakMemory:
+			peakMB := float64(peakMemory) / (1024 * 1024)
+			if peakMB > criterion.Threshold {
+				return fmt.Sprintf("%s (peak memory was %.0fMB)", criterion.String(), peakMB)
+			}
+		}
+	}
+	return ""
+}
+
+//----- StartAbortMonitor -----------------------------------------------------
+// Evaluate the abort criteria of configurationSettings continuously while the
+// test run is in progress. The first criterion that is triggered is recorded
+// in perfStatsForTest.AbortReason and stops the test run. Peak memory is read
+// atomically from peakMemory. The returned function stops the monitor.
+func StartAbortMonitor(
+	configurationSettings *perfTestUtils.Config,
+	perfStatsForTest *perfTestUtils.PerfStats,
+	peakMemory *uint64,
+) func() {
+	if len(configurationSettings.AbortCriteria) == 0 {
+		return func() {}
+	}
+
+	window := &slidingWindow{}
+	for _, criterion := range configurationSettings.AbortCriteria {
+		if criterion.WindowDuration() > window.length {
+			window.length = criterion.WindowDuration()
+		}
+	}
+	activeWindowMu.Lock()
+	activeWindow = window
+	activeWindowMu.Unlock()
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(abortCheckFreq)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-testRunStopped():
+				return
+			case now := <-ticker.C:
+				reason := checkAbortCriteria(configurationSettings.AbortCriteria, window, atomic.LoadUint64(peakMemory), now)
+				if reason == "" {
+					continue
+				}
+				log.Errorf("Abort criterion triggered: %s. Stopping the test run.", reason)
+				perfStatsForTest.AbortReason = reason
+				StopTestRun("aborted: "+reason, configurationSettings.GracePeriodDuration())
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-done
+		activeWindowMu.Lock()
+		activeWindow = nil
+		activeWindowMu.Unlock()
+	}
+}
@@ -0,0 +1,100 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowStats(t *testing.T) {
+	now := time.Now()
+	window := &slidingWindow{length: 10 * time.Second}
+	window.add(0, now.Add(-30*time.Second))
+	for i := 1; i <= 100; i++ {
+		window.add(int64(i)*int64(time.Millisecond), now.Add(-5*time.Second))
+	}
+	window.add(0, now)
+	window.add(0, now)
+
+	// The first sample fell out of the window when the others were added.
+	assert.Equal(t, 102, len(window.samples))
+
+	count, errorRate, p95 := window.stats(10*time.Second, now)
+	assert.Equal(t, 102, count)
+	assert.InDelta(t, 1.96, errorRate, 0.01)
+	assert.Equal(t, 95*int64(time.Millisecond), p95)
+
+	// Only the two failures are within the last second.
+	count, errorRate, p95 = window.stats(time.Second, now)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 100.0, errorRate)
+	assert.Equal(t, int64(0), p95)
+}
+
+func TestCheckAbortCriteria(t *testing.T) {
+	now := time.Now()
+	window := &slidingWindow{length: time.Minute}
+	for i := 0; i < 20; i++ {
+		window.add(int64(200*time.Millisecond), now)
+	}
+	for i := 0; i < 10; i++ {
+		window.add(0, now)
+	}
+
+	errorRate := perfTestUtils.AbortCriterion{Metric: perfTestUtils.AbortOnErrorRate, Threshold: 25, Window: "30s", MinRequests: 10}
+	p95 := perfTestUtils.AbortCriterion{Metric: perfTestUtils.AbortOnP95, Threshold: 150, Window: "30s", MinRequests: 10}
+	memory := perfTestUtils.AbortCriterion{Metric: perfTestUtils.AbortOnPeakMemory, Threshold: 512}
+
+	reason := checkAbortCriteria([]perfTestUtils.AbortCriterion{errorRate}, window, 0, now)
+	assert.Equal(t, "error rate above 25.0% over 30s (33.3% of 30 requests failed)", reason)
+
+	reason = checkAbortCriteria([]perfTestUtils.AbortCriterion{p95}, window, 0, now)
+	assert.Equal(t, "p95 above 150ms over 30s (p95 was 200ms over 30 requests)", reason)
+
+	assert.Equal(t, "", checkAbortCriteria([]perfTestUtils.AbortCriterion{memory}, window, 100*1024*1024, now))
+	reason = checkAbortCriteria([]perfTestUtils.AbortCriterion{memory}, window, 600*1024*1024, now)
+	assert.Equal(t, "peak memory above 512MB (peak memory was 600MB)", reason)
+
+	// Too few requests in the window to judge.
+	errorRate.MinRequests = 50
+	assert.Equal(t, "", checkAbortCriteria([]perfTestUtils.AbortCriterion{errorRate}, window, 0, now))
+}
+
+func TestStartAbortMonitor(t *testing.T) {
+	defer resetTestRun()
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.AbortCriteria = []perfTestUtils.AbortCriterion{
+		{Metric: perfTestUtils.AbortOnErrorRate, Threshold: 50, Window: "10s", MinRequests: 5},
+	}
+	perfStats := &perfTestUtils.PerfStats{}
+	peakMemory := uint64(0)
+
+	stopMonitor := StartAbortMonitor(config, perfStats, &peakMemory)
+	for i := 0; i < 10; i++ {
+		recordRequestOutcome(0)
+	}
+	select {
+	case <-testRunStopped():
+	case <-time.After(3 * abortCheckFreq):
+		t.Error("Expected the abort criterion to stop the test run")
+	}
+	stopMonitor()
+
+	assert.True(t, strings.HasPrefix(TestRunStopReason(), "aborted: error rate above 50.0% over 10s"))
+	assert.Equal(t, "error rate above 50.0% over 10s (100.0% of 10 requests failed)", perfStats.AbortReason)
+
+	// Nothing is recorded once the monitor has stopped.
+	assert.Nil(t, activeWindow)
+}
+
+func TestStartAbortMonitorWithoutCriteria(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	stopMonitor := StartAbortMonitor(config, &perfTestUtils.PerfStats{}, new(uint64))
+	recordRequestOutcome(0)
+	assert.Nil(t, activeWindow)
+	stopMonitor()
+}
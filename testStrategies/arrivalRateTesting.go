@@ -0,0 +1,196 @@
+package testStrategies
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scheduledArrival is a single unit of work handed to a virtual user by the
+// arrival rate scheduler: the iteration number, the time at which the
+// schedule intended it to start, and the interval to the arrival after it.
+type scheduledArrival struct {
+	iteration int
+	scheduled time.Time
+	interval  time.Duration
+}
+
+// arrivalSchedule returns the arrival with the given iteration number, or
+// false once the schedule has no more arrivals. It is called in order from
+// a single goroutine.
+type arrivalSchedule func(iteration int) (scheduledArrival, bool)
+
+// constantRateSchedule returns a schedule of configurationSettings.TargetRate
+// arrivals per second starting at scheduleStart, for as long as the run limit
+// allows.
+func constantRateSchedule(configurationSettings *perfTestUtils.Config, limit runLimit, scheduleStart time.Time) arrivalSchedule {
+	interval := time.Second / time.Duration(configurationSettings.TargetRate)
+	return func(iteration int) (scheduledArrival, bool) {
+		scheduled := scheduleStart.Add(time.Duration(iteration) * interval)
+		return scheduledArrival{iteration: iteration, scheduled: scheduled, interval: interval}, limit.allows(iteration, scheduled)
+	}
+}
+
+//----- executeAtArrivalRate --------------------------------------------------
+// Open model executor. Starts iterations at the times given by the schedule,
+// regardless of how long each iteration takes. Iterations are handed to a
+// pool of virtual users which starts at
+// configurationSettings.ConcurrentUsers and grows on demand up to
+// configurationSettings.MaxVirtualUsers. When all users are busy and the pool
+// cannot grow any further the arrival is dropped. Arrivals picked up after the
+// next one was already due are counted as late. runIteration is passed how
+// long after its scheduled time the iteration started. setUpUser, if any,
+// runs as a virtual user joins the pool, before it takes any arrival. No
+// arrivals are started once the test run has been stopped.
+func executeAtArrivalRate(
+	configurationSettings *perfTestUtils.Config,
+	perfStatsForTest *perfTestUtils.PerfStats,
+	schedule arrivalSchedule,
+	setUpUser func(userID int),
+	runIteration func(userID int, iteration int, startDelay time.Duration),
+) {
+	arrivals := make(chan scheduledArrival)
+	var userWaitGroup sync.WaitGroup
+	activeUsers := 0
+
+	runArrival := func(userID int, arrival scheduledArrival, received time.Time) {
+		startDelay := received.Sub(arrival.scheduled)
+		if startDelay > arrival.interval {
+			atomic.AddUint64(&perfStatsForTest.LateIterations, 1)
+		}
+		if startDelay < 0 {
+			startDelay = 0
+		}
+		runIteration(userID, arrival.iteration, startDelay)
+	}
+
+	// startUser adds a virtual user to the pool. A user added because every
+	// other user was busy is handed the arrival that found them busy, as of
+	// now, so that its setup delays neither that arrival nor the schedule.
+	startUser := func(first *scheduledArrival) {
+		userID := activeUsers
+		activeUsers++
+		received := time.Now()
+		userWaitGroup.Add(1)
+		go func() {
+			defer userWaitGroup.Done()
+			if setUpUser != nil {
+				setUpUser(userID)
+			}
+			if first != nil {
+				runArrival(userID, *first, received)
+			}
+			for arrival := range arrivals {
+				runArrival(userID, arrival, time.Now())
+			}
+		}()
+	}
+
+	// Pre-allocate the initial pool of virtual users.
+	for i := 0; i < initialArrivalRateUsers(configurationSettings); i++ {
+		startUser(nil)
+	}
+	log.Infof("ArrivalRate executor started. InitialUsers=[%d] MaxVirtualUsers=[%d]",
+		activeUsers,
+		configurationSettings.MaxVirtualUsers,
+	)
+
+	for i := 0; !isTestRunStopped(); i++ {
+		arrival, ok := schedule(i)
+		if !ok {
+			break
+		}
+		select {
+		case <-time.After(arrival.scheduled.Sub(time.Now())):
+		case <-testRunStopped():
+			continue
+		}
+
+		select {
+		case arrivals <- arrival:
+		default:
+			// Every virtual user is busy. Grow the pool if allowed,
+			// otherwise drop the arrival so the schedule is kept.
+			if activeUsers < configurationSettings.MaxVirtualUsers {
+				startUser(&arrival)
+			} else {
+				atomic.AddUint64(&perfStatsForTest.DroppedIterations, 1)
+				log.Debugf("ArrivalRate executor dropped iteration [%d]: all [%d] virtual users busy.", i, activeUsers)
+			}
+		}
+	}
+	close(arrivals)
+	waitForUsers(&userWaitGroup)
+
+	if dropped := atomic.LoadUint64(&perfStatsForTest.DroppedIterations); dropped > 0 {
+		log.Warnf("ArrivalRate executor dropped [%d] iterations. Increase maxVirtualUsers to hold the target rate.", dropped)
+	}
+	log.Infof("ArrivalRate executor finished. VirtualUsers=[%d] Dropped=[%d] Late=[%d]",
+		activeUsers,
+		atomic.LoadUint64(&perfStatsForTest.DroppedIterations),
+		atomic.LoadUint64(&perfStatsForTest.LateIterations),
+	)
+}
+
+// initialArrivalRateUsers returns the size of the initial pool of virtual
+// users of the ArrivalRate executor.
+func initialArrivalRateUsers(configurationSettings *perfTestUtils.Config) int {
+	if configurationSettings.ConcurrentUsers < configurationSettings.MaxVirtualUsers {
+		return configurationSettings.ConcurrentUsers
+	}
+	return configurationSettings.MaxVirtualUsers
+}
+
+//----- recordCorrectedResponseTime -------------------------------------------
+// Record the response time of a scheduled request measured from the time it
+// was intended to be sent, startDelay before it actually was. Measured from
+// the actual send time alone, a request held up behind a stalled server
+// hides the stall (coordinated omission). Failed requests are not recorded.
+func recordCorrectedResponseTime(
+	perfStatsForTest *perfTestUtils.PerfStats,
+	testName string,
+	responseTime int64,
+	startDelay time.Duration,
+) {
+	if responseTime <= 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if perfStatsForTest.ServiceCorrectedSamples == nil {
+		perfStatsForTest.ServiceCorrectedSamples = make(map[string][]int64)
+	}
+	perfStatsForTest.ServiceCorrectedSamples[testName] = append(perfStatsForTest.ServiceCorrectedSamples[testName], responseTime+int64(startDelay))
+}
+
+//----- recordCorrectedResponseTimes ------------------------------------------
+// Record the response times of an iteration of the test suite corrected for
+// the delay of its start, as if every request of the iteration had been sent
+// that much later than intended.
+func recordCorrectedResponseTimes(
+	perfStatsForTest *perfTestUtils.PerfStats,
+	responseTimes map[string][]int64,
+	startDelay time.Duration,
+) {
+	for serviceName, serviceResponseTimes := range responseTimes {
+		for _, responseTime := range serviceResponseTimes {
+			recordCorrectedResponseTime(perfStatsForTest, serviceName, responseTime, startDelay)
+		}
+	}
+}
+
+//----- CorrectedSamples ------------------------------------------------------
+// Return a copy of the corrected response times of each service. Users still
+// busy after the grace period of an interrupted run may go on adding to the
+// original.
+func CorrectedSamples(perfStatsForTest *perfTestUtils.PerfStats) map[string][]int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	snapshot := make(map[string][]int64, len(perfStatsForTest.ServiceCorrectedSamples))
+	for serviceName, samples := range perfStatsForTest.ServiceCorrectedSamples {
+		snapshot[serviceName] = append([]int64(nil), samples...)
+	}
+	return snapshot
+}
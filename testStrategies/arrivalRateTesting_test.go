@@ -0,0 +1,164 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecuteAtArrivalRate(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetRate = 200
+	config.ConcurrentUsers = 1
+	config.MaxVirtualUsers = 10
+	perfStats := &perfTestUtils.PerfStats{}
+
+	var m sync.Mutex
+	iterations := make(map[int]bool)
+	executeAtArrivalRate(config, perfStats, constantRateSchedule(config, runLimit{iterations: 20}, time.Now()), nil, func(userID int, iteration int, startDelay time.Duration) {
+		time.Sleep(10 * time.Millisecond)
+		m.Lock()
+		iterations[iteration] = true
+		m.Unlock()
+	})
+
+	// Ten users at 10ms per iteration easily hold 200 iterations/sec.
+	assert.Equal(t, 20, len(iterations))
+	assert.Equal(t, uint64(0), perfStats.DroppedIterations)
+}
+
+func TestExecuteAtArrivalRateDropsWhenPoolExhausted(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetRate = 100
+	config.ConcurrentUsers = 1
+	config.MaxVirtualUsers = 2
+	perfStats := &perfTestUtils.PerfStats{}
+
+	var m sync.Mutex
+	users := make(map[int]bool)
+	executed := 0
+	executeAtArrivalRate(config, perfStats, constantRateSchedule(config, runLimit{iterations: 10}, time.Now()), nil, func(userID int, iteration int, startDelay time.Duration) {
+		time.Sleep(100 * time.Millisecond)
+		m.Lock()
+		users[userID] = true
+		executed++
+		m.Unlock()
+	})
+
+	// Two users busy for 100ms cannot keep up with one arrival every 10ms.
+	assert.Equal(t, 2, len(users))
+	assert.True(t, perfStats.DroppedIterations > 0)
+	assert.Equal(t, 10, executed+int(perfStats.DroppedIterations))
+}
+
+func TestExecuteAtArrivalRateUntilDeadline(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetRate = 100
+	perfStats := &perfTestUtils.PerfStats{}
+
+	var m sync.Mutex
+	executed := 0
+	limit := runLimit{iterations: 1, deadline: time.Now().Add(200 * time.Millisecond)}
+	executeAtArrivalRate(config, perfStats, constantRateSchedule(config, limit, time.Now()), nil, func(userID int, iteration int, startDelay time.Duration) {
+		m.Lock()
+		executed++
+		m.Unlock()
+	})
+
+	// The deadline, not the iteration count, ends a duration based run.
+	assert.InDelta(t, 20, executed, 3)
+}
+
+func TestExecuteAtArrivalRateStopped(t *testing.T) {
+	defer resetTestRun()
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetRate = 100
+	perfStats := &perfTestUtils.PerfStats{}
+
+	time.AfterFunc(100*time.Millisecond, func() { StopTestRun("interrupted", time.Second) })
+
+	var m sync.Mutex
+	executed := 0
+	start := time.Now()
+	executeAtArrivalRate(config, perfStats, constantRateSchedule(config, runLimit{iterations: 1000}, time.Now()), nil, func(userID int, iteration int, startDelay time.Duration) {
+		m.Lock()
+		executed++
+		m.Unlock()
+	})
+
+	// No arrivals start once the run is stopped.
+	assert.True(t, time.Since(start) < time.Second)
+	assert.InDelta(t, 10, executed, 3)
+}
+
+func TestExecuteAtArrivalRateStartDelay(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.ConcurrentUsers = 1
+	perfStats := &perfTestUtils.PerfStats{}
+
+	// The iteration was due well before the executor got to it.
+	scheduled := time.Now().Add(-100 * time.Millisecond)
+	schedule := func(iteration int) (scheduledArrival, bool) {
+		return scheduledArrival{iteration: iteration, scheduled: scheduled, interval: time.Second}, iteration < 1
+	}
+	var startDelay time.Duration
+	executeAtArrivalRate(config, perfStats, schedule, nil, func(userID int, iteration int, delay time.Duration) {
+		startDelay = delay
+	})
+	assert.True(t, startDelay >= 100*time.Millisecond)
+	assert.Equal(t, uint64(0), perfStats.LateIterations)
+}
+
+func TestRecordCorrectedResponseTime(t *testing.T) {
+	perfStats := &perfTestUtils.PerfStats{}
+	recordCorrectedResponseTime(perfStats, "search", int64(5*time.Millisecond), 20*time.Millisecond)
+	recordCorrectedResponseTime(perfStats, "search", int64(5*time.Millisecond), 0)
+	// Failed requests have no response time to correct.
+	recordCorrectedResponseTime(perfStats, "checkout", 0, 20*time.Millisecond)
+
+	assert.Equal(t, []int64{int64(25 * time.Millisecond), int64(5 * time.Millisecond)}, perfStats.ServiceCorrectedSamples["search"])
+	assert.Nil(t, perfStats.ServiceCorrectedSamples["checkout"])
+}
+
+func TestExecuteAtArrivalRateSetUpUser(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetRate = 100
+	config.ConcurrentUsers = 1
+	config.MaxVirtualUsers = 20
+	perfStats := &perfTestUtils.PerfStats{}
+
+	var m sync.Mutex
+	setUp := make(map[int]int)
+	users := make(map[int]bool)
+	iterations := 0
+	executeAtArrivalRate(config, perfStats, constantRateSchedule(config, runLimit{iterations: 10}, time.Now()), func(userID int) {
+		time.Sleep(20 * time.Millisecond)
+		m.Lock()
+		setUp[userID]++
+		m.Unlock()
+	}, func(userID int, iteration int, startDelay time.Duration) {
+		m.Lock()
+		assert.Equal(t, 1, setUp[userID], "user %d", userID)
+		users[userID] = true
+		iterations++
+		m.Unlock()
+		time.Sleep(30 * time.Millisecond)
+	})
+
+	// Every user sets up once, before its first iteration, and the users
+	// added to keep up still run every arrival.
+	assert.Equal(t, 10, iterations)
+	assert.True(t, len(users) > 1)
+	assert.Equal(t, uint64(0), perfStats.DroppedIterations)
+	for userID := range users {
+		assert.Equal(t, 1, setUp[userID])
+	}
+}
@@ -0,0 +1,418 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jmespath/go-jmespath"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Assertions is the <assertions> block of a test definition: checks of the
+// response besides its status code. A response fails if any of them fails.
+type Assertions struct {
+	JMESPath    []JMESPathAssertion    `xml:"jmesPath"`
+	Body        []BodyAssertion        `xml:"body"`
+	Headers     []HeaderAssertion      `xml:"header"`
+	BodySize    []BodySizeAssertion    `xml:"bodySize"`
+	ContentType []ContentTypeAssertion `xml:"contentType"`
+	JSONSchema  []JSONSchemaAssertion  `xml:"jsonSchema"`
+}
+
+// JMESPathAssertion checks the result of a JMESPath expression on a JSON
+// response: that it Equals a value, Contains a value, as an element of an
+// array or a substring of a string, or Exists, that is it is not null. An
+// assertion with neither checks that the result exists. Exists "false"
+// checks that it does not.
+type JMESPathAssertion struct {
+	Name       string  `xml:"name,attr"`
+	Expression string  `xml:"expression,attr"`
+	Equals     *string `xml:"equals,attr"`
+	Contains   *string `xml:"contains,attr"`
+	Exists     string  `xml:"exists,attr"`
+}
+
+// BodyAssertion checks that the response body Matches a regular expression.
+type BodyAssertion struct {
+	Name    string `xml:"name,attr"`
+	Matches string `xml:"matches,attr"`
+
+	// matches is Matches compiled, see prepare.
+	matches *regexp.Regexp
+}
+
+// HeaderAssertion checks that the response has a header, and that the
+// header Equals a value if one is given.
+type HeaderAssertion struct {
+	Name   string  `xml:"name,attr"`
+	Key    string  `xml:"key,attr"`
+	Equals *string `xml:"equals,attr"`
+}
+
+// BodySizeAssertion checks that the size of the response body, in bytes, is
+// between Min and Max, inclusive. Either may be left out.
+type BodySizeAssertion struct {
+	Name string `xml:"name,attr"`
+	Min  string `xml:"min,attr"`
+	Max  string `xml:"max,attr"`
+}
+
+// ContentTypeAssertion checks the media type of the response, such as
+// "application/json", regardless of its parameters.
+type ContentTypeAssertion struct {
+	Name      string `xml:"name,attr"`
+	MediaType string `xml:",chardata"`
+}
+
+// JSONSchemaAssertion validates a JSON response against a JSON Schema, given
+// in the element or in a File relative to the test case directory. See
+// validateJSONSchema for the keywords supported.
+type JSONSchemaAssertion struct {
+	Name   string `xml:"name,attr"`
+	File   string `xml:"file,attr"`
+	Schema string `xml:",chardata"`
+
+	// schema is Schema parsed, see prepare.
+	schema interface{}
+}
+
+// assertionFailure is a failed assertion, by the name it is counted under.
+type assertionFailure struct {
+	name string
+	err  error
+}
+
+func (jp *JMESPathAssertion) name() string {
+	if jp.Name != "" {
+		return jp.Name
+	}
+	switch {
+	case jp.Equals != nil:
+		return fmt.Sprintf("jmesPath %s == %s", jp.Expression, *jp.Equals)
+	case jp.Contains != nil:
+		return fmt.Sprintf("jmesPath %s contains %s", jp.Expression, *jp.Contains)
+	case jp.Exists == "false":
+		return fmt.Sprintf("jmesPath %s does not exist", jp.Expression)
+	}
+	return fmt.Sprintf("jmesPath %s exists", jp.Expression)
+}
+
+func (jp *JMESPathAssertion) check(data interface{}) error {
+	result, err := jmespath.Search(jp.Expression, data)
+	if err != nil {
+		return err
+	}
+	switch {
+	case jp.Equals != nil:
+		if actual := assertionValue(result); actual != *jp.Equals {
+			return fmt.Errorf("got %s", actual)
+		}
+	case jp.Contains != nil:
+		if !containsValue(result, *jp.Contains) {
+			return fmt.Errorf("got %s", assertionValue(result))
+		}
+	case jp.Exists == "false":
+		if result != nil {
+			return fmt.Errorf("got %s", assertionValue(result))
+		}
+	default:
+		if result == nil {
+			return fmt.Errorf("got null")
+		}
+	}
+	return nil
+}
+
+// assertionValue returns a JSON value as text: strings as they are, any
+// other value as JSON.
+func assertionValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	bs, _ := json.Marshal(value)
+	return string(bs)
+}
+
+// containsValue returns whether an array has an element, or a string has a
+// substring, that reads as the expected value.
+func containsValue(value interface{}, expected string) bool {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, element := range v {
+			if assertionValue(element) == expected {
+				return true
+			}
+		}
+	case string:
+		return strings.Contains(v, expected)
+	}
+	return false
+}
+
+func (b *BodyAssertion) name() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return "body =~ " + b.Matches
+}
+
+// prepare compiles the regular expression once, rather than for every
+// response.
+func (b *BodyAssertion) prepare() error {
+	matches, err := regexp.Compile(b.Matches)
+	if err != nil {
+		return err
+	}
+	b.matches = matches
+	return nil
+}
+
+// UnmarshalJSON decodes the assertion of a test run sent to an agent, and
+// prepares it.
+func (b *BodyAssertion) UnmarshalJSON(data []byte) error {
+	type bodyAssertion BodyAssertion
+	if err := json.Unmarshal(data, (*bodyAssertion)(b)); err != nil {
+		return err
+	}
+	return b.prepare()
+}
+
+func (b *BodyAssertion) check(body []byte) error {
+	if b.matches == nil {
+		return fmt.Errorf("not prepared")
+	}
+	if !b.matches.Match(body) {
+		return fmt.Errorf("no match")
+	}
+	return nil
+}
+
+func (h *HeaderAssertion) name() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	if h.Equals != nil {
+		return fmt.Sprintf("header %s == %s", h.Key, *h.Equals)
+	}
+	return fmt.Sprintf("header %s present", h.Key)
+}
+
+func (h *HeaderAssertion) check(header http.Header) error {
+	values, ok := header[http.CanonicalHeaderKey(h.Key)]
+	if !ok {
+		return fmt.Errorf("no header")
+	}
+	if h.Equals != nil && strings.Join(values, ", ") != *h.Equals {
+		return fmt.Errorf("got %s", strings.Join(values, ", "))
+	}
+	return nil
+}
+
+func (bs *BodySizeAssertion) name() string {
+	if bs.Name != "" {
+		return bs.Name
+	}
+	return fmt.Sprintf("bodySize %s-%s", bs.Min, bs.Max)
+}
+
+func (bs *BodySizeAssertion) check(size int) error {
+	if min, err := strconv.Atoi(bs.Min); err == nil && size < min {
+		return fmt.Errorf("got %d bytes", size)
+	}
+	if max, err := strconv.Atoi(bs.Max); err == nil && size > max {
+		return fmt.Errorf("got %d bytes", size)
+	}
+	return nil
+}
+
+func (ct *ContentTypeAssertion) name() string {
+	if ct.Name != "" {
+		return ct.Name
+	}
+	return "contentType " + strings.TrimSpace(ct.MediaType)
+}
+
+func (ct *ContentTypeAssertion) check(header http.Header) error {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || !strings.EqualFold(mediaType, strings.TrimSpace(ct.MediaType)) {
+		return fmt.Errorf("got %s", header.Get("Content-Type"))
+	}
+	return nil
+}
+
+func (js *JSONSchemaAssertion) name() string {
+	if js.Name != "" {
+		return js.Name
+	}
+	if js.File != "" {
+		return "jsonSchema " + js.File
+	}
+	return "jsonSchema"
+}
+
+// prepare parses the schema once, rather than for every response.
+func (js *JSONSchemaAssertion) prepare() error {
+	schema, err := parseJSONSchema(js.Schema)
+	if err != nil {
+		return err
+	}
+	js.schema = schema
+	return nil
+}
+
+// UnmarshalJSON decodes the assertion of a test run sent to an agent, and
+// prepares it. The schema of a file has been read by the controller.
+func (js *JSONSchemaAssertion) UnmarshalJSON(data []byte) error {
+	type jsonSchemaAssertion JSONSchemaAssertion
+	if err := json.Unmarshal(data, (*jsonSchemaAssertion)(js)); err != nil {
+		return err
+	}
+	return js.prepare()
+}
+
+func (js *JSONSchemaAssertion) check(data interface{}) error {
+	if js.schema == nil {
+		return fmt.Errorf("not prepared")
+	}
+	return validateJSONSchema(js.schema, data, "$")
+}
+
+// validate checks the assertions when the test definition is loaded.
+func (a *Assertions) validate() error {
+	for i := range a.JMESPath {
+		jp := &a.JMESPath[i]
+		if _, err := jmespath.Compile(jp.Expression); err != nil {
+			return fmt.Errorf("assertion [%s]: %v", jp.name(), err)
+		}
+		if jp.Exists != "" && jp.Exists != "true" && jp.Exists != "false" {
+			return fmt.Errorf("assertion [%s]: invalid exists [%s]", jp.name(), jp.Exists)
+		}
+	}
+	for i := range a.Body {
+		if err := a.Body[i].prepare(); err != nil {
+			return fmt.Errorf("assertion [%s]: %v", a.Body[i].name(), err)
+		}
+	}
+	for i := range a.Headers {
+		if strings.TrimSpace(a.Headers[i].Key) == "" {
+			return fmt.Errorf("assertion [%s]: no header key", a.Headers[i].name())
+		}
+	}
+	for i := range a.BodySize {
+		bs := &a.BodySize[i]
+		for _, bound := range []string{bs.Min, bs.Max} {
+			if n, err := strconv.Atoi(bound); bound != "" && (err != nil || n < 0) {
+				return fmt.Errorf("assertion [%s]: invalid size [%s]", bs.name(), bound)
+			}
+		}
+	}
+	for i := range a.ContentType {
+		if strings.TrimSpace(a.ContentType[i].MediaType) == "" {
+			return fmt.Errorf("assertion [%s]: no media type", a.ContentType[i].name())
+		}
+	}
+	for i := range a.JSONSchema {
+		js := &a.JSONSchema[i]
+		if js.File != "" && strings.TrimSpace(js.Schema) == "" {
+			// Checked once the file is read, see loadSchemas.
+			continue
+		}
+		if err := js.prepare(); err != nil {
+			return fmt.Errorf("assertion [%s]: %v", js.name(), err)
+		}
+	}
+	return nil
+}
+
+// loadSchemas reads the JSON schemas given in files, relative to dir, into
+// the assertions, so that they are sent to the agents of a distributed run
+// with the test definition.
+func (a *Assertions) loadSchemas(dir string) error {
+	for i := range a.JSONSchema {
+		js := &a.JSONSchema[i]
+		if js.File == "" || strings.TrimSpace(js.Schema) != "" {
+			continue
+		}
+		bs, err := ioutil.ReadFile(filepath.Join(dir, js.File))
+		if err != nil {
+			return fmt.Errorf("assertion [%s]: %v", js.name(), err)
+		}
+		js.Schema = string(bs)
+		if err := js.prepare(); err != nil {
+			return fmt.Errorf("assertion [%s]: %v", js.name(), err)
+		}
+	}
+	return nil
+}
+
+// check runs every assertion against the response, and returns the ones that
+// failed.
+func (a *Assertions) check(resp *http.Response, body []byte) []assertionFailure {
+	failures := make([]assertionFailure, 0)
+	fail := func(name string, err error) {
+		if err != nil {
+			failures = append(failures, assertionFailure{name: name, err: err})
+		}
+	}
+
+	for i := range a.Headers {
+		fail(a.Headers[i].name(), a.Headers[i].check(resp.Header))
+	}
+	for i := range a.ContentType {
+		fail(a.ContentType[i].name(), a.ContentType[i].check(resp.Header))
+	}
+	for i := range a.BodySize {
+		fail(a.BodySize[i].name(), a.BodySize[i].check(len(body)))
+	}
+	for i := range a.Body {
+		fail(a.Body[i].name(), a.Body[i].check(body))
+	}
+
+	if len(a.JMESPath) == 0 && len(a.JSONSchema) == 0 {
+		return failures
+	}
+	var data interface{}
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		err = fmt.Errorf("the response is not JSON: %v", err)
+	}
+	for i := range a.JMESPath {
+		if err != nil {
+			fail(a.JMESPath[i].name(), err)
+			continue
+		}
+		fail(a.JMESPath[i].name(), a.JMESPath[i].check(data))
+	}
+	for i := range a.JSONSchema {
+		if err != nil {
+			fail(a.JSONSchema[i].name(), err)
+			continue
+		}
+		fail(a.JSONSchema[i].name(), a.JSONSchema[i].check(data))
+	}
+	return failures
+}
+
+// recordAssertionFailure counts a failed assertion of a service.
+func recordAssertionFailure(perfStatsForTest *perfTestUtils.PerfStats, serviceName string, assertionName string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if perfStatsForTest.ServiceAssertionFailures == nil {
+		perfStatsForTest.ServiceAssertionFailures = make(map[string]map[string]*uint64)
+	}
+	failures := perfStatsForTest.ServiceAssertionFailures[serviceName]
+	if failures == nil {
+		failures = make(map[string]*uint64)
+		perfStatsForTest.ServiceAssertionFailures[serviceName] = failures
+	}
+	if failures[assertionName] == nil {
+		failures[assertionName] = new(uint64)
+	}
+	*failures[assertionName]++
+}
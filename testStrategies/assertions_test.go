@@ -0,0 +1,189 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const xmlAssertionsTestDefinition = `<testDefinition>
+    <testName>getOrder</testName>
+    <httpMethod>GET</httpMethod>
+    <baseUri>/orders/17</baseUri>
+    <responseStatusCode>200</responseStatusCode>
+    <assertions>
+        <jmesPath expression="order.status" equals="shipped"/>
+        <jmesPath expression="order.tags" contains="gift"/>
+        <jmesPath name="no error" expression="error" exists="false"/>
+        <body matches="&quot;id&quot;:\s*17"/>
+        <header key="X-Request-Id"/>
+        <header key="Cache-Control" equals="no-store"/>
+        <bodySize min="10" max="1000"/>
+        <contentType>application/json</contentType>
+        <jsonSchema>{"type": "object", "required": ["order"]}</jsonSchema>
+    </assertions>
+</testDefinition>`
+
+func newAssertionsTestServer(body string, contentType string) (*httptest.Server, string, string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Request-Id", "r-1")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(body))
+	}))
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	return server, host, port
+}
+
+func TestLoadAssertions(t *testing.T) {
+	td, err := loadTestDefinition([]byte(xmlAssertionsTestDefinition))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(td.Assertions.JMESPath))
+	assert.Equal(t, "shipped", *td.Assertions.JMESPath[0].Equals)
+	assert.Nil(t, td.Assertions.JMESPath[0].Contains)
+	assert.Equal(t, "jmesPath order.status == shipped", td.Assertions.JMESPath[0].name())
+	assert.Equal(t, "no error", td.Assertions.JMESPath[2].name())
+	assert.Equal(t, "header X-Request-Id present", td.Assertions.Headers[0].name())
+	assert.Equal(t, "bodySize 10-1000", td.Assertions.BodySize[0].name())
+	assert.Equal(t, "contentType application/json", td.Assertions.ContentType[0].name())
+
+	for _, invalid := range []string{
+		`<jmesPath expression="order.["/>`,
+		`<jmesPath expression="order" exists="maybe"/>`,
+		`<body matches="("/>`,
+		`<header equals="x"/>`,
+		`<bodySize min="-1"/>`,
+		`<bodySize max="lots"/>`,
+		`<contentType/>`,
+		`<jsonSchema>{"type": </jsonSchema>`,
+		`<jsonSchema>{"$ref": "#/definitions/order"}</jsonSchema>`,
+	} {
+		_, err := loadTestDefinition([]byte(`<testDefinition><testName>a</testName><assertions>` + invalid + `</assertions></testDefinition>`))
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestAssertionsCheck(t *testing.T) {
+	server, host, port := newAssertionsTestServer(`{"order": {"id": 17, "status": "shipped", "tags": ["gift", "express"]}}`, "application/json; charset=utf-8")
+	defer server.Close()
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	td, _ := loadTestDefinition([]byte(xmlAssertionsTestDefinition))
+	perfStats := &perfTestUtils.PerfStats{}
+
+	responseTime, err := td.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.Nil(t, err)
+	assert.True(t, responseTime > 0)
+	assert.Nil(t, perfStats.ServiceAssertionFailures)
+
+	// Every failed assertion is counted, the request fails once.
+	td.Assertions.JMESPath[0].Equals = stringPtr("delivered")
+	td.Assertions.Headers[1].Equals = stringPtr("no-cache")
+	for i := 0; i < 2; i++ {
+		responseTime, err = td.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+		assert.EqualError(t, err, "assertion [header Cache-Control == no-cache] failed: got no-store")
+		assert.Equal(t, int64(0), responseTime)
+	}
+	assert.Equal(t, 2, len(perfStats.ServiceAssertionFailures["getOrder"]))
+	assert.Equal(t, uint64(2), *perfStats.ServiceAssertionFailures["getOrder"]["jmesPath order.status == delivered"])
+	assert.Equal(t, uint64(2), *perfStats.ServiceAssertionFailures["getOrder"]["header Cache-Control == no-cache"])
+}
+
+func TestAssertionsCheckFailures(t *testing.T) {
+	server, host, port := newAssertionsTestServer(`<order id="17"/>`, "text/xml")
+	defer server.Close()
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	td, _ := loadTestDefinition([]byte(xmlAssertionsTestDefinition))
+	perfStats := &perfTestUtils.PerfStats{}
+
+	_, err := td.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.EqualError(t, err, "assertion [contentType application/json] failed: got text/xml")
+
+	// The checks of JSON responses fail for a response that is not JSON.
+	failures := perfStats.ServiceAssertionFailures["getOrder"]
+	assert.Equal(t, 6, len(failures))
+	for _, name := range []string{"contentType application/json", `body =~ "id":\s*17`, "jmesPath order.status == shipped", "jmesPath order.tags contains gift", "no error", "jsonSchema"} {
+		assert.NotNil(t, failures[name], name)
+	}
+}
+
+func TestAssertionsCheckValues(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	check := func(a *Assertions, body string) []assertionFailure {
+		return a.check(resp, []byte(body))
+	}
+
+	a := &Assertions{JMESPath: []JMESPathAssertion{{Expression: "items[0].qty", Equals: stringPtr("3")}}}
+	assert.Equal(t, 0, len(check(a, `{"items": [{"qty": 3}]}`)))
+	assert.Equal(t, "got 4", check(a, `{"items": [{"qty": 4}]}`)[0].err.Error())
+
+	a = &Assertions{JMESPath: []JMESPathAssertion{{Expression: "name", Contains: stringPtr("ord")}}}
+	assert.Equal(t, 0, len(check(a, `{"name": "order"}`)))
+	assert.Equal(t, 1, len(check(a, `{"name": ["order"]}`)))
+
+	a = &Assertions{JMESPath: []JMESPathAssertion{{Expression: "id"}}}
+	assert.Equal(t, 0, len(check(a, `{"id": 0}`)))
+	assert.Equal(t, "got null", check(a, `{"id": null}`)[0].err.Error())
+
+	a = &Assertions{BodySize: []BodySizeAssertion{{Max: "5"}}}
+	assert.Equal(t, 0, len(check(a, `12345`)))
+	assert.Equal(t, "got 6 bytes", check(a, `123456`)[0].err.Error())
+
+	resp.Header.Set("Content-Type", "Application/JSON")
+	a = &Assertions{ContentType: []ContentTypeAssertion{{MediaType: " application/json\n"}}}
+	assert.Equal(t, 0, len(check(a, `{}`)))
+}
+
+func TestAssertionsPrepared(t *testing.T) {
+	td, err := loadTestDefinition([]byte(xmlAssertionsTestDefinition))
+	assert.Nil(t, err)
+	resp := &http.Response{Header: http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"r-1"}, "Cache-Control": {"no-store"}}}
+	body := []byte(`{"order": {"id": 17, "status": "shipped", "tags": ["gift"]}}`)
+	assert.Equal(t, 0, len(td.Assertions.check(resp, body)))
+
+	// The assertions of a test run sent to an agent are prepared as they are
+	// decoded.
+	bs, err := json.Marshal(td.Assertions)
+	assert.Nil(t, err)
+	decoded := new(Assertions)
+	assert.Nil(t, json.Unmarshal(bs, decoded))
+	assert.NotNil(t, decoded.Body[0].matches)
+	assert.NotNil(t, decoded.JSONSchema[0].schema)
+	assert.Equal(t, 0, len(decoded.check(resp, body)))
+	assert.Equal(t, 4, len(decoded.check(resp, []byte(`{"id": 18}`))))
+
+	// An assertion that was not prepared fails.
+	a := &Assertions{Body: []BodyAssertion{{Matches: "."}}}
+	assert.Equal(t, "not prepared", a.check(resp, body)[0].err.Error())
+}
+
+func TestLoadSchemas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemas")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "order.json"), []byte(`{"type": "object", "required": ["id"]}`), 0644)
+
+	a := &Assertions{JSONSchema: []JSONSchemaAssertion{{File: "order.json"}}}
+	assert.Nil(t, a.validate())
+	assert.Nil(t, a.loadSchemas(dir))
+	assert.Equal(t, "jsonSchema order.json", a.JSONSchema[0].name())
+	assert.Equal(t, 0, len(a.check(&http.Response{}, []byte(`{"id": 1}`))))
+	assert.Equal(t, "$.id is required", a.check(&http.Response{}, []byte(`{}`))[0].err.Error())
+
+	a = &Assertions{JSONSchema: []JSONSchemaAssertion{{File: "missing.json"}}}
+	assert.NotNil(t, a.loadSchemas(dir))
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,319 @@
+package testStrategies
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authProvider adds credentials to a request before it is sent. The payload
+// is the body of the request, nil if it has none.
+type authProvider interface {
+	authorize(req *http.Request, payload []byte) error
+}
+
+// authProviders holds the providers of each configuration, created on first
+// use. Providers are shared by all virtual users, so that a token is fetched
+// once and reused until it needs refreshing.
+var (
+	authProvidersMu sync.Mutex
+	authProviders   = make(map[*perfTestUtils.Config]map[string]authProvider)
+)
+
+// authorizeRequest adds the credentials of the named auth provider to the
+// request, whose body is payload.
+func authorizeRequest(configurationSettings *perfTestUtils.Config, name string, req *http.Request, payload []byte) error {
+	authProvidersMu.Lock()
+	providers := authProviders[configurationSettings]
+	if providers == nil {
+		providers = make(map[string]authProvider)
+		for _, settings := range configurationSettings.AuthProviders {
+			providers[settings.Name] = newAuthProvider(configurationSettings, settings)
+		}
+		authProviders[configurationSettings] = providers
+	}
+	provider := providers[name]
+	authProvidersMu.Unlock()
+
+	if provider == nil {
+		return fmt.Errorf("unknown auth provider [%s]", name)
+	}
+	return provider.authorize(req, payload)
+}
+
+// newAuthProvider returns the provider for the given settings. A provider
+// that cannot be set up fails every request it is used for.
+func newAuthProvider(configurationSettings *perfTestUtils.Config, settings perfTestUtils.AuthProvider) authProvider {
+	switch settings.Type {
+	case perfTestUtils.AuthBasic:
+		return &basicAuth{username: settings.Username, password: settings.Password}
+	case perfTestUtils.AuthOAuth2ClientCredentials:
+		return &oauth2ClientCredentials{settings: settings, client: newHTTPClient(configurationSettings)}
+	case perfTestUtils.AuthJWT:
+		provider, err := newJWTAuth(settings)
+		if err != nil {
+			return failedAuth{err: err}
+		}
+		return provider
+	case perfTestUtils.AuthHMAC:
+		return &hmacAuth{keyID: settings.KeyID, secret: []byte(settings.Secret)}
+	}
+	return failedAuth{err: fmt.Errorf("unknown auth provider type [%s]", settings.Type)}
+}
+
+// failedAuth is a provider that could not be set up.
+type failedAuth struct {
+	err error
+}
+
+func (fa failedAuth) authorize(req *http.Request, payload []byte) error {
+	return fa.err
+}
+
+//----- basic -----------------------------------------------------------------
+
+// basicAuth sends a username and password with every request.
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (ba *basicAuth) authorize(req *http.Request, payload []byte) error {
+	req.SetBasicAuth(ba.username, ba.password)
+	return nil
+}
+
+//----- bearer tokens ---------------------------------------------------------
+
+// cachedToken holds a bearer token until it is due for refresh. A zero
+// expiry means the token does not expire.
+type cachedToken struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// get returns the cached token, or a new one from fetch if there is none yet
+// or the cached one expires within refreshBefore. Concurrent callers wait
+// for a single fetch.
+func (ct *cachedToken) get(now time.Time, refreshBefore time.Duration, fetch func(now time.Time) (string, time.Time, error)) (string, error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.token != "" && (ct.expiry.IsZero() || now.Before(ct.expiry.Add(-refreshBefore))) {
+		return ct.token, nil
+	}
+	token, expiry, err := fetch(now)
+	if err != nil {
+		return "", err
+	}
+	ct.token = token
+	ct.expiry = expiry
+	return token, nil
+}
+
+//----- oauth2ClientCredentials -----------------------------------------------
+
+// oauth2ClientCredentials fetches a bearer token from the token endpoint
+// using the client credentials grant.
+type oauth2ClientCredentials struct {
+	settings perfTestUtils.AuthProvider
+	client   *http.Client
+	token    cachedToken
+}
+
+func (oa *oauth2ClientCredentials) authorize(req *http.Request, payload []byte) error {
+	token, err := oa.token.get(time.Now(), oa.settings.RefreshBeforeDuration(), oa.fetchToken)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// fetchToken requests a new access token and returns it with its expiry.
+func (oa *oauth2ClientCredentials) fetchToken(now time.Time) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if oa.settings.Scope != "" {
+		form.Set("scope", oa.settings.Scope)
+	}
+	req, err := http.NewRequest("POST", oa.settings.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(oa.settings.ClientID), url.QueryEscape(oa.settings.ClientSecret))
+
+	resp, err := oa.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("token request failed: %v", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", time.Time{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	tokenResponse := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid token response: %v", err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", time.Time{}, errors.New("no access_token in the token response")
+	}
+	var expiry time.Time
+	if tokenResponse.ExpiresIn > 0 {
+		expiry = now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	}
+	return tokenResponse.AccessToken, expiry, nil
+}
+
+//----- jwt -------------------------------------------------------------------
+
+// jwtAuth sends a locally generated and signed JWT as a bearer token.
+type jwtAuth struct {
+	settings perfTestUtils.AuthProvider
+	hsKey    []byte
+	rsaKey   *rsa.PrivateKey
+	token    cachedToken
+}
+
+// newJWTAuth returns a JWT provider, loading the RS256 private key if any.
+func newJWTAuth(settings perfTestUtils.AuthProvider) (*jwtAuth, error) {
+	ja := &jwtAuth{settings: settings}
+	if settings.Algorithm != "RS256" {
+		ja.hsKey = []byte(settings.Secret)
+		return ja, nil
+	}
+
+	pemBytes, err := ioutil.ReadFile(settings.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the JWT keyFile: %v", err)
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in the JWT keyFile [%s]", settings.KeyFile)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		ja.rsaKey = key
+		return ja, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT keyFile [%s]: %v", settings.KeyFile, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the JWT keyFile [%s] does not hold an RSA key", settings.KeyFile)
+	}
+	ja.rsaKey = rsaKey
+	return ja, nil
+}
+
+func (ja *jwtAuth) authorize(req *http.Request, payload []byte) error {
+	token, err := ja.token.get(time.Now(), ja.settings.RefreshBeforeDuration(), ja.generate)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// generate returns a new signed token, valid from now for the configured
+// lifetime.
+func (ja *jwtAuth) generate(now time.Time) (string, time.Time, error) {
+	expiry := now.Add(ja.settings.LifetimeDuration())
+	claims := map[string]interface{}{
+		"iat": now.Unix(),
+		"exp": expiry.Unix(),
+	}
+	if ja.settings.Issuer != "" {
+		claims["iss"] = ja.settings.Issuer
+	}
+	if ja.settings.Subject != "" {
+		claims["sub"] = ja.settings.Subject
+	}
+	if ja.settings.Audience != "" {
+		claims["aud"] = ja.settings.Audience
+	}
+	for _, claim := range ja.settings.Claims {
+		claims[claim.Name] = claim.Value
+	}
+
+	algorithm := "HS256"
+	if ja.rsaKey != nil {
+		algorithm = "RS256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": algorithm, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	if ja.rsaKey != nil {
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, ja.rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", time.Time{}, err
+		}
+	} else {
+		mac := hmac.New(sha256.New, ja.hsKey)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), expiry, nil
+}
+
+//----- hmac ------------------------------------------------------------------
+
+// hmacAuth signs every request with HMAC-SHA256. The signed string is the
+// method, the request URI, the X-Date header and the hex SHA-256 digest of
+// the body, separated by newlines.
+type hmacAuth struct {
+	keyID  string
+	secret []byte
+}
+
+func (ha *hmacAuth) authorize(req *http.Request, payload []byte) error {
+	date := time.Now().UTC().Format(http.TimeFormat)
+	bodyDigest := sha256.Sum256(payload)
+	contentDigest := hex.EncodeToString(bodyDigest[:])
+	var stringToSign bytes.Buffer
+	stringToSign.WriteString(req.Method + "\n")
+	stringToSign.WriteString(req.URL.RequestURI() + "\n")
+	stringToSign.WriteString(date + "\n")
+	stringToSign.WriteString(contentDigest)
+
+	mac := hmac.New(sha256.New, ha.secret)
+	mac.Write(stringToSign.Bytes())
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	req.Header.Set("X-Date", date)
+	req.Header.Set("X-Content-SHA256", contentDigest)
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC-SHA256 keyId=\"%s\", signature=\"%s\"", ha.keyID, signature))
+	return nil
+}
@@ -0,0 +1,201 @@
+package testStrategies
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func authConfig(providers ...perfTestUtils.AuthProvider) *perfTestUtils.Config {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.AuthProviders = providers
+	config.PrintAndValidateConfig()
+	return config
+}
+
+func authorizedRequest(t *testing.T, config *perfTestUtils.Config, name string, body string) *http.Request {
+	req, _ := http.NewRequest("POST", "http://localhost/orders?page=2", strings.NewReader(body))
+	err := authorizeRequest(config, name, req, []byte(body))
+	assert.Nil(t, err)
+	return req
+}
+
+func TestBasicAuth(t *testing.T) {
+	config := authConfig(perfTestUtils.AuthProvider{Name: "admin", Type: perfTestUtils.AuthBasic, Username: "alice", Password: "pa55"})
+	req := authorizedRequest(t, config, "admin", "")
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "alice", username)
+	assert.Equal(t, "pa55", password)
+
+	err := authorizeRequest(config, "missing", req, nil)
+	assert.NotNil(t, err)
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var fetches int32
+	expiresIn := 3600
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		r.ParseForm()
+		if clientID != "perf" || clientSecret != "s3cr3t" || r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "orders" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&fetches, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	defer tokenServer.Close()
+
+	provider := perfTestUtils.AuthProvider{
+		Name:         "api",
+		Type:         perfTestUtils.AuthOAuth2ClientCredentials,
+		TokenURL:     tokenServer.URL + "/token",
+		ClientID:     "perf",
+		ClientSecret: "s3cr3t",
+		Scope:        "orders",
+	}
+	config := authConfig(provider)
+
+	// The token is fetched once and shared.
+	for i := 0; i < 3; i++ {
+		req := authorizedRequest(t, config, "api", "")
+		assert.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// A token that expires within refreshBefore is replaced.
+	expiresIn = 10
+	config = authConfig(provider)
+	assert.Equal(t, "Bearer token-2", authorizedRequest(t, config, "api", "").Header.Get("Authorization"))
+	assert.Equal(t, "Bearer token-3", authorizedRequest(t, config, "api", "").Header.Get("Authorization"))
+
+	// A rejected token request fails the request.
+	provider.ClientSecret = "wrong"
+	config = authConfig(provider)
+	req, _ := http.NewRequest("GET", "http://localhost/orders", nil)
+	assert.NotNil(t, authorizeRequest(config, "api", req, nil))
+}
+
+func TestOAuth2ClientCredentialsRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token":"abc","expires_in":3600}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := authConfig(perfTestUtils.AuthProvider{Name: "api", Type: perfTestUtils.AuthOAuth2ClientCredentials, TokenURL: server.URL + "/token", ClientID: "perf"})
+	config.TargetHost = host
+	config.TargetPort = port
+	testDefinition := &TestDefinition{TestName: "orders", HTTPMethod: "GET", BaseURI: "/orders", ResponseStatusCode: 200, Auth: "api"}
+
+	responseTime, err := testDefinition.BuildAndSendRequest(httpClientFor(config, 0), config, &perfTestUtils.PerfStats{}, "http", host, port, "")
+	assert.Nil(t, err)
+	assert.True(t, responseTime > 0)
+}
+
+func decodeJWT(t *testing.T, token string) (map[string]string, map[string]interface{}, []byte, string) {
+	parts := strings.Split(token, ".")
+	assert.Equal(t, 3, len(parts))
+	header := make(map[string]string)
+	claims := make(map[string]interface{})
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.Nil(t, json.Unmarshal(headerJSON, &header))
+	assert.Nil(t, json.Unmarshal(claimsJSON, &claims))
+	return header, claims, signature, parts[0] + "." + parts[1]
+}
+
+func TestJWTAuthHS256(t *testing.T) {
+	config := authConfig(perfTestUtils.AuthProvider{
+		Name:     "jwt",
+		Type:     perfTestUtils.AuthJWT,
+		Secret:   "signing-key",
+		Issuer:   "perf-test",
+		Subject:  "user-1",
+		Audience: "orders-api",
+		Lifetime: "10m",
+		Claims:   []perfTestUtils.AuthClaim{{Name: "role", Value: "admin"}},
+	})
+	authorization := authorizedRequest(t, config, "jwt", "").Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(authorization, "Bearer "))
+
+	header, claims, signature, signingInput := decodeJWT(t, strings.TrimPrefix(authorization, "Bearer "))
+	assert.Equal(t, "HS256", header["alg"])
+	assert.Equal(t, "perf-test", claims["iss"])
+	assert.Equal(t, "user-1", claims["sub"])
+	assert.Equal(t, "orders-api", claims["aud"])
+	assert.Equal(t, "admin", claims["role"])
+	assert.Equal(t, float64(600), claims["exp"].(float64)-claims["iat"].(float64))
+
+	mac := hmac.New(sha256.New, []byte("signing-key"))
+	mac.Write([]byte(signingInput))
+	assert.Equal(t, mac.Sum(nil), signature)
+
+	// The token is reused until it is due for refresh.
+	assert.Equal(t, authorization, authorizedRequest(t, config, "jwt", "").Header.Get("Authorization"))
+}
+
+func TestJWTAuthRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	dir, err := ioutil.TempDir("", "jwt")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := writePEM(t, dir, "key.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+	config := authConfig(perfTestUtils.AuthProvider{Name: "jwt", Type: perfTestUtils.AuthJWT, Algorithm: "RS256", KeyFile: keyFile})
+	authorization := authorizedRequest(t, config, "jwt", "").Header.Get("Authorization")
+
+	header, _, signature, signingInput := decodeJWT(t, strings.TrimPrefix(authorization, "Bearer "))
+	assert.Equal(t, "RS256", header["alg"])
+	digest := sha256.Sum256([]byte(signingInput))
+	assert.Nil(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	// A key that cannot be loaded fails the requests.
+	config = authConfig(perfTestUtils.AuthProvider{Name: "jwt", Type: perfTestUtils.AuthJWT, Algorithm: "RS256", KeyFile: dir + "/missing.pem"})
+	req, _ := http.NewRequest("GET", "http://localhost/orders", nil)
+	assert.NotNil(t, authorizeRequest(config, "jwt", req, nil))
+}
+
+func TestHMACAuth(t *testing.T) {
+	config := authConfig(perfTestUtils.AuthProvider{Name: "signed", Type: perfTestUtils.AuthHMAC, KeyID: "key-1", Secret: "shared"})
+	req := authorizedRequest(t, config, "signed", `{"item":42}`)
+
+	date := req.Header.Get("X-Date")
+	_, err := time.Parse(http.TimeFormat, date)
+	assert.Nil(t, err)
+	bodyDigest := sha256.Sum256([]byte(`{"item":42}`))
+	assert.Equal(t, hex.EncodeToString(bodyDigest[:]), req.Header.Get("X-Content-SHA256"))
+
+	mac := hmac.New(sha256.New, []byte("shared"))
+	mac.Write([]byte("POST\n/orders?page=2\n" + date + "\n" + hex.EncodeToString(bodyDigest[:])))
+	expected := fmt.Sprintf("HMAC-SHA256 keyId=\"key-1\", signature=\"%s\"", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	assert.Equal(t, expected, req.Header.Get("Authorization"))
+}
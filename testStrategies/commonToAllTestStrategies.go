@@ -28,9 +28,131 @@ const (
 	SuiteBasedTesting   = "SuiteBased"
 )
 
+// runLimit controls how long virtual users keep starting new iterations:
+// either a fixed number of iterations, or a wall-clock deadline for duration
+// based runs. An iteration already in progress at the deadline is always
+// allowed to complete.
+type runLimit struct {
+	iterations int
+	deadline   time.Time
+}
+
+// newRunLimit returns the run limit for the given configuration. Duration
+// based runs end at runStart plus the configured duration.
+func newRunLimit(configurationSettings *perfTestUtils.Config, runStart time.Time) runLimit {
+	limit := runLimit{iterations: configurationSettings.NumIterations}
+	if d := configurationSettings.RunDuration(); d > 0 {
+		limit.deadline = runStart.Add(d)
+	}
+	return limit
+}
+
+// allows returns true if an iteration with the given number may start at
+// the given time. No iteration may start once the test run has been stopped.
+func (rl runLimit) allows(iteration int, now time.Time) bool {
+	if isTestRunStopped() {
+		return false
+	}
+	if !rl.deadline.IsZero() {
+		return now.Before(rl.deadline)
+	}
+	return iteration < rl.iterations
+}
+
 // Global Mutex
 var mu sync.Mutex
 
+// Test run stop control. stopChan is closed when the test run is stopped,
+// graceChan once the grace period for requests in progress has elapsed
+// after that.
+var (
+	stopMu     sync.Mutex
+	stopChan   = make(chan struct{})
+	graceChan  = make(chan struct{})
+	stopReason string
+)
+
+// StopTestRun stops the test run: executors start no new iterations and
+// wait up to gracePeriod for the ones in progress before returning the
+// results collected so far. Only the first call has any effect.
+func StopTestRun(reason string, gracePeriod time.Duration) {
+	stopMu.Lock()
+	defer stopMu.Unlock()
+	if stopReason != "" {
+		return
+	}
+	stopReason = reason
+	close(stopChan)
+
+	expired := graceChan
+	time.AfterFunc(gracePeriod, func() { close(expired) })
+}
+
+// resetTestRun clears the stop state of a stopped test run, so that an agent
+// can run the next test after it.
+func resetTestRun() {
+	stopMu.Lock()
+	defer stopMu.Unlock()
+	if stopReason == "" {
+		return
+	}
+	stopReason = ""
+	stopChan = make(chan struct{})
+	graceChan = make(chan struct{})
+}
+
+// TestRunStopReason returns the reason the test run was stopped, or an empty
+// string if it is still running.
+func TestRunStopReason() string {
+	stopMu.Lock()
+	defer stopMu.Unlock()
+	return stopReason
+}
+
+// isTestRunStopped returns true once StopTestRun has been called.
+func isTestRunStopped() bool {
+	select {
+	case <-testRunStopped():
+		return true
+	default:
+		return false
+	}
+}
+
+// testRunStopped returns a channel that is closed when the test run stops.
+func testRunStopped() <-chan struct{} {
+	stopMu.Lock()
+	defer stopMu.Unlock()
+	return stopChan
+}
+
+// gracePeriodExpired returns a channel that is closed when the grace period
+// of a stopped test run has elapsed.
+func gracePeriodExpired() <-chan struct{} {
+	stopMu.Lock()
+	defer stopMu.Unlock()
+	return graceChan
+}
+
+// waitForUsers waits for the virtual users of wg to finish. Once the test
+// run has been stopped it waits no longer than the grace period, and returns
+// false if users were still busy when it expired.
+func waitForUsers(wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-gracePeriodExpired():
+		log.Warn("Grace period expired with requests still in progress. Continuing with the results collected so far.")
+		return false
+	}
+}
+
 // globalsMap contains parameter substitution across concurrent threads.
 var globalsMap = make(map[string]map[string]interface{})
 
@@ -47,12 +169,21 @@ type ResponseValue struct {
 	ExtractionKey string `xml:"extractionKey,attr"`
 }
 
+// ExpectedCookies lists the cookies a response is checked for: cookies the
+// response must set, and cookies that must be present in the cookie jar of
+// the virtual user once the response has been received.
+type ExpectedCookies struct {
+	Set     []string `xml:"set"`
+	Present []string `xml:"present"`
+}
+
 // TestDefinition encapsulates the XML data.
 type TestDefinition struct {
 	XMLName             xml.Name             `xml:"testDefinition"`
 	TestName            string               `xml:"testName"`
 	OverrideHost        string               `xml:"overrideHost"`
 	OverridePort        string               `xml:"overridePort"`
+	OverrideScheme      string               `xml:"overrideScheme"`
 	HTTPMethod          string               `xml:"httpMethod"`
 	BaseURI             string               `xml:"baseUri"`
 	Multipart           bool                 `xml:"multipart"`
@@ -62,19 +193,43 @@ type TestDefinition struct {
 	ResponseContentType string               `xml:"responseContentType"`
 	Headers             []Header             `xml:"headers>header"`
 	ResponseValues      []ResponseValue      `xml:"responseProperties>value"`
-	PreThinkTime        int64
-	PostThinkTime       int64
+	ExpectedCookies     ExpectedCookies      `xml:"expectedCookies"`
+	Assertions          Assertions           `xml:"assertions"`
+	Auth                string               `xml:"auth"`
+	Retry               *RetryPolicy         `xml:"retry"`
+	WebSocket           *WebSocketStep       `xml:"webSocket"`
+	GraphQL             *GraphQLRequest      `xml:"graphQL"`
+	PreThinkTime        perfTestUtils.ThinkTime
+	PostThinkTime       perfTestUtils.ThinkTime
 	ExecWeight          string
 }
 
 // TestSuite fields get populated from the TestSuiteDefinition after the XML
 // unmarshal is complete. (See TestDefinition above).
 type TestSuite struct {
-	XMLName         xml.Name   `xml:"testSuite"`
-	Name            string     `xml:"name"`
-	TestStrategy    string     `xml:"testStrategy"`
-	TestCases       []TestCase `xml:"testCases>testCase"`
+	XMLName         xml.Name     `xml:"testSuite"`
+	Name            string       `xml:"name"`
+	TestStrategy    string       `xml:"testStrategy"`
+	TestCases       []TestCase   `xml:"testCases>testCase"`
+	Retry           *RetryPolicy `xml:"retry"`
+	Feeders         []*Feeder    `xml:"feeders>feeder"`
+	Setup           SuiteSteps   `xml:"setup"`
+	Teardown        SuiteSteps   `xml:"teardown"`
+	UserSetup       SuiteSteps   `xml:"userSetup"`
+	UserTeardown    SuiteSteps   `xml:"userTeardown"`
+	Steps           SuiteSteps   `xml:"-"`
+	Workloads       []*Workload  `xml:"-"`
 	TestDefinitions []*TestDefinition
+
+	// SetupValues are the values extracted by the setup of the suite, once
+	// SetupDone.
+	SetupValues map[string]interface{} `xml:"-"`
+	SetupDone   bool                   `xml:"-"`
+
+	// workloadStats is set while the suite runs as part of a mixed workload.
+	workloadStats *perfTestUtils.WorkloadStats
+	// users holds the setup state of the virtual users that ran.
+	users map[int]*userPhase
 }
 
 // TestCase is used to encapsulate and marshal a <testCase> tag from the
@@ -83,8 +238,8 @@ type TestSuite struct {
 type TestCase struct {
 	XMLName       xml.Name `xml:"testCase"`
 	Name          string   `xml:",chardata"`
-	PreThinkTime  int64    `xml:"preThinkTime,attr"`
-	PostThinkTime int64    `xml:"postThinkTime,attr"`
+	PreThinkTime  string   `xml:"preThinkTime,attr"`
+	PostThinkTime string   `xml:"postThinkTime,attr"`
 	ExecWeight    string   `xml:"execWeight,attr"`
 }
 
@@ -99,12 +254,17 @@ type multipartFormField struct {
 // accordingly. For ServiceBasedTesting, the test suite is built from test
 // cases in the TestCaseDir, unordered. For SuiteBasedTesting, the test suite
 // is built according to the testCases listed in the test suite definition.
+// When configurationSettings.Workloads is set, a suite is built for every
+// workload instead (see buildWorkloads).
 func (ts *TestSuite) BuildTestSuite(configurationSettings *perfTestUtils.Config) {
 	log.Info("Building Test Suite ....")
 	// Default to ServiceBased testing:
 	ts.TestStrategy = ServiceBasedTesting
 
-	if configurationSettings.TestSuite == "" {
+	if len(configurationSettings.Workloads) > 0 {
+		// A mixed workload runs several suites at the same time.
+		ts.buildWorkloads(configurationSettings)
+	} else if configurationSettings.TestSuite == "" {
 		ts.Name = "DefaultSuite"
 
 		// If no test suite has been defined, treat and all test case files
@@ -138,6 +298,14 @@ func (ts *TestSuite) BuildTestSuite(configurationSettings *perfTestUtils.Config)
 				log.Error("Failed to load test definition. Error:", err)
 				os.Exit(1)
 			}
+			if err := testDefinition.Assertions.loadSchemas(configurationSettings.TestCaseDir); err != nil {
+				log.Errorf("Failed to load test definition [%s]. Error: %v", fi.Name(), err)
+				os.Exit(1)
+			}
+			if testDefinition.WebSocket != nil {
+				log.Warnf("Skipping WebSocket test case [%s]: WebSocket steps run in suite based tests only.", testDefinition.TestName)
+				continue
+			}
 			ts.TestDefinitions = append(ts.TestDefinitions, testDefinition)
 		}
 	} else {
@@ -158,29 +326,33 @@ func (ts *TestSuite) BuildTestSuite(configurationSettings *perfTestUtils.Config)
 			os.Exit(1)
 		}
 
-		// Populate ts.TestDefinitions array with test definitions.
-		for _, testCase := range ts.TestCases {
-			bs, err := ioutil.ReadFile(configurationSettings.TestCaseDir + "/" + testCase.Name)
+		// Read the rows of the feeders, relative to the suite definition.
+		err = ts.loadFeeders(configurationSettings.TestSuiteDir)
+		if err != nil {
+			log.Errorf("Failed to load the feeders of the test suite: %v", err)
+			os.Exit(1)
+		}
+
+		// Populate ts.TestDefinitions array with test definitions, in the
+		// order of the suite steps.
+		loadFile := func(name string) (*TestDefinition, error) {
+			bs, err := ioutil.ReadFile(configurationSettings.TestCaseDir + "/" + name)
 			if err != nil {
-				log.Error("Failed to read test file. Filename: ", testCase.Name, err)
-				continue
+				log.Error("Failed to read test file. Filename: ", name, err)
+				return nil, err
 			}
 
 			testDefinition, err := loadTestDefinition(bs)
+			if err == nil {
+				err = testDefinition.Assertions.loadSchemas(configurationSettings.TestCaseDir)
+			}
 			if err != nil {
 				log.Error("Failed to load test definition. Error:", err)
 			}
-
-			// Add the testCase attributes to the TestDefinition (thinktime, etc).
-			// This effectively flattens the fields into TestDefinitions allowing
-			// us to ignore TestCases.
-			testDefinition.PreThinkTime = testCase.PreThinkTime
-			testDefinition.PostThinkTime = testCase.PostThinkTime
-			testDefinition.ExecWeight = testCase.ExecWeight
-
-			// Append the testDefinition to the testSuite
-			ts.TestDefinitions = append(ts.TestDefinitions, testDefinition)
+			return testDefinition, err
 		}
+		ts.resolveTestDefinitions(ts.Steps, loadFile)
+		ts.resolvePhaseDefinitions(loadFile)
 	}
 }
 
@@ -191,6 +363,14 @@ func loadTestDefinition(bs []byte) (*TestDefinition, error) {
 		log.Errorf("Error occurred loading XML testCase definition file: %v\n", err)
 		return nil, err
 	}
+	err = td.validatePlaceholders()
+	if err == nil {
+		err = td.Assertions.validate()
+	}
+	if err != nil {
+		log.Errorf("Error occurred loading XML testCase definition file: %v\n", err)
+		return nil, err
+	}
 	return td, nil
 }
 
@@ -201,51 +381,97 @@ func (ts *TestSuite) loadTestSuiteDefinition(bs []byte) error {
 		log.Errorf("Error occurred loading XML testSuite definition file: %v\n", err)
 		return err
 	}
+
+	// TestCases only holds the top level <testCase> elements. Decode the
+	// <testCases> block a second time to get every step, including
+	// <choice> elements, in document order.
+	layout := struct {
+		Steps SuiteSteps `xml:"testCases"`
+	}{}
+	err = xml.Unmarshal(bs, &layout)
+	if err != nil {
+		log.Errorf("Error occurred loading XML testSuite definition file: %v\n", err)
+		return err
+	}
+	ts.Steps = layout.Steps
+
+	err = ts.validateFeeders()
+	if err != nil {
+		log.Errorf("Error occurred loading XML testSuite definition file: %v\n", err)
+		return err
+	}
 	return nil
 }
 
 // BuildAndSendRequest builds a request from the test definition, performs
-// variable substitutions, sends the request, and returns the response time
-// of the call, or 0 if failure. Note: Response time does not include
-// RequestDelay or ThinkTime.
+// variable substitutions, adds the credentials of its auth provider, sends
+// the request with the given client, retrying according to its retry
+// policy, and returns the response time of the call, or 0 and the reason if
+// failure.
+// Note: Response time does not include RequestDelay or ThinkTime, which are
+// recorded as think time.
 func (testDefinition *TestDefinition) BuildAndSendRequest(
-	delay int,
+	client *http.Client,
+	configurationSettings *perfTestUtils.Config,
+	perfStatsForTest *perfTestUtils.PerfStats,
+	targetScheme string,
 	targetHost string,
 	targetPort string,
 	uniqueTestRunID string,
-) int64 {
+) (int64, error) {
 	log.Debugf("BEGIN \"%s\" testDefinition\n-----\n%+v\n-----\nEND \"%s\" testDefinition\n",
 		testDefinition.TestName,
 		testDefinition,
 		testDefinition.TestName,
 	)
 
-	randomDelay := rand.Intn(delay)
-	time.Sleep(time.Duration(randomDelay) * time.Millisecond)
+	// The think time before and after the request is recorded separately
+	// from the response time, even if the request fails.
+	var thinkTime time.Duration
+	defer func() {
+		recordThinkTime(perfStatsForTest, testDefinition.TestName, thinkTime)
+	}()
 
-	//Execute the PreThinkTime, if any.
-	if testDefinition.PreThinkTime > 0 {
-		tt := float64(testDefinition.PreThinkTime) / 1000
-		log.Infof("Think time: [%.2f] seconds.", tt)
-	}
-	time.Sleep(time.Duration(testDefinition.PreThinkTime) * time.Millisecond)
+	//Execute the PreThinkTime, or the default think time.
+	thinkTime += pause(testDefinition.preRequestThinkTime(configurationSettings))
 
 	var req *http.Request
+	// The request body is kept to send it again on retries.
+	var payload []byte
 	reqbody := "N/A" //for debug
 
 	//Retrieve requestBaseURI and perform any necessary substitution
 	requestBaseURI := substituteRequestValues(&testDefinition.BaseURI, uniqueTestRunID)
-
-	if !testDefinition.Multipart {
+	requestURL := targetScheme + "://" + targetHost + ":" + targetPort + requestBaseURI
+
+	if testDefinition.GraphQL != nil {
+		log.Debug("Building GraphQL request.")
+		operation := testDefinition.GraphQL.operation()
+		recordOperation(perfStatsForTest, testDefinition.TestName, operation)
+		var err error
+		payload, err = testDefinition.GraphQL.payload(uniqueTestRunID)
+		if err != nil {
+			log.Errorf("Failed to build GraphQL request [Name:%s Operation:%s]: %v", testDefinition.TestName, operation, err)
+			return 0, err
+		}
+		reqbody = string(payload)
+		httpMethod := testDefinition.HTTPMethod
+		if httpMethod == "" {
+			httpMethod = "POST"
+		}
+		req, _ = http.NewRequest(httpMethod, requestURL, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+	} else if !testDefinition.Multipart {
 		log.Debug("Building non-Multipart request.")
 		if testDefinition.Payload != "" {
 			//Retrieve Payload and perform any necessary substitution
-			payload := testDefinition.Payload
-			newPayload := substituteRequestValues(&payload, uniqueTestRunID)
+			templatePayload := testDefinition.Payload
+			newPayload := substituteRequestValues(&templatePayload, uniqueTestRunID)
 			reqbody = newPayload
-			req, _ = http.NewRequest(testDefinition.HTTPMethod, "http://"+targetHost+":"+targetPort+requestBaseURI, strings.NewReader(newPayload))
+			payload = []byte(newPayload)
+			req, _ = http.NewRequest(testDefinition.HTTPMethod, requestURL, bytes.NewReader(payload))
 		} else {
-			req, _ = http.NewRequest(testDefinition.HTTPMethod, "http://"+targetHost+":"+targetPort+requestBaseURI, nil)
+			req, _ = http.NewRequest(testDefinition.HTTPMethod, requestURL, nil)
 		}
 	} else {
 		log.Debug("Building Multipart request.")
@@ -263,7 +489,8 @@ func (testDefinition *TestDefinition) BuildAndSendRequest(
 				}
 			}
 			writer.Close()
-			req, _ = http.NewRequest(testDefinition.HTTPMethod, "http://"+targetHost+":"+targetPort+requestBaseURI, body)
+			payload = body.Bytes()
+			req, _ = http.NewRequest(testDefinition.HTTPMethod, requestURL, bytes.NewReader(payload))
 			req.Header.Set("Content-Type", writer.FormDataContentType())
 
 			// For debug output
@@ -276,6 +503,15 @@ func (testDefinition *TestDefinition) BuildAndSendRequest(
 		req.Header.Add(header.Key, substituteRequestValues(&header.Value, uniqueTestRunID))
 	}
 
+	// Add credentials. Any token is fetched before the timer starts, so it
+	// does not count towards the response time.
+	if testDefinition.Auth != "" {
+		if err := authorizeRequest(configurationSettings, testDefinition.Auth, req, payload); err != nil {
+			log.Errorf("Authentication failed for request [Name:%s]: %v", testDefinition.TestName, err)
+			return 0, err
+		}
+	}
+
 	log.Debugf(
 		"BEGIN \"%s\" Request:\n-----\nHEADER:%+v\nURL:%s\nREQ_BODY:%s\n-----\nEND [%s] Request",
 		testDefinition.TestName,
@@ -285,18 +521,12 @@ func (testDefinition *TestDefinition) BuildAndSendRequest(
 		testDefinition.TestName,
 	)
 
-	var resp *http.Response
-	var err error
-	startTime := time.Now()
-	if resp, err = (&http.Client{}).Do(req); err != nil {
-		log.Errorf("Connection failed for request [Name:%s]: %+v", testDefinition.TestName, err)
-		return 0
+	// Send the request, retrying transient failures according to the retry
+	// policy of the test definition.
+	resp, body, timeTaken, err := testDefinition.sendWithRetries(client, req, payload, perfStatsForTest)
+	if err != nil {
+		return 0, err
 	}
-	// Mark response time.
-	timeTaken := time.Since(startTime)
-	// Gather the response.
-	body, _ := ioutil.ReadAll(resp.Body)
-	defer resp.Body.Close()
 
 	log.Debugf(
 		"BEGIN \"%s\" Response:\n-----\nSTATUSCODE:%d\nHEADER:%+v\nRESP_BODY:%s\n-----\nEND [%s] Response",
@@ -311,21 +541,72 @@ func (testDefinition *TestDefinition) BuildAndSendRequest(
 	responseCodeOk := perfTestUtils.ValidateResponseStatusCode(resp.StatusCode, testDefinition.ResponseStatusCode, testDefinition.TestName)
 	responseTimeOK := perfTestUtils.ValidateServiceResponseTime(timeTaken.Nanoseconds(), testDefinition.TestName)
 
-	if !responseCodeOk || !responseTimeOK {
-		return 0
+	if !responseCodeOk {
+		return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if testDefinition.GraphQL != nil {
+		if err := checkGraphQLErrors(body); err != nil {
+			log.Errorf("GraphQL request failed [Name:%s]: %v", testDefinition.TestName, err)
+			return 0, err
+		}
+	}
+	if !responseTimeOK {
+		return 0, fmt.Errorf("invalid response time %v", timeTaken)
+	}
+	if err := checkExpectedCookies(testDefinition.ExpectedCookies, client.Jar, req, resp); err != nil {
+		log.Errorf("Cookie check failed for request [Name:%s]: %v", testDefinition.TestName, err)
+		return 0, err
+	}
+	if failures := testDefinition.Assertions.check(resp, body); len(failures) > 0 {
+		// Every failed assertion is counted, the request fails once.
+		for _, failure := range failures {
+			log.Errorf("Assertion [%s] failed for request [Name:%s]: %v", failure.name, testDefinition.TestName, failure.err)
+			recordAssertionFailure(perfStatsForTest, testDefinition.TestName, failure.name)
+		}
+		return 0, fmt.Errorf("assertion [%s] failed: %v", failures[0].name, failures[0].err)
 	}
 
 	contentType := detectContentType(resp.Header, body, testDefinition.ResponseContentType)
 	extractResponseValues(testDefinition.TestName, body, testDefinition.ResponseValues, uniqueTestRunID, contentType)
 
 	//Execute the PostThinkTime, if any.
-	if testDefinition.PostThinkTime > 0 {
-		tt := float64(testDefinition.PostThinkTime) / 1000
-		log.Infof("Think time: [%.2f] seconds.", tt)
+	if !testDefinition.PostThinkTime.IsZero() {
+		thinkTime += pause(testDefinition.PostThinkTime.Sample())
 	}
-	time.Sleep(time.Duration(testDefinition.PostThinkTime) * time.Millisecond)
 
-	return timeTaken.Nanoseconds()
+	return timeTaken.Nanoseconds(), nil
+}
+
+// checkExpectedCookies returns an error for the first expected cookie that
+// the response did not set, or that is not in the cookie jar for the request
+// URL. Without a jar, only the cookies set by the response are present.
+func checkExpectedCookies(expected ExpectedCookies, jar http.CookieJar, req *http.Request, resp *http.Response) error {
+	setCookies := make(map[string]bool)
+	for _, cookie := range resp.Cookies() {
+		setCookies[cookie.Name] = true
+	}
+	for _, name := range expected.Set {
+		if !setCookies[strings.TrimSpace(name)] {
+			return fmt.Errorf("expected cookie [%s] was not set", strings.TrimSpace(name))
+		}
+	}
+
+	if len(expected.Present) == 0 {
+		return nil
+	}
+	presentCookies := setCookies
+	if jar != nil {
+		presentCookies = make(map[string]bool)
+		for _, cookie := range jar.Cookies(req.URL) {
+			presentCookies[cookie.Name] = true
+		}
+	}
+	for _, name := range expected.Present {
+		if !presentCookies[strings.TrimSpace(name)] {
+			return fmt.Errorf("expected cookie [%s] is not present", strings.TrimSpace(name))
+		}
+	}
+	return nil
 }
 
 func detectContentType(respHeaders http.Header, respBody []byte, respContentType string) string {
@@ -338,23 +619,31 @@ func detectContentType(respHeaders http.Header, respBody []byte, respContentType
 	}
 }
 
-func determineHostandPortforRequest(testDefinition *TestDefinition, configurationSettings *perfTestUtils.Config) (string, string) {
+func determineTargetForRequest(testDefinition *TestDefinition, configurationSettings *perfTestUtils.Config) (string, string, string) {
 
+	var targetScheme = configurationSettings.TargetScheme
 	var targetHost = configurationSettings.TargetHost
 	var targetPort = configurationSettings.TargetPort
 
+	if testDefinition.OverrideScheme != "" {
+		targetScheme = strings.ToLower(testDefinition.OverrideScheme)
+	}
 	if testDefinition.OverrideHost != "" {
 		targetHost = testDefinition.OverrideHost
 	}
 	if testDefinition.OverridePort != "" {
 		targetPort = testDefinition.OverridePort
 	}
-	return targetHost, targetPort
+	if targetScheme == "" {
+		targetScheme = "http"
+	}
+	return targetScheme, targetHost, targetPort
 }
 
 func substituteRequestValues(requestBody *string, uniqueTestRunID string) string {
-	// Make a value copy for substitution.
-	requestPayloadCopy := *requestBody
+	// Make a value copy for substitution, with the built-in values such as
+	// {{$uuid}} generated first.
+	requestPayloadCopy := substituteBuiltinValues(*requestBody, uniqueTestRunID)
 
 	// Lock global data structure for the duration of this function and
 	// those function that branch.
@@ -373,6 +662,9 @@ func substituteRequestValues(requestBody *string, uniqueTestRunID string) string
 				//remove placeholder syntax
 				cleanedPropertyName := strings.TrimPrefix(propertyPlaceHolder, "{{")
 				cleanedPropertyName = strings.TrimSuffix(cleanedPropertyName, "}}")
+				if strings.HasPrefix(cleanedPropertyName, "$") {
+					continue
+				}
 
 				propertyPlaceHolderName := cleanedPropertyName
 				propertyPlaceHolderIndex := 0
@@ -5,7 +5,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/xtracdev/automated-perf-test/perfTestUtils"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -152,10 +154,10 @@ func TestLoadTestSuiteDefinitionXml(t *testing.T) {
 	assert.Equal(t, "xiws-loginLTPA-success.xml", ts.TestCases[0].Name)
 	assert.Equal(t, "xiws-workitem-create-success.xml", ts.TestCases[1].Name)
 	assert.Equal(t, "xiws-workitem-search-success.xml", ts.TestCases[2].Name)
-	assert.Equal(t, int64(10), ts.TestCases[0].PreThinkTime)
-	assert.Equal(t, int64(20), ts.TestCases[0].PostThinkTime)
+	assert.Equal(t, "10", ts.TestCases[0].PreThinkTime)
+	assert.Equal(t, "20", ts.TestCases[0].PostThinkTime)
 	assert.Equal(t, "Infrequent", ts.TestCases[0].ExecWeight)
-	assert.Equal(t, int64(0), ts.TestCases[1].PreThinkTime)
+	assert.Equal(t, "", ts.TestCases[1].PreThinkTime)
 }
 
 func TestLoadTestSuiteDefinitionXmlErr(t *testing.T) {
@@ -164,4 +166,54 @@ func TestLoadTestSuiteDefinitionXmlErr(t *testing.T) {
 	ts := new(TestSuite)
 	err := ts.loadTestSuiteDefinition([]byte(`This is not XML.`))
 	assert.NotNil(t, err)
-}
\ No newline at end of file
+}
+
+func TestRunLimit(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.NumIterations = 3
+	now := time.Now()
+
+	limit := newRunLimit(config, now)
+	assert.True(t, limit.allows(2, now.Add(time.Hour)))
+	assert.False(t, limit.allows(3, now))
+
+	config.Duration = "1m"
+	limit = newRunLimit(config, now)
+	assert.True(t, limit.allows(1000, now.Add(59*time.Second)))
+	assert.False(t, limit.allows(0, now.Add(time.Minute)))
+}
+
+func TestStopTestRun(t *testing.T) {
+	defer resetTestRun()
+	limit := runLimit{iterations: 10}
+	assert.Equal(t, "", TestRunStopReason())
+	assert.True(t, limit.allows(0, time.Now()))
+
+	StopTestRun("interrupted", time.Hour)
+	StopTestRun("ignored", 0)
+
+	assert.Equal(t, "interrupted", TestRunStopReason())
+	assert.False(t, limit.allows(0, time.Now()))
+}
+
+func TestWaitForUsersGracePeriod(t *testing.T) {
+	defer resetTestRun()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		wg.Done()
+	}()
+	assert.True(t, waitForUsers(&wg))
+
+	// A user that never finishes is given up on once the grace period of a
+	// stopped run expires.
+	wg.Add(1)
+	defer wg.Done()
+	StopTestRun("interrupted", 50*time.Millisecond)
+	start := time.Now()
+	assert.False(t, waitForUsers(&wg))
+	assert.InDelta(t, 50, time.Since(start).Seconds()*1000, 40)
+}
@@ -0,0 +1,432 @@
+package testStrategies
+
+import (
+	"encoding/xml"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of the control flow steps of a suite, as counted in the report.
+const (
+	StepTypeRepeat = "repeat"
+	StepTypeWhile  = "while"
+	StepTypeUntil  = "until"
+	StepTypeIf     = "if"
+)
+
+// defaultLoopTimeout bounds a <while> or <until> loop without a timeout.
+const defaultLoopTimeout = time.Minute
+
+// conditionOperators are the comparison operators of a condition. The
+// operator must have a space on either side.
+var conditionOperators = []string{"==", "!=", "<=", ">=", "=~", "!~", "<", ">"}
+
+// unresolvedPlaceholder matches the {{...}} placeholders of a condition
+// operand.
+var unresolvedPlaceholder = regexp.MustCompile("{{[^}]+}}")
+
+// Repeat is a <repeat> element. Its steps run a number of times in a row,
+// picked at random between Min and Max, inclusive, each time it is reached.
+type Repeat struct {
+	Name  string
+	Min   int
+	Max   int
+	Steps SuiteSteps
+}
+
+// Loop is a <while> or an <until> element. A while loop runs its steps for as
+// long as its condition holds, checked before every pass. An until loop runs
+// its steps until its condition holds, checked after every pass, so it runs
+// at least once. Passes are Interval apart. A loop that is still going
+// after Timeout ends there.
+type Loop struct {
+	Name      string
+	Until     bool
+	Condition Condition
+	Timeout   string
+	Interval  string
+	Steps     SuiteSteps
+}
+
+// Conditional is an <if> element. Its steps run if its condition holds, the
+// steps of its <else> element otherwise.
+type Conditional struct {
+	Name      string
+	Condition Condition
+	Steps     SuiteSteps
+	Else      SuiteSteps
+}
+
+// Condition compares two operands with an operator, eg. "{{getOrder.status}}
+// == PENDING". Operands are compared as numbers if both are numbers, as
+// strings otherwise; "=~" and "!~" match the left operand against the
+// regular expression on the right. A condition without an operator holds if
+// its single operand is not empty, "false", "0" or "null". The {{...}}
+// placeholders of values that were not extracted are empty.
+type Condition struct {
+	Left     string
+	Operator string
+	Right    string
+}
+
+// parseCondition parses the condition attribute of a loop or if.
+func parseCondition(condition string) (Condition, error) {
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		return Condition{}, fmt.Errorf("missing condition")
+	}
+
+	parsed := Condition{Left: condition}
+	position := -1
+	for _, operator := range conditionOperators {
+		i := strings.Index(condition, " "+operator+" ")
+		if i >= 0 && (position < 0 || i < position) {
+			position = i
+			parsed = Condition{
+				Left:     strings.TrimSpace(condition[:i]),
+				Operator: operator,
+				Right:    unquote(strings.TrimSpace(condition[i+len(operator)+2:])),
+			}
+		}
+	}
+	if parsed.Operator == "=~" || parsed.Operator == "!~" {
+		if _, err := regexp.Compile(parsed.Right); err != nil && !strings.Contains(parsed.Right, "{{") {
+			return Condition{}, fmt.Errorf("invalid regular expression in condition [%s]: %v", condition, err)
+		}
+	}
+	return parsed, nil
+}
+
+// unquote removes the single or double quotes around an operand, if any.
+func unquote(operand string) string {
+	if len(operand) >= 2 && (operand[0] == '\'' || operand[0] == '"') && operand[len(operand)-1] == operand[0] {
+		return operand[1 : len(operand)-1]
+	}
+	return operand
+}
+
+// String returns the condition as written.
+func (c Condition) String() string {
+	if c.Operator == "" {
+		return c.Left
+	}
+	return c.Left + " " + c.Operator + " " + c.Right
+}
+
+// holds evaluates the condition with the values of the given iteration.
+func (c Condition) holds(uniqueTestRunID string) bool {
+	left := resolveOperand(c.Left, uniqueTestRunID)
+	if c.Operator == "" {
+		switch strings.ToLower(left) {
+		case "", "false", "0", "null":
+			return false
+		}
+		return true
+	}
+	right := resolveOperand(c.Right, uniqueTestRunID)
+
+	if c.Operator == "=~" || c.Operator == "!~" {
+		re, err := regexp.Compile(right)
+		if err != nil {
+			log.Warnf("Invalid regular expression in condition [%s]: %v", c, err)
+			return false
+		}
+		return re.MatchString(left) == (c.Operator == "=~")
+	}
+
+	comparison := strings.Compare(left, right)
+	leftNumber, leftErr := strconv.ParseFloat(left, 64)
+	rightNumber, rightErr := strconv.ParseFloat(right, 64)
+	if leftErr == nil && rightErr == nil {
+		switch {
+		case leftNumber < rightNumber:
+			comparison = -1
+		case leftNumber > rightNumber:
+			comparison = 1
+		default:
+			comparison = 0
+		}
+	}
+	switch c.Operator {
+	case "==":
+		return comparison == 0
+	case "!=":
+		return comparison != 0
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	}
+	return comparison >= 0
+}
+
+// resolveOperand substitutes the values of the iteration into an operand.
+// Placeholders of values that are not set, or null, are removed first, as
+// they would otherwise read "<nil>". Built-in values are always set.
+func resolveOperand(operand string, uniqueTestRunID string) string {
+	mu.Lock()
+	testRunGlobals := globalsMap[uniqueTestRunID]
+	operand = unresolvedPlaceholder.ReplaceAllStringFunc(operand, func(placeholder string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(placeholder, "{{"), "}}")
+		if i := strings.Index(name, "["); i >= 0 {
+			name = name[:i]
+		}
+		if testRunGlobals[name] == nil && !strings.HasPrefix(name, "$") {
+			return ""
+		}
+		return placeholder
+	})
+	mu.Unlock()
+
+	resolved := substituteRequestValues(&operand, uniqueTestRunID)
+	return strings.TrimSpace(unresolvedPlaceholder.ReplaceAllString(resolved, ""))
+}
+
+// UnmarshalXML decodes the name and count attributes of a <repeat>, and its
+// child elements as suite steps. The count is a number, or a range such as
+// "1-5".
+func (r *Repeat) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	count := attrValue(start, "count")
+	min, max, err := parseRepeatCount(count)
+	if err != nil {
+		return err
+	}
+	r.Min, r.Max = min, max
+	r.Name = stepName(start, StepTypeRepeat+" "+strings.TrimSpace(count))
+	return r.Steps.UnmarshalXML(d, start)
+}
+
+// parseRepeatCount returns the lowest and highest count of a repeat.
+func parseRepeatCount(count string) (int, int, error) {
+	bounds := strings.SplitN(strings.TrimSpace(count), "-", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	max := min
+	if err == nil && len(bounds) == 2 {
+		max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+	}
+	if err != nil || min < 0 || max < min {
+		return 0, 0, fmt.Errorf("invalid repeat count [%s]", count)
+	}
+	return min, max, nil
+}
+
+// count returns the number of times to run the steps this time.
+func (r *Repeat) count() int {
+	return r.Min + rand.Intn(r.Max-r.Min+1)
+}
+
+// UnmarshalXML decodes the attributes of a <while> or <until>, and its child
+// elements as suite steps.
+func (l *Loop) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	l.Until = start.Name.Local == StepTypeUntil
+	condition, err := parseCondition(attrValue(start, "condition"))
+	if err != nil {
+		return fmt.Errorf("<%s>: %v", start.Name.Local, err)
+	}
+	l.Condition = condition
+	l.Name = stepName(start, start.Name.Local+" "+condition.String())
+	l.Timeout = attrValue(start, "timeout")
+	l.Interval = attrValue(start, "interval")
+	for _, duration := range []string{l.Timeout, l.Interval} {
+		if parsed, err := time.ParseDuration(duration); duration != "" && (err != nil || parsed < 0) {
+			return fmt.Errorf("<%s>: invalid duration [%s]", start.Name.Local, duration)
+		}
+	}
+	return l.Steps.UnmarshalXML(d, start)
+}
+
+// timeout returns how long the loop may go on for.
+func (l *Loop) timeout() time.Duration {
+	d, err := time.ParseDuration(l.Timeout)
+	if err != nil || d <= 0 {
+		return defaultLoopTimeout
+	}
+	return d
+}
+
+// interval returns the wait between passes.
+func (l *Loop) interval() time.Duration {
+	d, err := time.ParseDuration(l.Interval)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// UnmarshalXML decodes the attributes of an <if>, its <else> element, if
+// any, and its other child elements as suite steps.
+func (c *Conditional) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	condition, err := parseCondition(attrValue(start, "condition"))
+	if err != nil {
+		return fmt.Errorf("<if>: %v", err)
+	}
+	c.Condition = condition
+	c.Name = stepName(start, StepTypeIf+" "+condition.String())
+
+	hasElse := false
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "else" {
+				if hasElse {
+					return fmt.Errorf("more than one <else> in <if>")
+				}
+				hasElse = true
+				if err := c.Else.UnmarshalXML(d, t); err != nil {
+					return err
+				}
+				continue
+			}
+			step, err := decodeSuiteStep(d, t, start)
+			if err != nil {
+				return err
+			}
+			c.Steps = append(c.Steps, step)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// attrValue returns the value of the named attribute of an element.
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// stepName returns the name attribute of a control flow step, or the given
+// default, a description of the step.
+func stepName(start xml.StartElement, defaultName string) string {
+	if name := strings.TrimSpace(attrValue(start, "name")); name != "" {
+		return name
+	}
+	return defaultName
+}
+
+// executeRepeat runs the steps of a repeat.
+func (si *suiteIteration) executeRepeat(repeat *Repeat) {
+	count := repeat.count()
+	passes := 0
+	for ; passes < count && !isTestRunStopped(); passes++ {
+		si.executeSteps(repeat.Steps)
+	}
+	recordStep(si.perfStatsForTest, repeat.Name, StepTypeRepeat, passes, false)
+}
+
+// executeLoop runs the steps of a while or until loop until its condition
+// ends it, it times out, or the test run stops.
+func (si *suiteIteration) executeLoop(loop *Loop) {
+	stepType := StepTypeWhile
+	if loop.Until {
+		stepType = StepTypeUntil
+	}
+	deadline := time.Now().Add(loop.timeout())
+	passes := 0
+	timedOut := false
+	testNames := make(map[string]bool)
+	addStepTestNames(testNames, loop.Steps)
+	if loop.Until || loop.Condition.holds(si.uniqueTestRunID) {
+		for {
+			si.clearExtractedValues(testNames)
+			si.executeSteps(loop.Steps)
+			passes++
+			if loop.Condition.holds(si.uniqueTestRunID) == loop.Until || isTestRunStopped() {
+				break
+			}
+			if !time.Now().Add(loop.interval()).Before(deadline) {
+				log.Warnf("Loop [%s] timed out after %d passes. UniqueRunID: [%s]", loop.Name, passes, si.uniqueTestRunID)
+				timedOut = true
+				break
+			}
+			time.Sleep(loop.interval())
+		}
+	}
+	recordStep(si.perfStatsForTest, loop.Name, stepType, passes, timedOut)
+}
+
+// clearExtractedValues forgets the values extracted by the named test cases,
+// so that the next pass of a loop extracts them again. Values are otherwise
+// extracted once per iteration, and a loop polling for a change would never
+// see it.
+func (si *suiteIteration) clearExtractedValues(testNames map[string]bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	testRunGlobals := globalsMap[si.uniqueTestRunID]
+	for key := range testRunGlobals {
+		if i := strings.Index(key, "."); i > 0 && testNames[key[:i]] {
+			delete(testRunGlobals, key)
+		}
+	}
+}
+
+// addStepTestNames adds the names of the test cases of the steps, and of
+// the steps nested in them, to names.
+func addStepTestNames(names map[string]bool, steps SuiteSteps) {
+	for _, step := range steps {
+		switch {
+		case step.TestDefinition != nil:
+			names[step.TestDefinition.TestName] = true
+		case step.Choice != nil:
+			for i := range step.Choice.Branches {
+				addStepTestNames(names, step.Choice.Branches[i].Steps)
+			}
+		case step.Repeat != nil:
+			addStepTestNames(names, step.Repeat.Steps)
+		case step.Loop != nil:
+			addStepTestNames(names, step.Loop.Steps)
+		case step.If != nil:
+			addStepTestNames(names, step.If.Steps)
+			addStepTestNames(names, step.If.Else)
+		case step.Transaction != nil:
+			addStepTestNames(names, step.Transaction.Steps)
+		}
+	}
+}
+
+// executeConditional runs the steps of an if, or of its else.
+func (si *suiteIteration) executeConditional(conditional *Conditional) {
+	if conditional.Condition.holds(si.uniqueTestRunID) {
+		recordStep(si.perfStatsForTest, conditional.Name, StepTypeIf, 1, false)
+		si.executeSteps(conditional.Steps)
+		return
+	}
+	recordStep(si.perfStatsForTest, conditional.Name, StepTypeIf, 0, false)
+	si.executeSteps(conditional.Else)
+}
+
+// recordStep counts an execution of a control flow step with the given
+// number of passes.
+func recordStep(perfStatsForTest *perfTestUtils.PerfStats, name string, stepType string, passes int, timedOut bool) {
+	mu.Lock()
+	if perfStatsForTest.StepStats == nil {
+		perfStatsForTest.StepStats = make(map[string]*perfTestUtils.StepStats)
+	}
+	stepStats := perfStatsForTest.StepStats[name]
+	if stepStats == nil {
+		stepStats = &perfTestUtils.StepStats{Type: stepType}
+		perfStatsForTest.StepStats[name] = stepStats
+	}
+	mu.Unlock()
+
+	executed := &perfTestUtils.StepStats{Count: 1, Passes: uint64(passes)}
+	if timedOut {
+		executed.Timeouts = 1
+	}
+	stepStats.Add(executed)
+}
@@ -0,0 +1,309 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const xmlControlFlowTestSuite = `<testSuite>
+    <name>controlFlowSuite</name>
+    <testStrategy>SuiteBased</testStrategy>
+    <testCases>
+        <testCase>createOrder.xml</testCase>
+        <repeat count="1-3">
+            <testCase>addItem.xml</testCase>
+        </repeat>
+        <until name="waitForOrder" condition="{{getOrder.status}} == 'DONE'" timeout="2s" interval="10ms">
+            <testCase>getOrder.xml</testCase>
+        </until>
+        <if condition="{{getOrder.items}} >= 2">
+            <testCase>checkout.xml</testCase>
+            <else>
+                <while condition="{{getOrder.items}} &lt; 2" timeout="1s">
+                    <testCase>addItem.xml</testCase>
+                </while>
+            </else>
+        </if>
+    </testCases>
+</testSuite>`
+
+func TestLoadControlFlowSteps(t *testing.T) {
+	ts := new(TestSuite)
+	err := ts.loadTestSuiteDefinition([]byte(xmlControlFlowTestSuite))
+	assert.Nil(t, err)
+	ts.resolveTestDefinitions(ts.Steps, func(name string) (*TestDefinition, error) {
+		return &TestDefinition{TestName: name}, nil
+	})
+
+	assert.Equal(t, 4, len(ts.Steps))
+	repeat := ts.Steps[1].Repeat
+	assert.Equal(t, &Repeat{Name: "repeat 1-3", Min: 1, Max: 3, Steps: repeat.Steps}, repeat)
+	assert.Equal(t, "addItem.xml", repeat.Steps[0].TestCase.Name)
+
+	loop := ts.Steps[2].Loop
+	assert.Equal(t, "waitForOrder", loop.Name)
+	assert.True(t, loop.Until)
+	assert.Equal(t, Condition{Left: "{{getOrder.status}}", Operator: "==", Right: "DONE"}, loop.Condition)
+	assert.Equal(t, 2*time.Second, loop.timeout())
+	assert.Equal(t, 10*time.Millisecond, loop.interval())
+
+	conditional := ts.Steps[3].If
+	assert.Equal(t, "if {{getOrder.items}} >= 2", conditional.Name)
+	assert.Equal(t, "checkout.xml", conditional.Steps[0].TestCase.Name)
+	assert.Equal(t, "while {{getOrder.items}} < 2", conditional.Else[0].Loop.Name)
+	assert.False(t, conditional.Else[0].Loop.Until)
+	assert.Equal(t, defaultLoopTimeout, (&Loop{}).timeout())
+
+	// Test definitions are resolved in document order, else included.
+	names := make([]string, 0)
+	for _, td := range ts.TestDefinitions {
+		names = append(names, td.TestName)
+	}
+	assert.Equal(t, []string{"createOrder.xml", "addItem.xml", "getOrder.xml", "checkout.xml", "addItem.xml"}, names)
+
+	// Repeats count towards the configured mix, loops and ifs do not.
+	mix := ts.ConfiguredMix()
+	assert.Equal(t, map[string]float64{"createOrder.xml": 1, "addItem.xml": 2}, mix)
+
+	// The steps survive the JSON encoding that sends suites to agents.
+	encoded, err := json.Marshal(ts.Steps)
+	assert.Nil(t, err)
+	var decoded SuiteSteps
+	assert.Nil(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, loop.Condition, decoded[2].Loop.Condition)
+	assert.Equal(t, 3, decoded[1].Repeat.Max)
+	assert.Equal(t, "addItem.xml", decoded[3].If.Else[0].Loop.Steps[0].TestDefinition.TestName)
+}
+
+func TestLoadControlFlowStepsErr(t *testing.T) {
+	for _, testCases := range []string{
+		`<repeat count="x"/>`,
+		`<repeat count="3-1"/>`,
+		`<repeat/>`,
+		`<while timeout="1s"/>`,
+		`<until condition="{{a.b}}" timeout="soon"/>`,
+		`<if condition="{{a.b}} =~ ("/>`,
+		`<if condition="{{a.b}}"><else/><else/></if>`,
+		`<if condition="{{a.b}}"><loop/></if>`,
+	} {
+		ts := new(TestSuite)
+		err := ts.loadTestSuiteDefinition([]byte(`<testSuite><testCases>` + testCases + `</testCases></testSuite>`))
+		assert.NotNil(t, err, testCases)
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	condition, err := parseCondition(` {{login.token}} `)
+	assert.Nil(t, err)
+	assert.Equal(t, Condition{Left: "{{login.token}}"}, condition)
+
+	condition, err = parseCondition(`{{order.status}} != "IN PROGRESS"`)
+	assert.Nil(t, err)
+	assert.Equal(t, Condition{Left: "{{order.status}}", Operator: "!=", Right: "IN PROGRESS"}, condition)
+
+	// The first operator wins, the others are part of the right operand.
+	condition, err = parseCondition(`{{a.b}} =~ ^x <= y$`)
+	assert.Nil(t, err)
+	assert.Equal(t, Condition{Left: "{{a.b}}", Operator: "=~", Right: "^x <= y$"}, condition)
+
+	_, err = parseCondition(" ")
+	assert.NotNil(t, err)
+}
+
+func TestConditionHolds(t *testing.T) {
+	mu.Lock()
+	globalsMap["cfUser1Iter0"] = map[string]interface{}{
+		"order.status": "PENDING",
+		"order.items":  float64(10),
+		"order.paid":   false,
+	}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		globalsMap["cfUser1Iter0"] = nil
+		mu.Unlock()
+	}()
+
+	for condition, holds := range map[string]bool{
+		"{{order.status}} == PENDING":   true,
+		"{{order.status}} != 'PENDING'": false,
+		"{{order.status}} =~ ^PEND":     true,
+		"{{order.status}} !~ ^PEND":     false,
+		"{{order.items}} > 9":           true,
+		"{{order.items}} <= 9":          false,
+		"{{order.items}} >= 10.0":       true,
+		"{{order.status}} < QUEUED":     true,
+		"{{order.status}}":              true,
+		"{{order.paid}}":                false,
+		"{{order.missing}}":             false,
+		"{{order.missing}} == ''":       true,
+	} {
+		parsed, err := parseCondition(condition)
+		assert.Nil(t, err, condition)
+		assert.Equal(t, holds, parsed.holds("cfUser1Iter0"), condition)
+	}
+}
+
+func TestParseRepeatCount(t *testing.T) {
+	min, max, err := parseRepeatCount(" 4 ")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{4, 4}, []int{min, max})
+	min, max, err = parseRepeatCount("0 - 2")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 2}, []int{min, max})
+	_, _, err = parseRepeatCount("-1")
+	assert.NotNil(t, err)
+
+	repeat := &Repeat{Min: 1, Max: 3}
+	for i := 0; i < 100; i++ {
+		count := repeat.count()
+		assert.True(t, count >= 1 && count <= 3)
+	}
+}
+
+func TestExecuteControlFlowSteps(t *testing.T) {
+	// The order is done after the third poll.
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		status := "PENDING"
+		if atomic.AddInt32(&polls, 1) >= 3 {
+			status = "DONE"
+		}
+		w.Write([]byte(`{"status":"` + status + `","items":` + strconv.Itoa(int(atomic.LoadInt32(&polls))) + `}`))
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	ts := new(TestSuite)
+	assert.Nil(t, ts.loadTestSuiteDefinition([]byte(xmlControlFlowTestSuite)))
+	ts.resolveTestDefinitions(ts.Steps, func(name string) (*TestDefinition, error) {
+		return &TestDefinition{
+			TestName:           name[:len(name)-len(".xml")],
+			HTTPMethod:         "GET",
+			BaseURI:            "/" + name,
+			ResponseStatusCode: 200,
+			ResponseValues: []ResponseValue{
+				{ExtractionKey: "status", Value: "status"},
+				{ExtractionKey: "items", Value: "items"},
+			},
+		}, nil
+	})
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	executeTestSuiteIteration(ts, config, 1, 0, perfStats)
+
+	// Every request polls, so the until loop ends after the getOrder of the
+	// third request at most, and then the if finds enough items.
+	repeat := perfStats.StepStats["repeat 1-3"]
+	assert.Equal(t, perfTestUtils.StepStats{Type: StepTypeRepeat, Count: 1, Passes: repeat.Passes}, *repeat)
+	assert.True(t, repeat.Passes >= 1 && repeat.Passes <= 3)
+	assert.Equal(t, uint64(1), perfStats.StepStats["waitForOrder"].Count)
+	assert.Equal(t, uint64(0), perfStats.StepStats["waitForOrder"].Timeouts)
+	assert.Equal(t, perfTestUtils.StepStats{Type: StepTypeIf, Count: 1, Passes: 1}, *perfStats.StepStats["if {{getOrder.items}} >= 2"])
+	assert.Equal(t, uint64(1), *perfStats.ServiceTransCount["checkout"])
+	assert.Nil(t, perfStats.StepStats["while {{getOrder.items}} < 2"])
+}
+
+func TestExecuteLoopTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"PENDING"}`))
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	poll := &TestDefinition{
+		TestName:           "poll",
+		HTTPMethod:         "GET",
+		BaseURI:            "/poll",
+		ResponseStatusCode: 200,
+		ResponseValues:     []ResponseValue{{ExtractionKey: "status", Value: "status"}},
+	}
+	condition, _ := parseCondition("{{poll.status}} == PENDING")
+	loop := &Loop{Name: "poll", Condition: condition, Timeout: "100ms", Interval: "30ms", Steps: SuiteSteps{{TestDefinition: poll}}}
+	ts := &TestSuite{TestDefinitions: []*TestDefinition{poll}, Steps: SuiteSteps{{Loop: loop}}}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	start := time.Now()
+	executeTestSuiteIteration(ts, config, 1, 0, perfStats)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// The condition does not hold before the first request, so the loop
+	// does not run the first time; it keeps polling once it holds.
+	assert.Equal(t, perfTestUtils.StepStats{Type: StepTypeWhile, Count: 1}, *perfStats.StepStats["poll"])
+
+	loop.Until = true
+	loop.Condition, _ = parseCondition("{{poll.status}} == DONE")
+	executeTestSuiteIteration(ts, config, 1, 1, perfStats)
+	stepStats := perfStats.StepStats["poll"]
+	assert.Equal(t, uint64(2), stepStats.Count)
+	assert.Equal(t, uint64(1), stepStats.Timeouts)
+	assert.True(t, stepStats.Passes >= 2 && stepStats.Passes <= 4)
+}
+
+func TestExecuteLoopSeesNewValues(t *testing.T) {
+	// The order is pending for the first two polls.
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		status := "PENDING"
+		if atomic.AddInt32(&polls, 1) > 2 {
+			status = "DONE"
+		}
+		w.Write([]byte(`{"status":"` + status + `"}`))
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	poll := &TestDefinition{
+		TestName:           "poll",
+		HTTPMethod:         "GET",
+		BaseURI:            "/poll",
+		ResponseStatusCode: 200,
+		ResponseValues:     []ResponseValue{{ExtractionKey: "status", Value: "status"}},
+	}
+	condition, _ := parseCondition("{{poll.status}} == DONE")
+	loop := &Loop{Name: "poll", Until: true, Condition: condition, Timeout: "5s", Interval: "10ms", Steps: SuiteSteps{{TestDefinition: poll}}}
+	ts := &TestSuite{TestDefinitions: []*TestDefinition{poll}, Steps: SuiteSteps{{Loop: loop}}}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	responseTimes := executeTestSuiteIteration(ts, config, 1, 0, perfStats)
+
+	// Every pass extracts the status again, so the loop ends on the third.
+	assert.Equal(t, perfTestUtils.StepStats{Type: StepTypeUntil, Count: 1, Passes: 3}, *perfStats.StepStats["poll"])
+	assert.Equal(t, int32(3), atomic.LoadInt32(&polls))
+
+	// Every poll is a response time sample, as it is a transaction.
+	assert.Equal(t, 3, len(responseTimes["poll"]))
+	assert.Equal(t, uint64(3), *perfStats.ServiceTransCount["poll"])
+}
@@ -0,0 +1,359 @@
+package testStrategies
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths of the agent API. The controller sends the test run to every agent
+// with agentPreparePath, then starts all of them at once with
+// agentStartPath, which responds with the results when the run is over. A
+// stop of the test run on the controller is forwarded with agentStopPath.
+// Once the run is over, agentTeardownPath runs the <userTeardown> of the
+// virtual users of the agent.
+const (
+	agentPreparePath  = "/prepare"
+	agentStartPath    = "/start"
+	agentStopPath     = "/stop"
+	agentTeardownPath = "/teardown"
+)
+
+// agentRequestTimeout bounds the requests that prepare and stop agents. A
+// start request lasts as long as the test run.
+const agentRequestTimeout = 30 * time.Second
+
+// agentRun is a test run the controller sends to an agent.
+type agentRun struct {
+	ConfigurationSettings *perfTestUtils.Config
+	TestSuite             *TestSuite
+	Mode                  int
+}
+
+// agentResult is the result of a test run on an agent: the response time
+// samples of each service, and the counters of the run.
+type agentResult struct {
+	ResponseTimes map[string][]int64
+	PerfStats     *perfTestUtils.PerfStats
+}
+
+// agent runs the test runs sent by a controller, one at a time.
+type agent struct {
+	mu       sync.Mutex
+	prepared *agentRun
+	running  *agentRun
+	finished *agentRun
+}
+
+//----- ServeAgent ------------------------------------------------------------
+// Run as an agent of a distributed test run, listening on address for the
+// test runs of a controller. Only requests that carry the token are
+// accepted, so the agent does not start without one. Only returns if the
+// agent cannot listen.
+func ServeAgent(address string, token string) error {
+	if token == "" {
+		return errors.New("no agent token is set: set -agentToken on the agent and the controller")
+	}
+	log.Warnf("Agent listening on [%s]", address)
+	return http.ListenAndServe(address, newAgentHandler(token))
+}
+
+// newAgentHandler returns the handler of the agent API. Requests without the
+// token are rejected, as are all requests if the token is empty.
+func newAgentHandler(token string) http.Handler {
+	a := &agent{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(agentPreparePath, a.prepare)
+	mux.HandleFunc(agentStartPath, a.start)
+	mux.HandleFunc(agentStopPath, a.stop)
+	mux.HandleFunc(agentTeardownPath, a.teardown)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// prepare stores the test run of the request until it is started.
+func (a *agent) prepare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	run := new(agentRun)
+	if err := json.NewDecoder(r.Body).Decode(run); err != nil || run.ConfigurationSettings == nil || run.TestSuite == nil {
+		http.Error(w, "invalid test run", http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running != nil {
+		http.Error(w, "a test run is in progress", http.StatusConflict)
+		return
+	}
+	a.prepared = run
+	log.Infof("Prepared test run of suite [%s]", run.TestSuite.Name)
+}
+
+// start runs the prepared test run, and responds with its results.
+func (a *agent) start(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	a.mu.Lock()
+	run := a.prepared
+	if a.running != nil || run == nil {
+		a.mu.Unlock()
+		http.Error(w, "no test run prepared", http.StatusConflict)
+		return
+	}
+	a.prepared = nil
+	a.running = run
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.running = nil
+		a.finished = run
+		a.mu.Unlock()
+	}()
+
+	log.Infof("Test run of suite [%s] started", run.TestSuite.Name)
+	result := run.execute()
+	log.Infof("Test run of suite [%s] finished. Iterations=[%d] Trans=[%d] Errors=[%d]",
+		run.TestSuite.Name,
+		result.PerfStats.IterationCount,
+		result.PerfStats.OverAllTransCount,
+		result.PerfStats.OverAllErrorCount,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Error("Failed to send the results to the controller. Error: ", err)
+	}
+}
+
+// stop stops the test run in progress, if any. The request body is the
+// reason the controller stopped the test run.
+func (a *agent) stop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	reason, _ := ioutil.ReadAll(io.LimitReader(r.Body, 1024))
+
+	a.mu.Lock()
+	run := a.running
+	a.mu.Unlock()
+	if run != nil {
+		log.Warnf("Stopping the test run: %s", reason)
+		StopTestRun(string(reason), run.ConfigurationSettings.GracePeriodDuration())
+	}
+}
+
+// teardown runs the <userTeardown> of the virtual users of the last test
+// run, if it did not run yet.
+func (a *agent) teardown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	a.mu.Lock()
+	run := a.finished
+	a.finished = nil
+	a.mu.Unlock()
+	if run != nil {
+		runUserTeardowns(phaseSuites(run.TestSuite, run.ConfigurationSettings))
+	}
+}
+
+// execute runs the test suite the same way a standalone suite based run
+// does, and returns its results. The abort criteria apply to the requests
+// of this agent. Memory is polled by the controller.
+func (run *agentRun) execute() *agentResult {
+	resetTestRun()
+	runStart := time.Now()
+	perfStatsForTest := &perfTestUtils.PerfStats{
+		TestTimeStart:        runStart,
+		ServiceResponseTimes: make(map[string]int64),
+		ServiceTransCount:    make(map[string]*uint64),
+		ServiceErrorCount:    make(map[string]*uint64),
+		ServiceTimeoutCount:  make(map[string]*uint64),
+		ServiceRetryCount:    make(map[string]*uint64),
+		ServiceTPS:           make(map[string]float64),
+	}
+
+	stopAbortMonitor := StartAbortMonitor(run.ConfigurationSettings, perfStatsForTest, new(uint64))
+	var responseTimes map[string][]int64
+	if len(run.TestSuite.Workloads) > 0 {
+		responseTimes = ExecuteWorkloads(run.TestSuite, run.ConfigurationSettings, perfStatsForTest, runStart, run.Mode)
+	} else {
+		responseTimes = ExecuteTestSuiteWrapper(run.TestSuite, run.ConfigurationSettings, perfStatsForTest, runStart)
+	}
+	stopAbortMonitor()
+
+	perfStatsForTest.TestTimeEnd = time.Now()
+	perfStatsForTest.StopReason = TestRunStopReason()
+	return &agentResult{ResponseTimes: responseTimes, PerfStats: perfStatsForTest}
+}
+
+//----- ExecuteOnAgents -------------------------------------------------------
+// Run the test suite on every agent of configSettings.Agents at the same
+// time, and return the response times of every service across all agents.
+// Each agent runs the full configured load. The counters of every agent are
+// merged into perfStatsForTest. A stop of the test run is forwarded to the
+// agents, and an agent that stops early, or fails, stops the others. The
+// setup and teardown of the suite run on the controller, see RunSetups and
+// RunTeardowns, the ones of the virtual users on the agents.
+func ExecuteOnAgents(
+	testSuite *TestSuite,
+	configSettings *perfTestUtils.Config,
+	perfStatsForTest *perfTestUtils.PerfStats,
+	mode int,
+) (map[string][]int64, error) {
+	if configSettings.AgentToken == "" {
+		return nil, errors.New("no agent token is set: set agentToken on the controller and the agents")
+	}
+	run, err := json.Marshal(agentRun{ConfigurationSettings: configSettings, TestSuite: testSuite, Mode: mode})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: agentRequestTimeout}
+	for _, agent := range configSettings.Agents {
+		if _, err := postToAgent(client, agent, configSettings.AgentToken, agentPreparePath, "application/json", run); err != nil {
+			return nil, fmt.Errorf("failed to prepare agent [%s]: %v", agent, err)
+		}
+	}
+
+	log.Infof("Starting the test run on [%d] agents", len(configSettings.Agents))
+	done := make(chan struct{})
+	defer close(done)
+	go forwardStop(configSettings.Agents, configSettings.AgentToken, done)
+
+	allServicesResponseTimesMap := make(map[string][]int64)
+	var resultsMu sync.Mutex
+	var agentWaitGroup sync.WaitGroup
+	for _, agent := range configSettings.Agents {
+		agentWaitGroup.Add(1)
+		go func(agent string) {
+			defer agentWaitGroup.Done()
+			result, err := startAgent(agent, configSettings.AgentToken)
+			if err != nil {
+				log.Errorf("Agent [%s] failed. Error: %v", agent, err)
+				StopTestRun(fmt.Sprintf("agent [%s] failed", agent), configSettings.GracePeriodDuration())
+				return
+			}
+			if result.PerfStats.StopReason != "" {
+				StopTestRun(fmt.Sprintf("agent [%s] %s", agent, result.PerfStats.StopReason), configSettings.GracePeriodDuration())
+			}
+			log.Infof("Agent [%s] finished. Iterations=[%d] Trans=[%d] Errors=[%d]",
+				agent,
+				result.PerfStats.IterationCount,
+				result.PerfStats.OverAllTransCount,
+				result.PerfStats.OverAllErrorCount,
+			)
+
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+			perfStatsForTest.Merge(result.PerfStats)
+			for serviceName, serviceResponseTimes := range result.ResponseTimes {
+				allServicesResponseTimesMap[serviceName] = append(allServicesResponseTimesMap[serviceName], serviceResponseTimes...)
+			}
+		}(agent)
+	}
+	agentWaitGroup.Wait()
+
+	return allServicesResponseTimesMap, nil
+}
+
+// startAgent starts the prepared test run of the agent, and returns its
+// results once the run is over.
+func startAgent(agent string, token string) (*agentResult, error) {
+	body, err := postToAgent(&http.Client{}, agent, token, agentStartPath, "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	result := new(agentResult)
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, fmt.Errorf("invalid results: %v", err)
+	}
+	if result.PerfStats == nil {
+		return nil, fmt.Errorf("no results")
+	}
+	return result, nil
+}
+
+// tearDownAgents runs the <userTeardown> of the virtual users of every
+// agent, at the same time.
+func tearDownAgents(agents []string, token string) {
+	var teardownWaitGroup sync.WaitGroup
+	for _, agent := range agents {
+		teardownWaitGroup.Add(1)
+		go func(agent string) {
+			defer teardownWaitGroup.Done()
+			if _, err := postToAgent(&http.Client{}, agent, token, agentTeardownPath, "application/json", nil); err != nil {
+				log.Warnf("Failed to tear down the virtual users of agent [%s]. Error: %v", agent, err)
+			}
+		}(agent)
+	}
+	teardownWaitGroup.Wait()
+}
+
+// forwardStop stops the test run on every agent once it has been stopped on
+// the controller, unless done is closed first.
+func forwardStop(agents []string, token string, done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-testRunStopped():
+	}
+	reason := TestRunStopReason()
+	client := &http.Client{Timeout: agentRequestTimeout}
+	for _, agent := range agents {
+		if _, err := postToAgent(client, agent, token, agentStopPath, "text/plain", []byte(reason)); err != nil {
+			log.Warnf("Failed to stop agent [%s]. Error: %v", agent, err)
+		}
+	}
+}
+
+// postToAgent sends a request to the agent API, with the agent token, and
+// returns the response body. An agent is addressed as
+// host:port, or by its base URL.
+func postToAgent(client *http.Client, agent string, token string, path string, contentType string, body []byte) ([]byte, error) {
+	agentURL := strings.TrimSuffix(agent, "/")
+	if !strings.Contains(agentURL, "://") {
+		agentURL = "http://" + agentURL
+	}
+	req, err := http.NewRequest("POST", agentURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
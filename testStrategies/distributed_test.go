@@ -0,0 +1,142 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExecuteOnAgents(t *testing.T) {
+	var requests int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/checkout" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer target.Close()
+	host, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	agent1 := httptest.NewServer(newAgentHandler("secret"))
+	defer agent1.Close()
+	agent2 := httptest.NewServer(newAgentHandler("secret"))
+	defer agent2.Close()
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	config.ConcurrentUsers = 2
+	config.NumIterations = 3
+	config.Agents = []string{agent1.Listener.Addr().String(), agent2.URL}
+	config.AgentToken = "secret"
+	search := &TestDefinition{TestName: "search", HTTPMethod: "GET", BaseURI: "/search", ResponseStatusCode: 200}
+	checkout := &TestDefinition{TestName: "checkout", HTTPMethod: "GET", BaseURI: "/checkout", ResponseStatusCode: 200}
+	testSuite := &TestSuite{
+		Name:            "distributed",
+		TestStrategy:    SuiteBasedTesting,
+		Steps:           SuiteSteps{{TestDefinition: search}, {TestDefinition: checkout}},
+		TestDefinitions: []*TestDefinition{search, checkout},
+	}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	responseTimes, err := ExecuteOnAgents(testSuite, config, perfStats, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "", TestRunStopReason())
+
+	// Each agent runs the full load: 2 users of 3 iterations of 2 requests.
+	assert.Equal(t, int32(24), atomic.LoadInt32(&requests))
+	assert.Equal(t, 12, len(responseTimes["search"]))
+	assert.Equal(t, 12, len(responseTimes["checkout"]))
+	assert.Equal(t, uint64(12), perfStats.IterationCount)
+	assert.Equal(t, uint64(12), *perfStats.ServiceTransCount["search"])
+	assert.Equal(t, uint64(12), *perfStats.ServiceErrorCount["checkout"])
+	assert.Equal(t, uint64(12), perfStats.OverAllErrorCount)
+}
+
+func TestExecuteOnAgentsUnavailable(t *testing.T) {
+	agent := httptest.NewServer(http.NotFoundHandler())
+	address := agent.Listener.Addr().String()
+	agent.Close()
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.Agents = []string{address}
+	config.AgentToken = "secret"
+
+	_, err := ExecuteOnAgents(&TestSuite{}, config, &perfTestUtils.PerfStats{}, 2)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), address)
+}
+
+func TestExecuteOnAgentsWithoutToken(t *testing.T) {
+	var requests int32
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer agent.Close()
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.Agents = []string{agent.URL}
+
+	_, err := ExecuteOnAgents(&TestSuite{}, config, &perfTestUtils.PerfStats{}, 2)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
+
+func TestAgentAPI(t *testing.T) {
+	agent := httptest.NewServer(newAgentHandler("secret"))
+	defer agent.Close()
+	post := func(path string, body string) int {
+		req, _ := http.NewRequest("POST", agent.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// A run must be prepared before it is started.
+	assert.Equal(t, http.StatusConflict, post(agentStartPath, ""))
+	assert.Equal(t, http.StatusBadRequest, post(agentPreparePath, "{}"))
+	req, _ := http.NewRequest("GET", agent.URL+agentPreparePath, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	// Stopping an idle agent has no effect.
+	assert.Equal(t, http.StatusOK, post(agentStopPath, "interrupted"))
+	assert.Equal(t, "", TestRunStopReason())
+}
+
+func TestAgentAPIToken(t *testing.T) {
+	agent := httptest.NewServer(newAgentHandler("secret"))
+	defer agent.Close()
+
+	_, err := postToAgent(&http.Client{}, agent.URL, "", agentStopPath, "text/plain", []byte("interrupted"))
+	assert.EqualError(t, err, "status 401: unauthorized")
+	_, err = postToAgent(&http.Client{}, agent.URL, "wrong", agentStopPath, "text/plain", []byte("interrupted"))
+	assert.EqualError(t, err, "status 401: unauthorized")
+	_, err = postToAgent(&http.Client{}, agent.URL, "secret", agentStopPath, "text/plain", []byte("interrupted"))
+	assert.Nil(t, err)
+	assert.Equal(t, "", TestRunStopReason())
+
+	// Without a token, the agent does not start, and accepts no requests.
+	assert.NotNil(t, ServeAgent("127.0.0.1:0", ""))
+	noToken := httptest.NewServer(newAgentHandler(""))
+	defer noToken.Close()
+	_, err = postToAgent(&http.Client{}, noToken.URL, "", agentStopPath, "text/plain", []byte("interrupted"))
+	assert.EqualError(t, err, "status 401: unauthorized")
+}
@@ -0,0 +1,325 @@
+package testStrategies
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Feeder file formats, row selection policies and the policies for a feeder
+// that runs out of rows.
+const (
+	FeederFormatCSV   = "csv"
+	FeederFormatJSON  = "json"
+	FeederFormatLines = "lines"
+
+	FeederSequential         = "sequential"
+	FeederRandom             = "random"
+	FeederUniquePerUser      = "uniquePerUser"
+	FeederUniquePerIteration = "uniquePerIteration"
+
+	FeederRecycle  = "recycle"
+	FeederStopUser = "stopUser"
+	FeederStopTest = "stopTest"
+)
+
+// feederValueColumn is the column of the rows of a feeder file without
+// named columns: a JSON array of scalars, or plain lines.
+const feederValueColumn = "value"
+
+// Feeder is a <feeder> element of the <feeders> block of a test suite: a file
+// of rows, one of which is given to every iteration of the suite. The columns
+// of the row are available to the requests of the iteration as
+// {{feederName.column}} placeholders.
+//
+// Selection decides which row an iteration gets:
+//   - sequential: the iterations of all virtual users walk the rows in
+//     order from the first, sharing one cursor.
+//   - random: a row picked at random. A random feeder never runs out.
+//   - uniquePerUser: every virtual user gets a row of its own, and keeps it
+//     for all of its iterations.
+//   - uniquePerIteration: every iteration, of any user, gets a row of its
+//     own.
+//
+// OnExhausted decides what happens when there are no rows left: recycle
+// starts over from the first row, stopUser stops the virtual user and
+// stopTest stops the test run.
+//
+// The rows are read when the suite is built, so that they are sent to the
+// agents of a distributed run along with the suite. Rows are unique per
+// agent, every agent having a copy of all of them.
+type Feeder struct {
+	Name        string              `xml:"name,attr"`
+	File        string              `xml:"file,attr"`
+	Format      string              `xml:"format,attr"`
+	Selection   string              `xml:"selection,attr"`
+	OnExhausted string              `xml:"onExhausted,attr"`
+	Rows        []map[string]string `xml:"-"`
+
+	// next is the count of rows handed out to sequential and
+	// uniquePerIteration iterations.
+	next uint64
+}
+
+// validate checks the attributes of the feeder and sets the defaults of the
+// ones that are not set: the format is taken from the file extension, rows
+// are selected sequentially and recycled.
+func (f *Feeder) validate() error {
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Name == "" {
+		return fmt.Errorf("<feeder> without a name")
+	}
+	if strings.TrimSpace(f.File) == "" {
+		return fmt.Errorf("feeder [%s]: no file", f.Name)
+	}
+	if f.Format == "" {
+		switch strings.ToLower(filepath.Ext(f.File)) {
+		case ".csv":
+			f.Format = FeederFormatCSV
+		case ".json":
+			f.Format = FeederFormatJSON
+		default:
+			f.Format = FeederFormatLines
+		}
+	}
+	if f.Format != FeederFormatCSV && f.Format != FeederFormatJSON && f.Format != FeederFormatLines {
+		return fmt.Errorf("feeder [%s]: invalid format [%s]", f.Name, f.Format)
+	}
+	switch f.Selection {
+	case "":
+		f.Selection = FeederSequential
+	case FeederSequential, FeederRandom, FeederUniquePerUser, FeederUniquePerIteration:
+	default:
+		return fmt.Errorf("feeder [%s]: invalid selection [%s]", f.Name, f.Selection)
+	}
+	switch f.OnExhausted {
+	case "":
+		f.OnExhausted = FeederRecycle
+	case FeederRecycle, FeederStopUser, FeederStopTest:
+	default:
+		return fmt.Errorf("feeder [%s]: invalid onExhausted [%s]", f.Name, f.OnExhausted)
+	}
+	return nil
+}
+
+// load reads the rows of the feeder from its file, relative to dir.
+func (f *Feeder) load(dir string) error {
+	bs, err := ioutil.ReadFile(filepath.Join(dir, f.File))
+	if err != nil {
+		return fmt.Errorf("feeder [%s]: %v", f.Name, err)
+	}
+	switch f.Format {
+	case FeederFormatCSV:
+		f.Rows, err = parseCSVRows(bs)
+	case FeederFormatJSON:
+		f.Rows, err = parseJSONRows(bs)
+	default:
+		f.Rows, err = parseLineRows(bs)
+	}
+	if err != nil {
+		return fmt.Errorf("feeder [%s]: %v", f.Name, err)
+	}
+	if len(f.Rows) == 0 {
+		return fmt.Errorf("feeder [%s]: no rows in [%s]", f.Name, f.File)
+	}
+	return nil
+}
+
+// parseCSVRows reads CSV rows, the first of which names the columns.
+func parseCSVRows(bs []byte) ([]map[string]string, error) {
+	records, err := csv.NewReader(bytes.NewReader(bs)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSONRows reads a JSON array of objects, whose fields are the columns,
+// or of scalars, which are the "value" column.
+func parseJSONRows(bs []byte) ([]map[string]string, error) {
+	var values []json.RawMessage
+	if err := json.Unmarshal(bs, &values); err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]string, 0, len(values))
+	for _, value := range values {
+		row, err := parseJSONRow(value)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseLineRows reads a row from every line that is not blank. A line holding
+// a JSON object is read as in a JSON feeder, any other line is the "value"
+// column.
+func parseLineRows(bs []byte) ([]map[string]string, error) {
+	rows := make([]map[string]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "{") {
+			rows = append(rows, map[string]string{feederValueColumn: line})
+			continue
+		}
+		row, err := parseJSONRow(json.RawMessage(line))
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+// parseJSONRow returns the row of a JSON value. The columns of an object keep
+// the JSON text of numbers, objects and arrays.
+func parseJSONRow(value json.RawMessage) (map[string]string, error) {
+	d := json.NewDecoder(bytes.NewReader(value))
+	d.UseNumber()
+	var decoded interface{}
+	if err := d.Decode(&decoded); err != nil && err != io.EOF {
+		return nil, err
+	}
+	object, ok := decoded.(map[string]interface{})
+	if !ok {
+		return map[string]string{feederValueColumn: jsonColumn(decoded)}, nil
+	}
+	row := make(map[string]string, len(object))
+	for column, v := range object {
+		row[column] = jsonColumn(v)
+	}
+	return row, nil
+}
+
+func jsonColumn(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	default:
+		bs, _ := json.Marshal(v)
+		return string(bs)
+	}
+}
+
+// row returns the row for the next iteration of a virtual user, or false if
+// the feeder ran out of rows and does not recycle them.
+func (f *Feeder) row(userID int) (map[string]string, bool) {
+	var index int
+	switch f.Selection {
+	case FeederRandom:
+		return f.Rows[rand.Intn(len(f.Rows))], true
+	case FeederUniquePerUser:
+		index = userID
+	default:
+		index = int(atomic.AddUint64(&f.next, 1) - 1)
+	}
+	if index >= len(f.Rows) && f.OnExhausted != FeederRecycle {
+		return nil, false
+	}
+	return f.Rows[index%len(f.Rows)], true
+}
+
+// loadFeeders validates the feeders of the suite and reads their rows from
+// files relative to dir.
+func (ts *TestSuite) loadFeeders(dir string) error {
+	for _, feeder := range ts.Feeders {
+		if err := feeder.load(dir); err != nil {
+			return err
+		}
+		log.Infof("Feeder [%s] loaded. Rows=[%d] Selection=[%s] OnExhausted=[%s]",
+			feeder.Name,
+			len(feeder.Rows),
+			feeder.Selection,
+			feeder.OnExhausted,
+		)
+	}
+	return nil
+}
+
+// validateFeeders checks the feeders of the suite, whose names must be
+// unique.
+func (ts *TestSuite) validateFeeders() error {
+	names := make(map[string]bool)
+	for _, feeder := range ts.Feeders {
+		if err := feeder.validate(); err != nil {
+			return err
+		}
+		if names[feeder.Name] {
+			return fmt.Errorf("duplicate feeder [%s]", feeder.Name)
+		}
+		names[feeder.Name] = true
+	}
+	return nil
+}
+
+// feed sets the columns of a row of every feeder as the values of an
+// iteration. It returns false if the iteration must not run because a
+// feeder ran out of rows, after stopping the test run if that feeder says
+// so.
+func (si *suiteIteration) feed(feeders []*Feeder) bool {
+	if len(feeders) == 0 {
+		return true
+	}
+	values := make(map[string]string)
+	for _, feeder := range feeders {
+		row, ok := feeder.row(si.userID)
+		if !ok {
+			if feeder.OnExhausted == FeederStopTest {
+				StopTestRun(fmt.Sprintf("feeder [%s] ran out of rows", feeder.Name), si.configurationSettings.GracePeriodDuration())
+			} else {
+				log.Infof("Feeder [%s] ran out of rows, stopping user [%d].", feeder.Name, si.userID)
+			}
+			return false
+		}
+		for column, value := range row {
+			values[feeder.Name+"."+column] = value
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	testRunGlobals := globalsMap[si.uniqueTestRunID]
+	if testRunGlobals == nil {
+		testRunGlobals = make(map[string]interface{})
+		globalsMap[si.uniqueTestRunID] = testRunGlobals
+	}
+	for name, value := range values {
+		testRunGlobals[name] = value
+	}
+	return true
+}
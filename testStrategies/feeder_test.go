@@ -0,0 +1,182 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+const xmlFeederTestSuite = `<testSuite>
+    <name>feederSuite</name>
+    <testStrategy>SuiteBased</testStrategy>
+    <feeders>
+        <feeder name="accounts" file="accounts.csv" selection="uniquePerUser" onExhausted="stopUser"/>
+        <feeder name="terms" file="terms.txt" selection="random"/>
+        <feeder name="orders" file="orders.json" format="json" selection="sequential" onExhausted="stopTest"/>
+    </feeders>
+    <testCases>
+        <testCase>search.xml</testCase>
+    </testCases>
+</testSuite>`
+
+func TestLoadFeeders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeders")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeWorkloadFile(t, dir, "feeder-suite.xml", xmlFeederTestSuite)
+	writeWorkloadFile(t, dir, "search.xml", `<testDefinition><testName>search</testName></testDefinition>`)
+	writeWorkloadFile(t, dir, "accounts.csv", "id, name\n1,\"Doe, Jane\"\n2,Roe\n")
+	writeWorkloadFile(t, dir, "terms.txt", "shoes\n\n{\"value\":\"red hat\",\"size\":9}\n")
+	writeWorkloadFile(t, dir, "orders.json", `[{"id":12345678901,"lines":[1,2],"gift":true,"note":null},"A-1"]`)
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TestCaseDir = dir
+	config.TestSuiteDir = dir
+	config.TestSuite = "feeder-suite.xml"
+	ts := new(TestSuite)
+	ts.BuildTestSuite(config)
+
+	assert.Equal(t, 3, len(ts.Feeders))
+	accounts := ts.Feeders[0]
+	assert.Equal(t, FeederFormatCSV, accounts.Format)
+	assert.Equal(t, []map[string]string{{"id": "1", "name": "Doe, Jane"}, {"id": "2", "name": "Roe"}}, accounts.Rows)
+
+	terms := ts.Feeders[1]
+	assert.Equal(t, FeederFormatLines, terms.Format)
+	assert.Equal(t, FeederRecycle, terms.OnExhausted)
+	assert.Equal(t, []map[string]string{{"value": "shoes"}, {"value": "red hat", "size": "9"}}, terms.Rows)
+
+	orders := ts.Feeders[2]
+	assert.Equal(t, []map[string]string{{"id": "12345678901", "lines": "[1,2]", "gift": "true", "note": ""}, {"value": "A-1"}}, orders.Rows)
+
+	// The rows are sent to the agents of a distributed run with the suite.
+	encoded, err := json.Marshal(ts)
+	assert.Nil(t, err)
+	decoded := new(TestSuite)
+	assert.Nil(t, json.Unmarshal(encoded, decoded))
+	assert.Equal(t, accounts.Rows, decoded.Feeders[0].Rows)
+	assert.Equal(t, FeederStopTest, decoded.Feeders[2].OnExhausted)
+}
+
+func TestLoadFeedersErr(t *testing.T) {
+	for _, feeders := range []string{
+		`<feeder file="a.csv"/>`,
+		`<feeder name="a"/>`,
+		`<feeder name="a" file="a.csv" format="xml"/>`,
+		`<feeder name="a" file="a.csv" selection="roundRobin"/>`,
+		`<feeder name="a" file="a.csv" onExhausted="wait"/>`,
+		`<feeder name="a" file="a.csv"/><feeder name="a" file="b.csv"/>`,
+	} {
+		ts := new(TestSuite)
+		err := ts.loadTestSuiteDefinition([]byte(`<testSuite><feeders>` + feeders + `</feeders><testCases/></testSuite>`))
+		assert.NotNil(t, err, feeders)
+	}
+
+	dir, err := ioutil.TempDir("", "feeders")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeWorkloadFile(t, dir, "header.csv", "id\n")
+	writeWorkloadFile(t, dir, "ragged.csv", "id,name\n1\n")
+	writeWorkloadFile(t, dir, "object.json", `{"id":1}`)
+	writeWorkloadFile(t, dir, "broken.txt", "{\"id\":\n")
+	for _, file := range []string{"missing.csv", "header.csv", "ragged.csv", "object.json", "broken.txt"} {
+		feeder := &Feeder{Name: "a", File: file}
+		assert.Nil(t, feeder.validate())
+		assert.NotNil(t, feeder.load(dir), file)
+	}
+}
+
+func TestFeederRow(t *testing.T) {
+	rows := []map[string]string{{"id": "0"}, {"id": "1"}, {"id": "2"}}
+	ids := func(feeder *Feeder, userID int, iterations int) []string {
+		ids := make([]string, 0)
+		for i := 0; i < iterations; i++ {
+			row, ok := feeder.row(userID)
+			if !ok {
+				break
+			}
+			ids = append(ids, row["id"])
+		}
+		return ids
+	}
+
+	// The users share one cursor over the rows.
+	sequential := &Feeder{Selection: FeederSequential, OnExhausted: FeederRecycle, Rows: rows}
+	assert.Equal(t, []string{"0", "1", "2", "0"}, ids(sequential, 0, 4))
+	assert.Equal(t, []string{"1", "2", "0", "1"}, ids(sequential, 1, 4))
+	sequential = &Feeder{Selection: FeederSequential, OnExhausted: FeederStopUser, Rows: rows}
+	assert.Equal(t, []string{"0", "1"}, ids(sequential, 0, 2))
+	assert.Equal(t, []string{"2"}, ids(sequential, 1, 2))
+
+	perUser := &Feeder{Selection: FeederUniquePerUser, OnExhausted: FeederStopUser, Rows: rows}
+	assert.Equal(t, []string{"1", "1"}, ids(perUser, 1, 2))
+	assert.Equal(t, []string{}, ids(perUser, 3, 2))
+	perUser.OnExhausted = FeederRecycle
+	assert.Equal(t, []string{"0"}, ids(perUser, 3, 1))
+
+	// Every iteration of any user gets the next row.
+	perIteration := &Feeder{Selection: FeederUniquePerIteration, OnExhausted: FeederStopTest, Rows: rows}
+	assert.Equal(t, []string{"0", "1"}, ids(perIteration, 0, 2))
+	assert.Equal(t, []string{"2"}, ids(perIteration, 1, 2))
+	assert.Equal(t, []string{}, ids(perIteration, 2, 2))
+
+	random := &Feeder{Selection: FeederRandom, OnExhausted: FeederStopUser, Rows: rows}
+	assert.Equal(t, 100, len(ids(random, 0, 100)))
+}
+
+func TestExecuteFeeders(t *testing.T) {
+	var m sync.Mutex
+	requests := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		requests = append(requests, r.URL.RequestURI())
+		m.Unlock()
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	account := &TestDefinition{
+		TestName:           "account",
+		HTTPMethod:         "GET",
+		BaseURI:            "/accounts/{{accounts.id}}?q={{terms.value}}",
+		ResponseStatusCode: 200,
+	}
+	ts := &TestSuite{
+		TestDefinitions: []*TestDefinition{account},
+		Steps:           SuiteSteps{{TestDefinition: account}},
+		Feeders: []*Feeder{
+			{Name: "accounts", Selection: FeederSequential, OnExhausted: FeederStopUser, Rows: []map[string]string{{"id": "7"}, {"id": "8"}}},
+			{Name: "terms", Selection: FeederRandom, Rows: []map[string]string{{"value": "shoes"}}},
+		},
+	}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	// The user stops once it has used every account.
+	var suiteWaitGroup sync.WaitGroup
+	suiteWaitGroup.Add(1)
+	executeTestSuite(make(map[string][]int64), ts, config, 0, perfStats, runLimit{iterations: 5}, &suiteWaitGroup)
+	assert.Equal(t, []string{"/accounts/7?q=shoes", "/accounts/8?q=shoes"}, requests)
+	assert.Equal(t, uint64(2), perfStats.IterationCount)
+
+	// A feeder that stops the test stops the test run.
+	defer resetTestRun()
+	ts.Feeders[0].OnExhausted = FeederStopTest
+	assert.Nil(t, executeTestSuiteIteration(ts, config, 1, 0, perfStats))
+	assert.Equal(t, "feeder [accounts] ran out of rows", TestRunStopReason())
+	assert.Equal(t, 2, len(requests))
+}
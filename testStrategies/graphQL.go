@@ -0,0 +1,86 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"regexp"
+	"strings"
+)
+
+// anonymousOperation groups the results of GraphQL requests with neither an
+// operation name nor a named operation in their query.
+const anonymousOperation = "anonymous"
+
+// graphQLOperationName matches the name of the first operation of a query.
+var graphQLOperationName = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// GraphQLRequest turns a test definition into a GraphQL request. The query,
+// operation name and variables are sent as a JSON POST body. Variables is a
+// JSON object, with the same {{...}} substitution as a payload.
+type GraphQLRequest struct {
+	Query         string `xml:"query"`
+	OperationName string `xml:"operationName"`
+	Variables     string `xml:"variables"`
+}
+
+// operation returns the name the results of the request are grouped by: the
+// operation name, else the name of the first operation of the query.
+func (gq *GraphQLRequest) operation() string {
+	if name := strings.TrimSpace(gq.OperationName); name != "" {
+		return name
+	}
+	if match := graphQLOperationName.FindStringSubmatch(gq.Query); match != nil {
+		return match[1]
+	}
+	return anonymousOperation
+}
+
+// payload returns the JSON body of the request, with the values of the
+// iteration substituted into the variables.
+func (gq *GraphQLRequest) payload(uniqueTestRunID string) ([]byte, error) {
+	body := struct {
+		Query         string          `json:"query"`
+		OperationName string          `json:"operationName,omitempty"`
+		Variables     json.RawMessage `json:"variables,omitempty"`
+	}{
+		Query:         strings.TrimSpace(gq.Query),
+		OperationName: strings.TrimSpace(gq.OperationName),
+	}
+	if strings.TrimSpace(gq.Variables) != "" {
+		variables := substituteRequestValues(&gq.Variables, uniqueTestRunID)
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(variables), &object); err != nil {
+			return nil, fmt.Errorf("graphQL variables are not a JSON object: %v", err)
+		}
+		body.Variables = json.RawMessage(variables)
+	}
+	return json.Marshal(body)
+}
+
+// checkGraphQLErrors returns an error if the response body has a non-empty
+// errors array, whatever the status code of the response.
+func checkGraphQLErrors(body []byte) error {
+	var response struct {
+		Errors []json.RawMessage `json:"errors"`
+	}
+	if json.Unmarshal(body, &response) != nil || len(response.Errors) == 0 {
+		return nil
+	}
+	var first struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(response.Errors[0], &first)
+	return fmt.Errorf("graphQL response has %d error(s): %s", len(response.Errors), first.Message)
+}
+
+// recordOperation records the GraphQL operation the given service sends, for
+// the results to be grouped by operation.
+func recordOperation(perfStatsForTest *perfTestUtils.PerfStats, testName string, operation string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if perfStatsForTest.ServiceOperations == nil {
+		perfStatsForTest.ServiceOperations = make(map[string]string)
+	}
+	perfStatsForTest.ServiceOperations[testName] = operation
+}
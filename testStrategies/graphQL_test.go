@@ -0,0 +1,122 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGraphQLOperation(t *testing.T) {
+	assert.Equal(t, "GetOrder", (&GraphQLRequest{OperationName: " GetOrder ", Query: "query Other { a }"}).operation())
+	assert.Equal(t, "AddItem", (&GraphQLRequest{Query: "\n  mutation AddItem($id: ID!) { add(id: $id) }"}).operation())
+	assert.Equal(t, anonymousOperation, (&GraphQLRequest{Query: "{ orders { id } }"}).operation())
+}
+
+func TestLoadGraphQLTestDefinition(t *testing.T) {
+	testDefinition, err := loadTestDefinition([]byte(`<testDefinition>
+    <testName>getOrder</testName>
+    <baseUri>/graphql</baseUri>
+    <graphQL>
+        <query><![CDATA[query GetOrder($id: ID!) { order(id: $id) { id total } }]]></query>
+        <operationName>GetOrder</operationName>
+        <variables>{"id": "{{createOrder.id}}"}</variables>
+    </graphQL>
+    <responseStatusCode>200</responseStatusCode>
+</testDefinition>`))
+	assert.Nil(t, err)
+	assert.Equal(t, "query GetOrder($id: ID!) { order(id: $id) { id total } }", testDefinition.GraphQL.Query)
+	assert.Equal(t, "GetOrder", testDefinition.GraphQL.OperationName)
+	assert.Equal(t, `{"id": "{{createOrder.id}}"}`, testDefinition.GraphQL.Variables)
+}
+
+func TestGraphQLPayload(t *testing.T) {
+	mu.Lock()
+	globalsMap["gqlUser1Iter0"] = map[string]interface{}{"createOrder.id": "A-17"}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		globalsMap["gqlUser1Iter0"] = nil
+		mu.Unlock()
+	}()
+
+	gq := &GraphQLRequest{Query: " query GetOrder { a } ", Variables: `{"id": "{{createOrder.id}}", "first": 10}`}
+	payload, err := gq.payload("gqlUser1Iter0")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"query":"query GetOrder { a }","variables":{"id":"A-17","first":10}}`, string(payload))
+
+	// Without variables, only the query is sent.
+	payload, err = (&GraphQLRequest{Query: "{ a }", OperationName: "A"}).payload("")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"query":"{ a }","operationName":"A"}`, string(payload))
+
+	_, err = (&GraphQLRequest{Query: "{ a }", Variables: `[1, 2]`}).payload("")
+	assert.NotNil(t, err)
+}
+
+func TestCheckGraphQLErrors(t *testing.T) {
+	assert.Nil(t, checkGraphQLErrors([]byte(`{"data":{"a":1}}`)))
+	assert.Nil(t, checkGraphQLErrors([]byte(`{"data":{"a":1},"errors":[]}`)))
+	assert.Nil(t, checkGraphQLErrors([]byte(`not json`)))
+	assert.EqualError(t, checkGraphQLErrors([]byte(`{"data":null,"errors":[{"message":"order not found"},{"message":"x"}]}`)),
+		"graphQL response has 2 error(s): order not found")
+}
+
+func TestBuildAndSendRequestGraphQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query         string                 `json:"query"`
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &request)
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if request.Variables["id"] == "missing" {
+			w.Write([]byte(`{"data":null,"errors":[{"message":"order not found"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"order":{"id":"` + request.Variables["id"].(string) + `"}}}`))
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{
+		TestName:           "getOrder",
+		BaseURI:            "/graphql",
+		ResponseStatusCode: 200,
+		GraphQL:            &GraphQLRequest{Query: "query GetOrder($id: ID!) { order(id: $id) { id } }", Variables: `{"id": "A-17"}`},
+		ResponseValues:     []ResponseValue{{ExtractionKey: "orderId", Value: "data.order.id"}},
+	}
+	perfStats := &perfTestUtils.PerfStats{}
+
+	responseTime, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "gqlUser2Iter0")
+	assert.Nil(t, err)
+	assert.True(t, responseTime > 0)
+	assert.Equal(t, map[string]string{"getOrder": "GetOrder"}, perfStats.ServiceOperations)
+	mu.Lock()
+	assert.Equal(t, "A-17", globalsMap["gqlUser2Iter0"]["getOrder.orderId"])
+	globalsMap["gqlUser2Iter0"] = nil
+	mu.Unlock()
+
+	// A response with errors fails, even with the expected status code.
+	testDefinition.GraphQL.Variables = `{"id": "missing"}`
+	responseTime, err = testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.EqualError(t, err, "graphQL response has 1 error(s): order not found")
+	assert.Equal(t, int64(0), responseTime)
+
+	// So do invalid variables, before anything is sent.
+	testDefinition.GraphQL.Variables = `{"id": }`
+	_, err = testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.NotNil(t, err)
+}
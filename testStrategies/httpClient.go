@@ -0,0 +1,147 @@
+package testStrategies
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// httpClientPool holds the HTTP clients of one configuration: a single client
+// shared by all virtual users, or one client per virtual user, each with a
+// transport and connection pool of its own. It also holds the cookie jars
+// kept for the lifetime of each virtual user.
+type httpClientPool struct {
+	mu         sync.Mutex
+	shared     *http.Client
+	perUser    map[int]*http.Client
+	cookieJars map[int]http.CookieJar
+}
+
+// httpClientPools holds a pool of clients for each configuration used by the
+// test run, so that the workloads of a mixed workload run keep their own
+// connections.
+var (
+	httpClientPoolsMu sync.Mutex
+	httpClientPools   = make(map[*perfTestUtils.Config]*httpClientPool)
+)
+
+// httpClientPoolFor returns the pool of clients of the given configuration.
+func httpClientPoolFor(configurationSettings *perfTestUtils.Config) *httpClientPool {
+	httpClientPoolsMu.Lock()
+	defer httpClientPoolsMu.Unlock()
+	pool := httpClientPools[configurationSettings]
+	if pool == nil {
+		pool = &httpClientPool{
+			perUser:    make(map[int]*http.Client),
+			cookieJars: make(map[int]http.CookieJar),
+		}
+		httpClientPools[configurationSettings] = pool
+	}
+	return pool
+}
+
+// httpClientFor returns the HTTP client the given virtual user sends its
+// requests with.
+func httpClientFor(configurationSettings *perfTestUtils.Config, userID int) *http.Client {
+	pool := httpClientPoolFor(configurationSettings)
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if configurationSettings.HTTPClient.ConnectionPool == perfTestUtils.PerUserConnectionPool {
+		client := pool.perUser[userID]
+		if client == nil {
+			client = newHTTPClient(configurationSettings)
+			pool.perUser[userID] = client
+		}
+		return client
+	}
+	if pool.shared == nil {
+		pool.shared = newHTTPClient(configurationSettings)
+	}
+	return pool.shared
+}
+
+// cookieJarFor returns the cookie jar for a new iteration of the given
+// virtual user: an empty jar, or the jar the user has kept since it started
+// when cookies are kept for the lifetime of the user.
+func cookieJarFor(configurationSettings *perfTestUtils.Config, userID int) http.CookieJar {
+	if configurationSettings.CookieScope != perfTestUtils.UserCookieScope {
+		jar, _ := cookiejar.New(nil)
+		return jar
+	}
+
+	pool := httpClientPoolFor(configurationSettings)
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	jar := pool.cookieJars[userID]
+	if jar == nil {
+		jar, _ = cookiejar.New(nil)
+		pool.cookieJars[userID] = jar
+	}
+	return jar
+}
+
+// withCookieJar returns a client that sends requests over the connections of
+// the given client and keeps its cookies in jar.
+func withCookieJar(client *http.Client, jar http.CookieJar) *http.Client {
+	clientWithJar := *client
+	clientWithJar.Jar = jar
+	return &clientWithJar
+}
+
+// newHTTPClient returns a client with its own transport, set up from the
+// HTTP client and TLS configuration.
+func newHTTPClient(configurationSettings *perfTestUtils.Config) *http.Client {
+	clientConfig := configurationSettings.HTTPClient
+	tlsConfig, err := configurationSettings.TLS.ClientConfig()
+	if err != nil {
+		log.Errorf("Invalid TLS settings, https requests will use the defaults: %v", err)
+	}
+	dialer := &net.Dialer{
+		Timeout:   clientConfig.ConnectTimeoutDuration(),
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   clientConfig.TLSHandshakeTimeoutDuration(),
+		ResponseHeaderTimeout: clientConfig.ResponseHeaderTimeoutDuration(),
+		MaxIdleConnsPerHost:   clientConfig.MaxIdleConnsPerHost,
+		DisableKeepAlives:     clientConfig.DisableKeepAlives,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   clientConfig.RequestTimeoutDuration(),
+	}
+}
+
+// isTimeout returns true if the error is one of the HTTP client timeouts.
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// countTimeout records a request of the given service that failed on one of
+// the HTTP client timeouts. Timeouts are also counted as errors, where the
+// test strategy counts errors.
+func countTimeout(perfStatsForTest *perfTestUtils.PerfStats, testName string) {
+	atomic.AddUint64(&perfStatsForTest.OverAllTimeoutCount, 1)
+
+	mu.Lock()
+	if perfStatsForTest.ServiceTimeoutCount == nil {
+		perfStatsForTest.ServiceTimeoutCount = make(map[string]*uint64)
+	}
+	timeoutCount := perfStatsForTest.ServiceTimeoutCount[testName]
+	if timeoutCount == nil {
+		timeoutCount = new(uint64)
+		perfStatsForTest.ServiceTimeoutCount[testName] = timeoutCount
+	}
+	mu.Unlock()
+	atomic.AddUint64(timeoutCount, 1)
+}
@@ -0,0 +1,213 @@
+package testStrategies
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientForConnectionPool(t *testing.T) {
+	shared := &perfTestUtils.Config{}
+	shared.SetDefaults()
+	assert.True(t, httpClientFor(shared, 0) == httpClientFor(shared, 1))
+
+	perUser := &perfTestUtils.Config{}
+	perUser.SetDefaults()
+	perUser.HTTPClient.ConnectionPool = perfTestUtils.PerUserConnectionPool
+	assert.True(t, httpClientFor(perUser, 0) != httpClientFor(perUser, 1))
+	assert.True(t, httpClientFor(perUser, 1) == httpClientFor(perUser, 1))
+	// Each configuration has clients of its own.
+	assert.True(t, httpClientFor(shared, 0) != httpClientFor(perUser, 0))
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.HTTPClient = perfTestUtils.HTTPClientConfig{
+		ConnectTimeout:        "2s",
+		TLSHandshakeTimeout:   "3s",
+		ResponseHeaderTimeout: "4s",
+		RequestTimeout:        "5s",
+		MaxIdleConnsPerHost:   7,
+		DisableKeepAlives:     true,
+	}
+	config.TLS.ServerName = "api.example.com"
+	client := newHTTPClient(config)
+	assert.Equal(t, 5*time.Second, client.Timeout)
+
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(t, 3*time.Second, transport.TLSHandshakeTimeout)
+	assert.Equal(t, 4*time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, 7, transport.MaxIdleConnsPerHost)
+	assert.True(t, transport.DisableKeepAlives)
+	assert.Equal(t, "api.example.com", transport.TLSClientConfig.ServerName)
+}
+
+func TestRequestTimeoutCounted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	config.HTTPClient.RequestTimeout = "50ms"
+	testSuite := &TestSuite{
+		TestDefinitions: []*TestDefinition{
+			{TestName: "slow", HTTPMethod: "GET", BaseURI: "/slow", ResponseStatusCode: 200},
+			{TestName: "fast", HTTPMethod: "GET", BaseURI: "/fast", ResponseStatusCode: 200},
+		},
+	}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	responseTimes := executeTestSuiteIteration(testSuite, config, 0, 0, perfStats)
+
+	// A timeout is an error of its own category.
+	assert.Equal(t, []int64{0}, responseTimes["slow"])
+	assert.True(t, responseTimes["fast"][0] > 0)
+	assert.Equal(t, uint64(1), perfStats.OverAllErrorCount)
+	assert.Equal(t, uint64(1), perfStats.OverAllTimeoutCount)
+	assert.Equal(t, uint64(1), *perfStats.ServiceTimeoutCount["slow"])
+	assert.Nil(t, perfStats.ServiceTimeoutCount["fast"])
+}
+
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	assert.Nil(t, err)
+	return path
+}
+
+func TestHTTPSTarget(t *testing.T) {
+	var clientCerts int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&clientCerts, int32(len(r.TLS.PeerCertificates)))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	dir, err := ioutil.TempDir("", "tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	serverCert := server.TLS.Certificates[0]
+	caFile := writePEM(t, dir, "ca.pem", "CERTIFICATE", serverCert.Certificate[0])
+	key := x509.MarshalPKCS1PrivateKey(serverCert.PrivateKey.(*rsa.PrivateKey))
+	keyFile := writePEM(t, dir, "key.pem", "RSA PRIVATE KEY", key)
+
+	testDefinition := &TestDefinition{TestName: "secure", HTTPMethod: "GET", BaseURI: "/secure", ResponseStatusCode: 200}
+	send := func(config *perfTestUtils.Config) int64 {
+		scheme, host, port := determineTargetForRequest(testDefinition, config)
+		responseTime, _ := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, &perfTestUtils.PerfStats{}, scheme, host, port, "")
+		return responseTime
+	}
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	config.TargetScheme = "https"
+
+	// The test server certificate is not trusted by default.
+	assert.Equal(t, int64(0), send(config))
+
+	config.TLS.InsecureSkipVerify = true
+	assert.True(t, send(config) > 0)
+
+	// Trusting the CA bundle, and presenting a client certificate.
+	config.TLS.InsecureSkipVerify = false
+	config.TLS.CAFile = caFile
+	config.TLS.ServerName = "example.com"
+	config.TLS.CertFile = caFile
+	config.TLS.KeyFile = keyFile
+	assert.True(t, send(config) > 0)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&clientCerts))
+
+	// The scheme can be overridden per test definition.
+	testDefinition.OverrideScheme = "http"
+	assert.Equal(t, int64(0), send(config))
+}
+
+func TestCookieJarFor(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	// A fresh jar for every iteration by default.
+	assert.True(t, cookieJarFor(config, 0) != cookieJarFor(config, 0))
+
+	config.CookieScope = perfTestUtils.UserCookieScope
+	assert.True(t, cookieJarFor(config, 0) == cookieJarFor(config, 0))
+	assert.True(t, cookieJarFor(config, 0) != cookieJarFor(config, 1))
+}
+
+func TestSuiteIterationCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "s3cr3t", Path: "/"})
+		case "/profile":
+			if cookie, err := r.Cookie("SESSION"); err != nil || cookie.Value != "s3cr3t" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	login := &TestDefinition{TestName: "login", HTTPMethod: "POST", BaseURI: "/login", ResponseStatusCode: 200,
+		ExpectedCookies: ExpectedCookies{Set: []string{"SESSION"}}}
+	profile := &TestDefinition{TestName: "profile", HTTPMethod: "GET", BaseURI: "/profile", ResponseStatusCode: 200,
+		ExpectedCookies: ExpectedCookies{Present: []string{"SESSION"}}}
+	logout := &TestDefinition{TestName: "logout", HTTPMethod: "GET", BaseURI: "/logout", ResponseStatusCode: 200,
+		ExpectedCookies: ExpectedCookies{Set: []string{"SESSION"}}}
+	newPerfStats := func() *perfTestUtils.PerfStats {
+		return &perfTestUtils.PerfStats{
+			ServiceTransCount: make(map[string]*uint64),
+			ServiceErrorCount: make(map[string]*uint64),
+		}
+	}
+
+	// The session cookie of the login step is sent with the next step.
+	perfStats := newPerfStats()
+	responseTimes := executeTestSuiteIteration(&TestSuite{TestDefinitions: []*TestDefinition{login, profile, logout}}, config, 0, 0, perfStats)
+	assert.True(t, responseTimes["profile"][0] > 0)
+	// The logout response does not set the expected cookie.
+	assert.Equal(t, []int64{0}, responseTimes["logout"])
+	assert.Equal(t, uint64(1), perfStats.OverAllErrorCount)
+
+	// The next iteration starts without cookies ...
+	perfStats = newPerfStats()
+	profileOnly := &TestSuite{TestDefinitions: []*TestDefinition{profile}}
+	responseTimes = executeTestSuiteIteration(profileOnly, config, 0, 1, perfStats)
+	assert.Equal(t, []int64{0}, responseTimes["profile"])
+
+	// ... unless cookies are kept for the lifetime of the user.
+	config.CookieScope = perfTestUtils.UserCookieScope
+	executeTestSuiteIteration(&TestSuite{TestDefinitions: []*TestDefinition{login}}, config, 0, 2, perfStats)
+	responseTimes = executeTestSuiteIteration(profileOnly, config, 0, 3, perfStats)
+	assert.True(t, responseTimes["profile"][0] > 0)
+}
@@ -0,0 +1,306 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseJSONSchema parses a JSON Schema and checks that it only uses what
+// validateJSONSchema supports: the type, enum, const, object, array, string,
+// number and combining keywords of the schema, but no references.
+func parseJSONSchema(schema string) (interface{}, error) {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+	if err := checkJSONSchema(parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// checkJSONSchema returns an error for the first part of the schema that is
+// not a schema, has an invalid pattern or is a reference. Patterns are
+// replaced by their compiled form, so that they are compiled only once.
+func checkJSONSchema(schema interface{}) error {
+	if _, ok := schema.(bool); ok {
+		return nil
+	}
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid JSON schema: a schema is an object or a boolean")
+	}
+	if _, ok := object["$ref"]; ok {
+		return fmt.Errorf("invalid JSON schema: $ref is not supported")
+	}
+	if pattern, ok := object["pattern"].(string); ok {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid JSON schema pattern [%s]: %v", pattern, err)
+		}
+		object["pattern"] = compiled
+	}
+	for _, keyword := range []string{"additionalProperties", "items", "not"} {
+		if subschema, ok := object[keyword]; ok {
+			if items, ok := subschema.([]interface{}); ok && keyword == "items" {
+				for _, item := range items {
+					if err := checkJSONSchema(item); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := checkJSONSchema(subschema); err != nil {
+				return err
+			}
+		}
+	}
+	if properties, ok := object["properties"].(map[string]interface{}); ok {
+		for _, subschema := range properties {
+			if err := checkJSONSchema(subschema); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if subschemas, ok := object[keyword].([]interface{}); ok {
+			for _, subschema := range subschemas {
+				if err := checkJSONSchema(subschema); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateJSONSchema returns an error for the first part of value, found at
+// path, that does not match the schema. Unknown keywords, such as "format",
+// are ignored, as JSON Schema requires.
+func validateJSONSchema(schema interface{}, value interface{}, path string) error {
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			return fmt.Errorf("%s is not allowed", path)
+		}
+		return nil
+	}
+	object, _ := schema.(map[string]interface{})
+
+	if t, ok := object["type"]; ok && !matchesJSONType(t, value) {
+		return fmt.Errorf("%s is %s, expected %v", path, jsonType(value), t)
+	}
+	if enum, ok := object["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || reflect.DeepEqual(allowed, value)
+		}
+		if !found {
+			return fmt.Errorf("%s is not one of %v", path, enum)
+		}
+	}
+	if constant, ok := object["const"]; ok && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s is not %v", path, constant)
+	}
+
+	var err error
+	switch v := value.(type) {
+	case map[string]interface{}:
+		err = validateJSONObject(object, v, path)
+	case []interface{}:
+		err = validateJSONArray(object, v, path)
+	case string:
+		err = validateJSONString(object, v, path)
+	case float64:
+		err = validateJSONNumber(object, v, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if subschemas, ok := object["allOf"].([]interface{}); ok {
+		for _, subschema := range subschemas {
+			if err := validateJSONSchema(subschema, value, path); err != nil {
+				return err
+			}
+		}
+	}
+	if subschemas, ok := object["anyOf"].([]interface{}); ok {
+		matches := 0
+		for _, subschema := range subschemas {
+			if validateJSONSchema(subschema, value, path) == nil {
+				matches++
+			}
+		}
+		if matches == 0 {
+			return fmt.Errorf("%s matches none of anyOf", path)
+		}
+	}
+	if subschemas, ok := object["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, subschema := range subschemas {
+			if validateJSONSchema(subschema, value, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s matches %d of oneOf, expected 1", path, matches)
+		}
+	}
+	if subschema, ok := object["not"]; ok && validateJSONSchema(subschema, value, path) == nil {
+		return fmt.Errorf("%s matches not", path)
+	}
+	return nil
+}
+
+func validateJSONObject(schema map[string]interface{}, value map[string]interface{}, path string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, ok := value[name]; !ok {
+					return fmt.Errorf("%s.%s is required", path, name)
+				}
+			}
+		}
+	}
+	if min, ok := schema["minProperties"].(float64); ok && float64(len(value)) < min {
+		return fmt.Errorf("%s has %d properties, expected at least %v", path, len(value), min)
+	}
+	if max, ok := schema["maxProperties"].(float64); ok && float64(len(value)) > max {
+		return fmt.Errorf("%s has %d properties, expected at most %v", path, len(value), max)
+	}
+
+	// Validate the properties in order, for the same error every time.
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	properties, _ := schema["properties"].(map[string]interface{})
+	additionalProperties, hasAdditionalProperties := schema["additionalProperties"]
+	for _, name := range names {
+		if subschema, ok := properties[name]; ok {
+			if err := validateJSONSchema(subschema, value[name], path+"."+name); err != nil {
+				return err
+			}
+		} else if hasAdditionalProperties {
+			if err := validateJSONSchema(additionalProperties, value[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateJSONArray(schema map[string]interface{}, value []interface{}, path string) error {
+	if min, ok := schema["minItems"].(float64); ok && float64(len(value)) < min {
+		return fmt.Errorf("%s has %d items, expected at least %v", path, len(value), min)
+	}
+	if max, ok := schema["maxItems"].(float64); ok && float64(len(value)) > max {
+		return fmt.Errorf("%s has %d items, expected at most %v", path, len(value), max)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if reflect.DeepEqual(value[i], value[j]) {
+					return fmt.Errorf("%s has duplicate items %d and %d", path, i, j)
+				}
+			}
+		}
+	}
+	switch items := schema["items"].(type) {
+	case []interface{}:
+		// A schema per position.
+		for i := 0; i < len(items) && i < len(value); i++ {
+			if err := validateJSONSchema(items[i], value[i], fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		for i := range value {
+			if err := validateJSONSchema(items, value[i], fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateJSONString(schema map[string]interface{}, value string, path string) error {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := schema["minLength"].(float64); ok && length < min {
+		return fmt.Errorf("%s is shorter than %v", path, min)
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > max {
+		return fmt.Errorf("%s is longer than %v", path, max)
+	}
+	if pattern, ok := schema["pattern"].(*regexp.Regexp); ok && !pattern.MatchString(value) {
+		return fmt.Errorf("%s does not match %s", path, pattern)
+	}
+	return nil
+}
+
+func validateJSONNumber(schema map[string]interface{}, value float64, path string) error {
+	if min, ok := schema["minimum"].(float64); ok && value < min {
+		return fmt.Errorf("%s is less than %v", path, min)
+	}
+	if max, ok := schema["maximum"].(float64); ok && value > max {
+		return fmt.Errorf("%s is greater than %v", path, max)
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && value <= min {
+		return fmt.Errorf("%s is not greater than %v", path, min)
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && value >= max {
+		return fmt.Errorf("%s is not less than %v", path, max)
+	}
+	if multipleOf, ok := schema["multipleOf"].(float64); ok && multipleOf > 0 {
+		if quotient := value / multipleOf; quotient != math.Trunc(quotient) {
+			return fmt.Errorf("%s is not a multiple of %v", path, multipleOf)
+		}
+	}
+	return nil
+}
+
+// matchesJSONType returns whether value is of the type, or one of the types,
+// of a "type" keyword.
+func matchesJSONType(t interface{}, value interface{}) bool {
+	types, ok := t.([]interface{})
+	if !ok {
+		types = []interface{}{t}
+	}
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	}
+	return strings.ToLower(reflect.TypeOf(value).Kind().String())
+}
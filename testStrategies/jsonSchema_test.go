@@ -0,0 +1,98 @@
+package testStrategies
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const orderSchema = `{
+    "type": "object",
+    "required": ["id", "status", "lines"],
+    "additionalProperties": false,
+    "properties": {
+        "id": {"type": "integer", "minimum": 1},
+        "status": {"enum": ["open", "shipped"]},
+        "reference": {"type": "string", "pattern": "^[A-Z]{2}-[0-9]+$", "maxLength": 10},
+        "total": {"type": "number", "multipleOf": 0.5, "exclusiveMinimum": 0},
+        "note": {"type": ["string", "null"]},
+        "lines": {
+            "type": "array",
+            "minItems": 1,
+            "uniqueItems": true,
+            "items": {"type": "object", "required": ["sku"], "properties": {"sku": {"type": "string", "minLength": 1}}}
+        }
+    }
+}`
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema, err := parseJSONSchema(orderSchema)
+	assert.Nil(t, err)
+
+	validate := func(document string) error {
+		var value interface{}
+		assert.Nil(t, json.Unmarshal([]byte(document), &value))
+		return validateJSONSchema(schema, value, "$")
+	}
+
+	assert.Nil(t, validate(`{"id": 17, "status": "open", "reference": "AB-12", "total": 2.5, "note": null, "lines": [{"sku": "a"}]}`))
+
+	for document, expected := range map[string]string{
+		`[]`:                           "$ is array, expected object",
+		`{"id": 17, "status": "open"}`: "$.lines is required",
+		`{"id": 1.5, "status": "open", "lines": [{"sku": "a"}]}`:                    "$.id is number, expected integer",
+		`{"id": 0, "status": "open", "lines": [{"sku": "a"}]}`:                      "$.id is less than 1",
+		`{"id": 1, "status": "lost", "lines": [{"sku": "a"}]}`:                      "$.status is not one of [open shipped]",
+		`{"id": 1, "status": "open", "reference": "ab-1", "lines": [{"sku": "a"}]}`: "$.reference does not match ^[A-Z]{2}-[0-9]+$",
+		`{"id": 1, "status": "open", "total": 0.7, "lines": [{"sku": "a"}]}`:        "$.total is not a multiple of 0.5",
+		`{"id": 1, "status": "open", "total": 0, "lines": [{"sku": "a"}]}`:          "$.total is not greater than 0",
+		`{"id": 1, "status": "open", "lines": []}`:                                  "$.lines has 0 items, expected at least 1",
+		`{"id": 1, "status": "open", "lines": [{"sku": "a"}, {"sku": "a"}]}`:        "$.lines has duplicate items 0 and 1",
+		`{"id": 1, "status": "open", "lines": [{"sku": ""}]}`:                       "$.lines[0].sku is shorter than 1",
+		`{"id": 1, "status": "open", "lines": [{"sku": "a"}], "extra": true}`:       "$.extra is not allowed",
+	} {
+		assert.EqualError(t, validate(document), expected, document)
+	}
+}
+
+func TestValidateJSONSchemaCombinations(t *testing.T) {
+	validate := func(schema string, document string) error {
+		parsed, err := parseJSONSchema(schema)
+		assert.Nil(t, err)
+		var value interface{}
+		assert.Nil(t, json.Unmarshal([]byte(document), &value))
+		return validateJSONSchema(parsed, value, "$")
+	}
+
+	anyOf := `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`
+	assert.Nil(t, validate(anyOf, `"a"`))
+	assert.Nil(t, validate(anyOf, `1`))
+	assert.EqualError(t, validate(anyOf, `true`), "$ matches none of anyOf")
+
+	oneOf := `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`
+	assert.Nil(t, validate(oneOf, `1.5`))
+	assert.EqualError(t, validate(oneOf, `1`), "$ matches 2 of oneOf, expected 1")
+
+	assert.EqualError(t, validate(`{"allOf": [{"minimum": 1}, {"maximum": 5}]}`, `6`), "$ is greater than 5")
+	assert.EqualError(t, validate(`{"not": {"const": "x"}}`, `"x"`), "$ matches not")
+	assert.EqualError(t, validate(`{"items": [{"type": "string"}, {"type": "integer"}]}`, `["a", "b"]`), "$[1] is string, expected integer")
+	assert.EqualError(t, validate(`{"maxProperties": 1}`, `{"a": 1, "b": 2}`), "$ has 2 properties, expected at most 1")
+
+	// Unknown keywords are ignored.
+	assert.Nil(t, validate(`{"type": "string", "format": "email"}`, `"not an email"`))
+}
+
+func TestParseJSONSchema(t *testing.T) {
+	_, err := parseJSONSchema(`true`)
+	assert.Nil(t, err)
+
+	for schema, expected := range map[string]string{
+		`[]`: "invalid JSON schema: a schema is an object or a boolean",
+		`{"properties": {"a": {"$ref": "#/definitions/a"}}}`: "invalid JSON schema: $ref is not supported",
+		`{"items": {"pattern": "("}}`:                        "invalid JSON schema pattern [(]: error parsing regexp: missing closing ): `(`",
+		`{"anyOf": [1]}`:                                     "invalid JSON schema: a schema is an object or a boolean",
+	} {
+		_, err := parseJSONSchema(schema)
+		assert.EqualError(t, err, expected, schema)
+	}
+}
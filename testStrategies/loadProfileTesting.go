@@ -0,0 +1,174 @@
+package testStrategies
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"sync"
+	"time"
+)
+
+// loadProfileTick is how often the user controller adjusts the number of
+// running virtual users to the target of the current stage.
+const loadProfileTick = 100 * time.Millisecond
+
+// loadProfile is the resolved form of Config.LoadProfile: the offset of
+// each stage from the start of the profile, and the level it moves from and
+// to over its duration.
+type loadProfile struct {
+	stages  []perfTestUtils.LoadStage
+	offsets []time.Duration
+	from    []float64
+	to      []float64
+	length  time.Duration
+}
+
+// newLoadProfile resolves the stages of the load profile. Targets are read
+// from the stage Rate for the ArrivalRate executor, and from the stage Users
+// otherwise. The profile starts from zero.
+func newLoadProfile(configurationSettings *perfTestUtils.Config) *loadProfile {
+	lp := &loadProfile{stages: configurationSettings.LoadProfile}
+	level := 0.0
+	for _, stage := range lp.stages {
+		target := float64(stage.Users)
+		if configurationSettings.Executor == perfTestUtils.ArrivalRateExecutor {
+			target = float64(stage.Rate)
+		}
+
+		from, to := level, target
+		switch stage.Shape {
+		case perfTestUtils.LoadStageRamp:
+			level = target
+		case perfTestUtils.LoadStageStep:
+			from = target
+			level = target
+		case perfTestUtils.LoadStageHold:
+			if target == 0 {
+				target = level
+			}
+			from, to = target, target
+			level = target
+		case perfTestUtils.LoadStageSpike:
+			// Return to the current level once the spike is over.
+			from = target
+		}
+
+		lp.offsets = append(lp.offsets, lp.length)
+		lp.from = append(lp.from, from)
+		lp.to = append(lp.to, to)
+		lp.length += stage.StageDuration()
+	}
+	return lp
+}
+
+// at returns the target level and the index of the stage at the given time
+// since the start of the profile, or false once the profile has ended.
+func (lp *loadProfile) at(elapsed time.Duration) (float64, int, bool) {
+	for i := range lp.stages {
+		stageDuration := lp.stages[i].StageDuration()
+		if elapsed < lp.offsets[i]+stageDuration {
+			progress := float64(elapsed-lp.offsets[i]) / float64(stageDuration)
+			return lp.from[i] + (lp.to[i]-lp.from[i])*progress, i, true
+		}
+	}
+	return 0, len(lp.stages), false
+}
+
+// stageStarts returns the wall-clock start time of each stage for a profile
+// started at profileStart.
+func (lp *loadProfile) stageStarts(profileStart time.Time) []perfTestUtils.LoadStageStart {
+	starts := make([]perfTestUtils.LoadStageStart, 0, len(lp.stages))
+	for i, stage := range lp.stages {
+		name := stage.Name
+		if name == "" {
+			name = stage.String()
+		}
+		starts = append(starts, perfTestUtils.LoadStageStart{Name: name, Time: profileStart.Add(lp.offsets[i])})
+	}
+	return starts
+}
+
+// arrivalSchedule returns a schedule for the ArrivalRate executor whose rate
+// follows the profile. Stages with a target rate of zero start no iterations.
+func (lp *loadProfile) arrivalSchedule(profileStart time.Time) arrivalSchedule {
+	next := profileStart
+	return func(iteration int) (scheduledArrival, bool) {
+		for {
+			rate, _, ok := lp.at(next.Sub(profileStart))
+			if !ok {
+				return scheduledArrival{}, false
+			}
+			if rate < 0.1 {
+				next = next.Add(loadProfileTick)
+				continue
+			}
+			interval := time.Duration(float64(time.Second) / rate)
+			arrival := scheduledArrival{iteration: iteration, scheduled: next, interval: interval}
+			next = next.Add(interval)
+			return arrival, true
+		}
+	}
+}
+
+//----- executeLoadProfileUsers -----------------------------------------------
+// Closed model executor following the load profile. A controller starts and
+// stops virtual users to match the target number of users of the current
+// stage. Users that are stopped during a ramp down finish their iteration in
+// progress first, as do all users when the test run is stopped. Every
+// iteration is passed to runIteration, paced by a pacer the user gets from
+// newUserPacer as it starts. runIteration returns the response times of the
+// iteration, and a user stops for good when it returns nil.
+func executeLoadProfileUsers(lp *loadProfile, profileStart time.Time, newUserPacer func(userID int) *pacer, runIteration func(userID int, iteration int) map[string][]int64) {
+	var userWaitGroup sync.WaitGroup
+	activeUsers := make([]chan bool, 0)
+	nextUserID := 0
+	currentStage := -1
+
+	for {
+		target, stage, ok := lp.at(time.Since(profileStart))
+		if !ok || isTestRunStopped() {
+			break
+		}
+		if stage != currentStage {
+			currentStage = stage
+			log.Infof("Load profile stage [%d] started: %s", stage, lp.stages[stage].String())
+		}
+
+		// Add users to reach the target ...
+		for len(activeUsers) < int(target+0.5) {
+			quit := make(chan bool)
+			activeUsers = append(activeUsers, quit)
+			userWaitGroup.Add(1)
+			go func(userID int, quit chan bool) {
+				defer userWaitGroup.Done()
+				pacer := newUserPacer(userID)
+				for i := 0; ; i++ {
+					select {
+					case <-quit:
+						return
+					default:
+						pacer.iterationStarted(time.Now())
+						responseTimes := runIteration(userID, i)
+						if responseTimes == nil {
+							return
+						}
+						pacer.iterationDone(responseTimes)
+						pacer.waitForWindow(quit)
+					}
+				}
+			}(nextUserID, quit)
+			nextUserID++
+		}
+		// ... or stop the most recently started users.
+		for len(activeUsers) > int(target+0.5) {
+			close(activeUsers[len(activeUsers)-1])
+			activeUsers = activeUsers[:len(activeUsers)-1]
+		}
+
+		time.Sleep(loadProfileTick)
+	}
+
+	for _, quit := range activeUsers {
+		close(quit)
+	}
+	waitForUsers(&userWaitGroup)
+}
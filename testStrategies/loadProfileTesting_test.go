@@ -0,0 +1,106 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLoadProfile(executor string, stages ...perfTestUtils.LoadStage) *loadProfile {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.Executor = executor
+	config.LoadProfile = stages
+	config.PrintAndValidateConfig()
+	return newLoadProfile(config)
+}
+
+func TestLoadProfileAt(t *testing.T) {
+	lp := newTestLoadProfile(perfTestUtils.ClosedModelExecutor,
+		perfTestUtils.LoadStage{Shape: "ramp", Users: 10, Duration: "10s"},
+		perfTestUtils.LoadStage{Shape: "hold", Duration: "10s"},
+		perfTestUtils.LoadStage{Shape: "spike", Users: 50, Duration: "5s"},
+		perfTestUtils.LoadStage{Shape: "step", Users: 20, Duration: "5s"},
+		perfTestUtils.LoadStage{Shape: "ramp", Users: 0, Duration: "10s"},
+	)
+
+	level, stage, ok := lp.at(5 * time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 0, stage)
+	assert.InDelta(t, 5, level, 0.01)
+
+	level, stage, _ = lp.at(15 * time.Second)
+	assert.Equal(t, 1, stage)
+	assert.InDelta(t, 10, level, 0.01)
+
+	level, stage, _ = lp.at(22 * time.Second)
+	assert.Equal(t, 2, stage)
+	assert.InDelta(t, 50, level, 0.01)
+
+	level, stage, _ = lp.at(27 * time.Second)
+	assert.Equal(t, 3, stage)
+	assert.InDelta(t, 20, level, 0.01)
+
+	// Ramp down from the step level, not the spike level.
+	level, stage, _ = lp.at(35 * time.Second)
+	assert.Equal(t, 4, stage)
+	assert.InDelta(t, 10, level, 0.01)
+
+	_, _, ok = lp.at(40 * time.Second)
+	assert.False(t, ok)
+}
+
+func TestLoadProfileUsesRateForArrivalRate(t *testing.T) {
+	lp := newTestLoadProfile(perfTestUtils.ArrivalRateExecutor,
+		perfTestUtils.LoadStage{Shape: "step", Users: 5, Rate: 200, Duration: "1s"},
+	)
+	level, _, _ := lp.at(0)
+	assert.InDelta(t, 200, level, 0.01)
+
+	schedule := lp.arrivalSchedule(time.Now())
+	count := 0
+	for i := 0; ; i++ {
+		if _, ok := schedule(i); !ok {
+			break
+		}
+		count++
+	}
+	assert.Equal(t, 200, count)
+}
+
+func TestLoadProfileStageStarts(t *testing.T) {
+	lp := newTestLoadProfile(perfTestUtils.ClosedModelExecutor,
+		perfTestUtils.LoadStage{Name: "warmup", Shape: "ramp", Users: 10, Duration: "1m"},
+		perfTestUtils.LoadStage{Shape: "hold", Duration: "2m"},
+	)
+	start := time.Now()
+	starts := lp.stageStarts(start)
+	assert.Equal(t, 2, len(starts))
+	assert.Equal(t, "warmup", starts[0].Name)
+	assert.Equal(t, start, starts[0].Time)
+	assert.Equal(t, "hold at 10 users for 2m", starts[1].Name)
+	assert.Equal(t, start.Add(time.Minute), starts[1].Time)
+}
+
+func TestExecuteLoadProfileUsers(t *testing.T) {
+	lp := newTestLoadProfile(perfTestUtils.ClosedModelExecutor,
+		perfTestUtils.LoadStage{Shape: "step", Users: 3, Duration: "300ms"},
+		perfTestUtils.LoadStage{Shape: "step", Users: 1, Duration: "300ms"},
+	)
+
+	var m sync.Mutex
+	users := make(map[int]bool)
+	start := time.Now()
+	executeLoadProfileUsers(lp, start, func(userID int) *pacer { return nil }, func(userID int, iteration int) map[string][]int64 {
+		m.Lock()
+		users[userID] = true
+		m.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		return map[string][]int64{}
+	})
+
+	assert.Equal(t, 3, len(users))
+	assert.True(t, time.Since(start) >= 600*time.Millisecond)
+}
@@ -0,0 +1,110 @@
+package testStrategies
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"sync/atomic"
+	"time"
+)
+
+// pacingThinkTimeName is the name the waits of the pacers are recorded
+// under, as think time.
+const pacingThinkTimeName = "pacing"
+
+// pacer spaces out the iterations of a single virtual user according to the
+// pacing of the configuration. A nil pacer does not pace.
+type pacer struct {
+	mode             string
+	period           time.Duration
+	perfStatsForTest *perfTestUtils.PerfStats
+	deadline         time.Time
+
+	// windowEnd is the end of the pacing window of the iteration in
+	// progress, and startDelay how long after the start of its window on
+	// the schedule the iteration started.
+	windowEnd  time.Time
+	startDelay time.Duration
+}
+
+// newPacer returns the pacer of a virtual user, or nil if iterations are not
+// paced. No wait lasts beyond the deadline of a duration based run.
+func newPacer(configurationSettings *perfTestUtils.Config, perfStatsForTest *perfTestUtils.PerfStats, deadline time.Time) *pacer {
+	period := configurationSettings.Pacing.PeriodDuration()
+	if period == 0 {
+		return nil
+	}
+	return &pacer{
+		mode:             configurationSettings.Pacing.Mode,
+		period:           period,
+		perfStatsForTest: perfStatsForTest,
+		deadline:         deadline,
+	}
+}
+
+// iterationStarted opens the pacing window of the iteration starting at
+// start. Interval windows follow each other from the start of the first
+// iteration, so an iteration that starts late has less time to complete.
+// After an overrun that runs past the next window as well, the windows
+// start again from the late iteration, rather than leaving the user to
+// catch up on the windows it missed.
+func (p *pacer) iterationStarted(start time.Time) {
+	if p == nil {
+		return
+	}
+	p.startDelay = 0
+	if p.mode != perfTestUtils.PacingMinimum && !p.windowEnd.IsZero() {
+		if start.After(p.windowEnd) {
+			p.startDelay = start.Sub(p.windowEnd)
+		}
+		p.windowEnd = p.windowEnd.Add(p.period)
+		if p.windowEnd.After(start) {
+			return
+		}
+	}
+	p.windowEnd = start.Add(p.period)
+}
+
+// iterationDone counts the iteration, flagging it if it overran its pacing
+// window. Iterations that follow an interval schedule also record their
+// response times corrected for the delay of their start, as the ArrivalRate
+// executor does.
+func (p *pacer) iterationDone(responseTimes map[string][]int64) {
+	if p == nil {
+		return
+	}
+	if p.mode != perfTestUtils.PacingMinimum {
+		recordCorrectedResponseTimes(p.perfStatsForTest, responseTimes, p.startDelay)
+	}
+	atomic.AddUint64(&p.perfStatsForTest.PacedIterations, 1)
+	if overrun := time.Since(p.windowEnd); overrun > 0 {
+		atomic.AddUint64(&p.perfStatsForTest.OverrunIterations, 1)
+		log.Debugf("Iteration overran its pacing window of [%v] by [%v].", p.period, overrun)
+	}
+}
+
+// waitForWindow waits until the end of the pacing window of the last
+// iteration, the end of the run, or until quit is closed, and records the
+// wait as think time. After an overrun the next iteration starts at once.
+func (p *pacer) waitForWindow(quit <-chan bool) {
+	if p == nil {
+		return
+	}
+	windowEnd := p.windowEnd
+	if !p.deadline.IsZero() && windowEnd.After(p.deadline) {
+		windowEnd = p.deadline
+	}
+	wait := windowEnd.Sub(time.Now())
+	if wait <= 0 {
+		return
+	}
+
+	waitStart := time.Now()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-testRunStopped():
+	case <-quit:
+	}
+	recordThinkTime(p.perfStatsForTest, pacingThinkTimeName, time.Since(waitStart))
+}
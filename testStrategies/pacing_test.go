@@ -0,0 +1,186 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// pacedConfig returns a configuration with the given pacing.
+func pacedConfig(mode string, period string) *perfTestUtils.Config {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.Pacing = perfTestUtils.Pacing{Mode: mode, Period: period}
+	return config
+}
+
+func TestNewPacer(t *testing.T) {
+	assert.Nil(t, newPacer(pacedConfig("", ""), &perfTestUtils.PerfStats{}, time.Time{}))
+
+	// A nil pacer does not pace.
+	var p *pacer
+	p.iterationStarted(time.Now())
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+}
+
+func TestPacerMinimum(t *testing.T) {
+	perfStats := &perfTestUtils.PerfStats{}
+	p := newPacer(pacedConfig(perfTestUtils.PacingMinimum, "100ms"), perfStats, time.Time{})
+
+	start := time.Now()
+	p.iterationStarted(start)
+	time.Sleep(20 * time.Millisecond)
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+	assert.Equal(t, uint64(0), perfStats.OverrunIterations)
+
+	// An iteration that overruns is flagged, and the next one starts at once.
+	start = time.Now()
+	p.iterationStarted(start)
+	time.Sleep(120 * time.Millisecond)
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+	assert.True(t, time.Since(start) < 150*time.Millisecond)
+	assert.Equal(t, uint64(2), perfStats.PacedIterations)
+	assert.Equal(t, uint64(1), perfStats.OverrunIterations)
+	assert.Equal(t, uint64(1), *perfStats.ServiceThinkCount[pacingThinkTimeName])
+	assert.True(t, *perfStats.ServiceThinkTime[pacingThinkTimeName] > uint64(30*time.Millisecond))
+}
+
+func TestPacerIntervalAfterOverrun(t *testing.T) {
+	perfStats := &perfTestUtils.PerfStats{}
+	p := newPacer(pacedConfig(perfTestUtils.PacingInterval, "50ms"), perfStats, time.Time{})
+
+	// One iteration overruns by more than a window, the ones after it are
+	// fast.
+	p.iterationStarted(time.Now())
+	time.Sleep(130 * time.Millisecond)
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+	restart := time.Now()
+	for i := 0; i < 3; i++ {
+		p.iterationStarted(time.Now())
+		time.Sleep(5 * time.Millisecond)
+		p.iterationDone(nil)
+		p.waitForWindow(nil)
+	}
+
+	// The windows start again from the late iteration, so only the slow
+	// iteration overran, and the fast ones are still paced.
+	assert.Equal(t, uint64(4), perfStats.PacedIterations)
+	assert.Equal(t, uint64(1), perfStats.OverrunIterations)
+	assert.True(t, time.Since(restart) >= 150*time.Millisecond)
+}
+
+func TestPacerInterval(t *testing.T) {
+	perfStats := &perfTestUtils.PerfStats{}
+	p := newPacer(pacedConfig(perfTestUtils.PacingInterval, "100ms"), perfStats, time.Time{})
+
+	start := time.Now()
+	p.iterationStarted(start)
+	time.Sleep(120 * time.Millisecond)
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+	assert.Equal(t, uint64(1), perfStats.OverrunIterations)
+
+	// The next iteration starts late, but its window still ends on the
+	// schedule, and the user waits out the rest of it.
+	p.iterationStarted(time.Now())
+	time.Sleep(20 * time.Millisecond)
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 200*time.Millisecond)
+	assert.True(t, elapsed < 250*time.Millisecond)
+	assert.Equal(t, uint64(2), perfStats.PacedIterations)
+	assert.Equal(t, uint64(1), perfStats.OverrunIterations)
+}
+
+func TestPacerCorrectedResponseTimes(t *testing.T) {
+	responseTimes := map[string][]int64{"search": {int64(10 * time.Millisecond)}, "checkout": {0}}
+
+	// Interval pacing records the response times from the start of the
+	// window of each iteration, failed requests excepted.
+	perfStats := &perfTestUtils.PerfStats{}
+	p := newPacer(pacedConfig(perfTestUtils.PacingInterval, "100ms"), perfStats, time.Time{})
+	start := time.Now()
+	p.iterationStarted(start)
+	p.iterationDone(responseTimes)
+	p.iterationStarted(start.Add(130 * time.Millisecond))
+	p.iterationDone(responseTimes)
+	assert.Equal(t, []int64{int64(10 * time.Millisecond), int64(40 * time.Millisecond)}, perfStats.ServiceCorrectedSamples["search"])
+	assert.Nil(t, perfStats.ServiceCorrectedSamples["checkout"])
+
+	// Minimum pacing follows no schedule.
+	perfStats = &perfTestUtils.PerfStats{}
+	p = newPacer(pacedConfig(perfTestUtils.PacingMinimum, "100ms"), perfStats, time.Time{})
+	p.iterationStarted(start)
+	p.iterationDone(responseTimes)
+	assert.Nil(t, perfStats.ServiceCorrectedSamples)
+}
+
+func TestPacerWaitEnds(t *testing.T) {
+	defer resetTestRun()
+	perfStats := &perfTestUtils.PerfStats{}
+
+	// The wait ends at the deadline of the run ...
+	start := time.Now()
+	p := newPacer(pacedConfig(perfTestUtils.PacingMinimum, "10s"), perfStats, start.Add(50*time.Millisecond))
+	p.iterationStarted(start)
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// ... when the user is stopped ...
+	quit := make(chan bool)
+	time.AfterFunc(50*time.Millisecond, func() { close(quit) })
+	start = time.Now()
+	p = newPacer(pacedConfig(perfTestUtils.PacingMinimum, "10s"), perfStats, time.Time{})
+	p.iterationStarted(start)
+	p.iterationDone(nil)
+	p.waitForWindow(quit)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// ... or when the test run is stopped.
+	time.AfterFunc(50*time.Millisecond, func() { StopTestRun("interrupted", 0) })
+	start = time.Now()
+	p.iterationStarted(start)
+	p.iterationDone(nil)
+	p.waitForWindow(nil)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestExecuteTestSuiteWrapperPaced(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := pacedConfig(perfTestUtils.PacingInterval, "100ms")
+	config.TargetHost = host
+	config.TargetPort = port
+	config.ConcurrentUsers = 2
+	config.NumIterations = 3
+	testSuite := &TestSuite{
+		TestDefinitions: []*TestDefinition{{TestName: "ping", HTTPMethod: "GET", BaseURI: "/ping", ResponseStatusCode: 200}},
+	}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	start := time.Now()
+	responseTimes := ExecuteTestSuiteWrapper(testSuite, config, perfStats, start)
+
+	// Each user waits out the window of every iteration but the last.
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+	assert.True(t, time.Since(start) < 300*time.Millisecond)
+	assert.Equal(t, 6, len(responseTimes["ping"]))
+	assert.Equal(t, uint64(6), perfStats.PacedIterations)
+	assert.Equal(t, uint64(0), perfStats.OverrunIterations)
+}
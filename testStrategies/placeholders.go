@@ -0,0 +1,259 @@
+package testStrategies
+
+import (
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// builtinPlaceholder matches the {{$name}} and {{$name(args)}} placeholders
+// of the built-in values, which are generated for every request rather than
+// extracted from earlier responses.
+var builtinPlaceholder = regexp.MustCompile(`{{\$(\w+)(?:\(([^)]*)\))?}}`)
+
+// randomStringChars are the characters of a {{$randomString}}.
+const randomStringChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// defaultRandomStringLength is the length of a {{$randomString}} without one.
+const defaultRandomStringLength = 10
+
+// builtinValues generate the values of the built-in placeholders from their
+// arguments and the iteration they are used in.
+var builtinValues = map[string]func(args []string, uniqueTestRunID string) (string, error){
+	"uuid":         builtinUUID,
+	"randomInt":    builtinRandomInt,
+	"randomString": builtinRandomString,
+	"timestamp": func(args []string, uniqueTestRunID string) (string, error) {
+		t, err := offsetTime(args)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	},
+	"isoDate": func(args []string, uniqueTestRunID string) (string, error) {
+		t, err := offsetTime(args)
+		if err != nil {
+			return "", err
+		}
+		return t.UTC().Format("2006-01-02"), nil
+	},
+	"isoDateTime": func(args []string, uniqueTestRunID string) (string, error) {
+		t, err := offsetTime(args)
+		if err != nil {
+			return "", err
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	},
+	"env": func(args []string, uniqueTestRunID string) (string, error) {
+		if len(args) != 1 || args[0] == "" {
+			return "", fmt.Errorf("expected the name of an environment variable")
+		}
+		return os.Getenv(args[0]), nil
+	},
+	"userId": func(args []string, uniqueTestRunID string) (string, error) {
+		return strconv.Itoa(runUserOf(uniqueTestRunID).userID), noArgs(args)
+	},
+	"iteration": func(args []string, uniqueTestRunID string) (string, error) {
+		return strconv.Itoa(runUserOf(uniqueTestRunID).iteration), noArgs(args)
+	},
+}
+
+// runUser is the virtual user and iteration a unique run ID belongs to.
+type runUser struct {
+	userID    int
+	iteration int
+}
+
+// runUsers holds the virtual user and iteration of the suite iterations, and
+// user setups and teardowns, in progress by their unique run ID, for the
+// {{$userId}} and {{$iteration}} placeholders.
+var runUsers = make(map[string]runUser)
+
+// startRunUser records the virtual user and iteration of a run ID until
+// endRunUser is called.
+func startRunUser(uniqueTestRunID string, userID int, iteration int) {
+	mu.Lock()
+	defer mu.Unlock()
+	runUsers[uniqueTestRunID] = runUser{userID: userID, iteration: iteration}
+}
+
+func endRunUser(uniqueTestRunID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(runUsers, uniqueTestRunID)
+}
+
+// runUserOf returns the virtual user and iteration of a run ID, or zeros
+// outside of a suite iteration or user phase.
+func runUserOf(uniqueTestRunID string) runUser {
+	mu.Lock()
+	defer mu.Unlock()
+	return runUsers[uniqueTestRunID]
+}
+
+// substituteBuiltinValues replaces the built-in placeholders of s with their
+// values. Every placeholder gets a value of its own, so two {{$uuid}} of the
+// same request differ. Placeholders that are not valid are left as they are.
+func substituteBuiltinValues(s string, uniqueTestRunID string) string {
+	if !strings.Contains(s, "{{$") {
+		return s
+	}
+	return builtinPlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		value, err := builtinValue(placeholder, uniqueTestRunID)
+		if err != nil {
+			return placeholder
+		}
+		return value
+	})
+}
+
+// builtinValue returns the value of a built-in placeholder.
+func builtinValue(placeholder string, uniqueTestRunID string) (string, error) {
+	match := builtinPlaceholder.FindStringSubmatch(placeholder)
+	generate := builtinValues[match[1]]
+	if generate == nil {
+		return "", fmt.Errorf("unknown placeholder %s", placeholder)
+	}
+	var args []string
+	if strings.TrimSpace(match[2]) != "" {
+		args = strings.Split(match[2], ",")
+		for i := range args {
+			args[i] = strings.TrimSpace(args[i])
+		}
+	}
+	value, err := generate(args, uniqueTestRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid placeholder %s: %v", placeholder, err)
+	}
+	return value, nil
+}
+
+// validateBuiltinPlaceholders returns an error for the first built-in
+// placeholder of s that is unknown or has invalid arguments.
+func validateBuiltinPlaceholders(s string) error {
+	for _, placeholder := range builtinPlaceholder.FindAllString(s, -1) {
+		if _, err := builtinValue(placeholder, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("expected no arguments")
+	}
+	return nil
+}
+
+// builtinUUID returns a random (version 4) UUID.
+func builtinUUID(args []string, uniqueTestRunID string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), noArgs(args)
+}
+
+// builtinRandomInt returns a random integer between its two arguments,
+// inclusive.
+func builtinRandomInt(args []string, uniqueTestRunID string) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("expected a minimum and a maximum")
+	}
+	min, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	max, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	if max < min {
+		return "", fmt.Errorf("maximum %d is less than minimum %d", max, min)
+	}
+	span := max - min + 1
+	if span <= 0 {
+		return "", fmt.Errorf("range from %d to %d is too wide", min, max)
+	}
+	return strconv.FormatInt(min+mathrand.Int63n(span), 10), nil
+}
+
+// builtinRandomString returns a random string of letters and digits, of the
+// length of its argument.
+func builtinRandomString(args []string, uniqueTestRunID string) (string, error) {
+	length := defaultRandomStringLength
+	if len(args) > 1 {
+		return "", fmt.Errorf("expected a length")
+	}
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid length [%s]", args[0])
+		}
+		length = n
+	}
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = randomStringChars[mathrand.Intn(len(randomStringChars))]
+	}
+	return string(b), nil
+}
+
+// offsetTime returns the current time, moved by the offset of the optional
+// argument: a duration such as "-90m", or a number of days such as "+1d".
+func offsetTime(args []string) (time.Time, error) {
+	if len(args) > 1 {
+		return time.Time{}, fmt.Errorf("expected an offset")
+	}
+	now := time.Now()
+	if len(args) == 0 {
+		return now, nil
+	}
+	offset := strings.TrimPrefix(args[0], "+")
+	if strings.HasSuffix(offset, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(offset, "d"))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid offset [%s]", args[0])
+		}
+		return now.AddDate(0, 0, days), nil
+	}
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid offset [%s]", args[0])
+	}
+	return now.Add(d), nil
+}
+
+// validatePlaceholders checks the built-in placeholders of the parts of the
+// request that are substituted.
+func (testDefinition *TestDefinition) validatePlaceholders() error {
+	values := []string{testDefinition.BaseURI, testDefinition.Payload}
+	for _, header := range testDefinition.Headers {
+		values = append(values, header.Value)
+	}
+	for _, field := range testDefinition.MultipartPayload {
+		values = append(values, field.FieldValue)
+	}
+	if testDefinition.GraphQL != nil {
+		values = append(values, testDefinition.GraphQL.Variables)
+	}
+	if testDefinition.WebSocket != nil {
+		for _, message := range testDefinition.WebSocket.Messages {
+			values = append(values, message.Send)
+		}
+	}
+	for _, value := range values {
+		if err := validateBuiltinPlaceholders(value); err != nil {
+			return fmt.Errorf("test case [%s]: %v", testDefinition.TestName, err)
+		}
+	}
+	return nil
+}
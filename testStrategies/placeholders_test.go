@@ -0,0 +1,100 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSubstituteBuiltinValues(t *testing.T) {
+	os.Setenv("PERF_TEST_TENANT", "acme")
+	defer os.Unsetenv("PERF_TEST_TENANT")
+
+	startRunUser("User3Iter14", 3, 14)
+	defer endRunUser("User3Iter14")
+	s := substituteBuiltinValues(`{"id":"{{$uuid}}","other":"{{$uuid}}","tenant":"{{$env(PERF_TEST_TENANT)}}","user":{{$userId}},"iter":{{$iteration}}}`, "User3Iter14")
+	match := regexp.MustCompile(`^{"id":"([0-9a-f-]{36})","other":"([0-9a-f-]{36})","tenant":"acme","user":3,"iter":14}$`).FindStringSubmatch(s)
+	assert.Equal(t, 3, len(match), s)
+	assert.NotEqual(t, match[1], match[2])
+	assert.Equal(t, "4", match[1][14:15])
+
+	for i := 0; i < 100; i++ {
+		n, err := strconv.Atoi(substituteBuiltinValues("{{$randomInt(-2, 2)}}", ""))
+		assert.Nil(t, err)
+		assert.True(t, n >= -2 && n <= 2)
+	}
+	assert.Regexp(t, "^[a-zA-Z0-9]{12}$", substituteBuiltinValues("{{$randomString(12)}}", ""))
+	assert.Equal(t, defaultRandomStringLength, len(substituteBuiltinValues("{{$randomString}}", "")))
+
+	// Outside of a suite iteration the user and iteration are 0, whatever the
+	// run ID reads.
+	assert.Equal(t, "0/0", substituteBuiltinValues("{{$userId}}/{{$iteration}}", ""))
+	assert.Equal(t, "0/0", substituteBuiltinValues("{{$userId}}/{{$iteration}}", "User5Iter6"))
+
+	// Invalid placeholders and extracted values are left as they are.
+	assert.Equal(t, "{{$randomInt(5,1)}} {{$unknown}} {{login.token}}", substituteBuiltinValues("{{$randomInt(5,1)}} {{$unknown}} {{login.token}}", ""))
+}
+
+func TestBuiltinDates(t *testing.T) {
+	now := time.Now()
+	timestamp, err := strconv.ParseInt(substituteBuiltinValues("{{$timestamp}}", ""), 10, 64)
+	assert.Nil(t, err)
+	assert.True(t, timestamp >= now.Unix() && timestamp <= now.Unix()+1)
+	timestamp, _ = strconv.ParseInt(substituteBuiltinValues("{{$timestamp(-1h)}}", ""), 10, 64)
+	assert.True(t, timestamp >= now.Unix()-3600 && timestamp <= now.Unix()-3599)
+
+	tomorrow := now.AddDate(0, 0, 1).UTC().Format("2006-01-02")
+	assert.Equal(t, tomorrow, substituteBuiltinValues("{{$isoDate(+1d)}}", ""))
+	isoDateTime, err := time.Parse(time.RFC3339, substituteBuiltinValues("{{$isoDateTime(90m)}}", ""))
+	assert.Nil(t, err)
+	assert.True(t, isoDateTime.Sub(now) > 89*time.Minute && isoDateTime.Sub(now) < 91*time.Minute)
+}
+
+func TestValidatePlaceholders(t *testing.T) {
+	td := &TestDefinition{
+		TestName: "createOrder",
+		BaseURI:  "/orders/{{$uuid}}?date={{$isoDate(-7d)}}",
+		Payload:  `{"ref":"{{$randomString(8)}}","qty":{{$randomInt(1,100)}},"token":"{{login.token}}"}`,
+		Headers:  []Header{{Key: "X-Request-Id", Value: "{{$uuid}}"}},
+	}
+	assert.Nil(t, td.validatePlaceholders())
+
+	for _, invalid := range []string{"{{$uuid(1)}}", "{{$randomInt(1)}}", "{{$randomInt(a,b)}}", "{{$randomInt(-9223372036854775808,9223372036854775807)}}", "{{$randomInt(0,9223372036854775807)}}", "{{$randomString(0)}}", "{{$isoDate(tomorrow)}}", "{{$env()}}", "{{$now}}"} {
+		td.Headers[0].Value = invalid
+		assert.NotNil(t, td.validatePlaceholders(), invalid)
+	}
+
+	_, err := loadTestDefinition([]byte(`<testDefinition><testName>a</testName><baseUri>/a/{{$random}}</baseUri></testDefinition>`))
+	assert.NotNil(t, err)
+}
+
+func TestSubstituteRequestValuesBuiltins(t *testing.T) {
+	mu.Lock()
+	globalsMap["User901Iter2"] = map[string]interface{}{"login.token": "abc"}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		globalsMap["User901Iter2"] = nil
+		mu.Unlock()
+	}()
+	for _, run := range []runUser{{901, 2}, {7, 0}, {7, 2}, {7, 3}} {
+		id := "User" + strconv.Itoa(run.userID) + "Iter" + strconv.Itoa(run.iteration)
+		startRunUser(id, run.userID, run.iteration)
+		defer endRunUser(id)
+	}
+
+	s := "/users/{{$userId}}/iterations/{{$iteration}}?token={{login.token}}"
+	assert.Equal(t, "/users/901/iterations/2?token=abc", substituteRequestValues(&s, "User901Iter2"))
+
+	// Built-in values do not need extracted values.
+	s = "/users/{{$userId}}"
+	assert.Equal(t, "/users/7", substituteRequestValues(&s, "User7Iter0"))
+
+	// They can be compared in conditions.
+	condition, _ := parseCondition("{{$iteration}} < 3")
+	assert.True(t, condition.holds("User7Iter2"))
+	assert.False(t, condition.holds("User7Iter3"))
+}
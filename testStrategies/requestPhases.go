@@ -0,0 +1,96 @@
+package testStrategies
+
+import (
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// phaseTrace records when the phases of a single request attempt start and
+// end, using net/http/httptrace. The hooks may be called from the goroutines
+// of the transport, hence the mutex. The TLS handshake of an https request
+// runs from the TCP connection being made to the connection being ready.
+type phaseTrace struct {
+	mu           sync.Mutex
+	https        bool
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	gotConn      time.Time
+	firstByte    time.Time
+}
+
+// traceRequest returns a copy of the request that records its phases in the
+// returned trace.
+func traceRequest(req *http.Request) (*http.Request, *phaseTrace) {
+	pt := &phaseTrace{https: req.URL.Scheme == "https"}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { pt.mark(&pt.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { pt.mark(&pt.dnsDone) },
+		ConnectStart: func(network string, addr string) {
+			pt.mark(&pt.connectStart)
+		},
+		ConnectDone: func(network string, addr string, err error) {
+			if err == nil {
+				pt.mark(&pt.connectDone)
+			}
+		},
+		GotConn:              func(httptrace.GotConnInfo) { pt.mark(&pt.gotConn) },
+		GotFirstResponseByte: func() { pt.mark(&pt.firstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), pt
+}
+
+// mark sets t to the current time, unless already set. With several
+// addresses to dial, the first connection counts.
+func (pt *phaseTrace) mark(t *time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+// phaseTimes returns the phase times of the request, whose body was read by
+// end. A phase that did not complete counts as zero.
+func (pt *phaseTrace) phaseTimes(end time.Time) *perfTestUtils.PhaseTimes {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	between := func(from time.Time, to time.Time) uint64 {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return 0
+		}
+		return uint64(to.Sub(from))
+	}
+	var tlsTime uint64
+	if pt.https {
+		tlsTime = between(pt.connectDone, pt.gotConn)
+	}
+	return &perfTestUtils.PhaseTimes{
+		Count:    1,
+		DNS:      between(pt.dnsStart, pt.dnsDone),
+		Connect:  between(pt.connectStart, pt.connectDone),
+		TLS:      tlsTime,
+		TTFB:     between(pt.gotConn, pt.firstByte),
+		Download: between(pt.firstByte, end),
+	}
+}
+
+// recordPhaseTimes adds the phase times of a request of the given service
+// to its totals.
+func recordPhaseTimes(perfStatsForTest *perfTestUtils.PerfStats, testName string, phaseTimes *perfTestUtils.PhaseTimes) {
+	mu.Lock()
+	if perfStatsForTest.ServicePhaseTimes == nil {
+		perfStatsForTest.ServicePhaseTimes = make(map[string]*perfTestUtils.PhaseTimes)
+	}
+	servicePhaseTimes := perfStatsForTest.ServicePhaseTimes[testName]
+	if servicePhaseTimes == nil {
+		servicePhaseTimes = new(perfTestUtils.PhaseTimes)
+		perfStatsForTest.ServicePhaseTimes[testName] = servicePhaseTimes
+	}
+	mu.Unlock()
+	servicePhaseTimes.Add(phaseTimes)
+}
@@ -0,0 +1,75 @@
+package testStrategies
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowServer waits before sending the response headers, and again before
+// the rest of the body.
+func slowServer(wait time.Duration) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(wait)
+		w.Write([]byte("first part,"))
+		w.(http.Flusher).Flush()
+		time.Sleep(wait)
+		w.Write([]byte("second part"))
+	}))
+}
+
+func TestSendAttemptPhaseTimes(t *testing.T) {
+	server := slowServer(30 * time.Millisecond)
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	req, _ := http.NewRequest("GET", server.URL+"/slow", nil)
+	resp, body, timeTaken, phaseTimes, err := sendAttempt(client, req)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "first part,second part", string(body))
+	assert.Equal(t, uint64(1), phaseTimes.Count)
+	// The server is addressed by IP, so there is no DNS lookup.
+	assert.Equal(t, uint64(0), phaseTimes.DNS)
+	assert.True(t, phaseTimes.Connect > 0)
+	assert.True(t, phaseTimes.TLS > 0)
+	assert.True(t, phaseTimes.TTFB >= uint64(30*time.Millisecond))
+	assert.True(t, phaseTimes.Download >= uint64(30*time.Millisecond))
+	// The response time stops at the response headers.
+	assert.True(t, timeTaken < 60*time.Millisecond)
+
+	// A reused connection skips the connection phases.
+	req, _ = http.NewRequest("GET", server.URL+"/slow", nil)
+	_, _, _, phaseTimes, err = sendAttempt(client, req)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), phaseTimes.Connect)
+	assert.Equal(t, uint64(0), phaseTimes.TLS)
+	assert.True(t, phaseTimes.TTFB >= uint64(30*time.Millisecond))
+}
+
+func TestBuildAndSendRequestPhaseTimes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{TestName: "search", HTTPMethod: "GET", BaseURI: "/search", ResponseStatusCode: 200}
+	perfStats := &perfTestUtils.PerfStats{}
+
+	_, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.Nil(t, err)
+	_, err = testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), perfStats.ServicePhaseTimes["search"].Count)
+
+	// Requests that get no response have no phase times.
+	server.Close()
+	_, err = testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(2), perfStats.ServicePhaseTimes["search"].Count)
+}
@@ -0,0 +1,164 @@
+package testStrategies
+
+import (
+	"bytes"
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RetryTimingLast and RetryTimingAll are the valid values of
+// RetryPolicy.Timing. The response time of a retried request is that of the
+// last attempt, or the time from the start of the first attempt to the
+// response of the last one, backoff included.
+const (
+	RetryTimingLast = "last"
+	RetryTimingAll  = "all"
+)
+
+// defaultRetryStatusCodes are retried when a policy lists no status codes.
+var defaultRetryStatusCodes = []int{502, 503, 504}
+
+// RetryPolicy controls how a failed request is retried. It is set by a
+// <retry> element in a test definition, or in a test suite as the default
+// for the test definitions of the suite that have none.
+type RetryPolicy struct {
+	MaxAttempts      int    `xml:"maxAttempts,attr"`
+	Backoff          string `xml:"backoff,attr"`
+	StatusCodes      string `xml:"statusCodes,attr"`
+	ConnectionErrors bool   `xml:"connectionErrors,attr"`
+	Timing           string `xml:"timing,attr"`
+}
+
+// attempts returns the maximum number of attempts, at least 1.
+func (rp *RetryPolicy) attempts() int {
+	if rp == nil || rp.MaxAttempts < 1 {
+		return 1
+	}
+	return rp.MaxAttempts
+}
+
+// backoff returns the wait before the given retry. The wait doubles with
+// every retry.
+func (rp *RetryPolicy) backoff(retry int) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(rp.Backoff))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d << uint(retry-1)
+}
+
+// retryable returns true if an attempt that ended with the given response
+// or connection error may be retried.
+func (rp *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return rp.ConnectionErrors
+	}
+	statusCodes := defaultRetryStatusCodes
+	if strings.TrimSpace(rp.StatusCodes) != "" {
+		statusCodes = nil
+		for _, code := range strings.Split(rp.StatusCodes, ",") {
+			if statusCode, err := strconv.Atoi(strings.TrimSpace(code)); err == nil {
+				statusCodes = append(statusCodes, statusCode)
+			}
+		}
+	}
+	for _, statusCode := range statusCodes {
+		if resp.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// sendWithRetries sends the request, and retries it while the attempts fail
+// in a way the retry policy allows. It returns the response and body of the
+// last attempt with the response time, or the error of the last attempt.
+// The body of each retry is read again from payload, the body of the request.
+// Retries, and the phase times of the last attempt, are recorded in
+// perfStatsForTest.
+func (testDefinition *TestDefinition) sendWithRetries(
+	client *http.Client,
+	req *http.Request,
+	payload []byte,
+	perfStatsForTest *perfTestUtils.PerfStats,
+) (*http.Response, []byte, time.Duration, error) {
+	policy := testDefinition.Retry
+	firstStart := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && payload != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(payload))
+		}
+
+		startTime := time.Now()
+		resp, body, timeTaken, phaseTimes, err := sendAttempt(client, req)
+		if policy != nil && policy.Timing == RetryTimingAll {
+			timeTaken += startTime.Sub(firstStart)
+		}
+
+		if attempt >= policy.attempts() || !policy.retryable(resp, err) || isTestRunStopped() {
+			if err != nil {
+				log.Errorf("Connection failed for request [Name:%s]: %+v", testDefinition.TestName, err)
+			} else {
+				recordPhaseTimes(perfStatsForTest, testDefinition.TestName, phaseTimes)
+			}
+			return resp, body, timeTaken, err
+		}
+
+		countRetry(perfStatsForTest, testDefinition.TestName)
+		wait := policy.backoff(attempt)
+		if err != nil {
+			log.Warnf("Retrying request [Name:%s] in %v, attempt %d of %d: %v", testDefinition.TestName, wait, attempt+1, policy.attempts(), err)
+		} else {
+			log.Warnf("Retrying request [Name:%s] in %v, attempt %d of %d: status code %d", testDefinition.TestName, wait, attempt+1, policy.attempts(), resp.StatusCode)
+		}
+		select {
+		case <-time.After(wait):
+		case <-testRunStopped():
+		}
+	}
+}
+
+// sendAttempt sends the request once and reads the response. The response
+// time is measured up to the response headers, the same as a single request.
+// The phase times of the attempt cover the whole response, body included.
+func sendAttempt(client *http.Client, req *http.Request) (*http.Response, []byte, time.Duration, *perfTestUtils.PhaseTimes, error) {
+	req, trace := traceRequest(req)
+	startTime := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	// Mark response time.
+	timeTaken := time.Since(startTime)
+	// Gather the response. The request timeout also covers reading the body.
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return resp, body, timeTaken, trace.phaseTimes(time.Now()), nil
+}
+
+// countRetry records a retry of a request of the given service.
+func countRetry(perfStatsForTest *perfTestUtils.PerfStats, testName string) {
+	atomic.AddUint64(&perfStatsForTest.OverAllRetryCount, 1)
+
+	mu.Lock()
+	if perfStatsForTest.ServiceRetryCount == nil {
+		perfStatsForTest.ServiceRetryCount = make(map[string]*uint64)
+	}
+	retryCount := perfStatsForTest.ServiceRetryCount[testName]
+	if retryCount == nil {
+		retryCount = new(uint64)
+		perfStatsForTest.ServiceRetryCount[testName] = retryCount
+	}
+	mu.Unlock()
+	atomic.AddUint64(retryCount, 1)
+}
@@ -0,0 +1,168 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var policy *RetryPolicy
+	assert.Equal(t, 1, policy.attempts())
+
+	policy = &RetryPolicy{MaxAttempts: 3, Backoff: "100ms"}
+	assert.Equal(t, 3, policy.attempts())
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, time.Duration(0), (&RetryPolicy{Backoff: "soon"}).backoff(1))
+
+	assert.True(t, policy.retryable(&http.Response{StatusCode: 503}, nil))
+	assert.False(t, policy.retryable(&http.Response{StatusCode: 500}, nil))
+	assert.False(t, policy.retryable(nil, net.UnknownNetworkError("tcp")))
+
+	policy = &RetryPolicy{StatusCodes: "500, 429", ConnectionErrors: true}
+	assert.True(t, policy.retryable(&http.Response{StatusCode: 429}, nil))
+	assert.False(t, policy.retryable(&http.Response{StatusCode: 503}, nil))
+	assert.True(t, policy.retryable(nil, net.UnknownNetworkError("tcp")))
+}
+
+// flakyServer fails the first failures requests with 503.
+func flakyServer(failures int32) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	return server, &requests
+}
+
+func TestSendWithRetries(t *testing.T) {
+	server, requests := flakyServer(2)
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{TestName: "flaky", HTTPMethod: "POST", BaseURI: "/flaky", Payload: "{}", ResponseStatusCode: 200,
+		Retry: &RetryPolicy{MaxAttempts: 3, Backoff: "50ms"}}
+	perfStats := &perfTestUtils.PerfStats{}
+
+	responseTime, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.Nil(t, err)
+	assert.True(t, responseTime > 0)
+	// The response time is that of the last attempt only.
+	assert.True(t, responseTime < int64(150*time.Millisecond))
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+	assert.Equal(t, uint64(2), perfStats.OverAllRetryCount)
+	assert.Equal(t, uint64(2), *perfStats.ServiceRetryCount["flaky"])
+}
+
+func TestSendWithRetriesResendsBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{TestName: "flaky", HTTPMethod: "POST", BaseURI: "/flaky", Payload: `{"id":1}`, ResponseStatusCode: 200,
+		Retry: &RetryPolicy{MaxAttempts: 3}}
+
+	_, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, &perfTestUtils.PerfStats{}, "http", host, port, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"id":1}`, `{"id":1}`, `{"id":1}`}, bodies)
+}
+
+func TestSendWithRetriesExhausted(t *testing.T) {
+	server, requests := flakyServer(5)
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{TestName: "flaky", HTTPMethod: "GET", BaseURI: "/flaky", ResponseStatusCode: 200,
+		Retry: &RetryPolicy{MaxAttempts: 2}}
+	perfStats := &perfTestUtils.PerfStats{}
+
+	responseTime, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(0), responseTime)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	assert.Equal(t, uint64(1), perfStats.OverAllRetryCount)
+}
+
+func TestSendWithRetriesTimingAll(t *testing.T) {
+	server, _ := flakyServer(1)
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{TestName: "flaky", HTTPMethod: "GET", BaseURI: "/flaky", ResponseStatusCode: 200,
+		Retry: &RetryPolicy{MaxAttempts: 2, Backoff: "50ms", Timing: RetryTimingAll}}
+
+	responseTime, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, &perfTestUtils.PerfStats{}, "http", host, port, "")
+	assert.Nil(t, err)
+	// The backoff is part of the response time.
+	assert.True(t, responseTime >= int64(50*time.Millisecond))
+}
+
+func TestSendWithRetriesConnectionErrors(t *testing.T) {
+	// Nothing listens on the address of a closed server.
+	server := httptest.NewServer(http.NotFoundHandler())
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	server.Close()
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{TestName: "down", HTTPMethod: "GET", BaseURI: "/down", ResponseStatusCode: 200,
+		Retry: &RetryPolicy{MaxAttempts: 3}}
+	perfStats := &perfTestUtils.PerfStats{}
+
+	_, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(0), perfStats.OverAllRetryCount)
+
+	testDefinition.Retry.ConnectionErrors = true
+	_, err = testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(2), perfStats.OverAllRetryCount)
+}
+
+func TestSuiteRetryPolicyIsDefault(t *testing.T) {
+	ts := new(TestSuite)
+	err := ts.loadTestSuiteDefinition([]byte(`<testSuite>
+    <name>retries</name>
+    <testStrategy>SuiteBased</testStrategy>
+    <retry maxAttempts="2" backoff="100ms"/>
+    <testCases>
+        <testCase>search.xml</testCase>
+        <testCase>checkout.xml</testCase>
+    </testCases>
+</testSuite>`))
+	assert.Nil(t, err)
+	own := &RetryPolicy{MaxAttempts: 5}
+	ts.resolveTestDefinitions(ts.Steps, func(name string) (*TestDefinition, error) {
+		if name == "checkout.xml" {
+			return &TestDefinition{TestName: name, Retry: own}, nil
+		}
+		return &TestDefinition{TestName: name}, nil
+	})
+
+	assert.Equal(t, 2, ts.TestDefinitions[0].Retry.MaxAttempts)
+	assert.Equal(t, "100ms", ts.TestDefinitions[0].Retry.Backoff)
+	assert.True(t, ts.TestDefinitions[1].Retry == own)
+}
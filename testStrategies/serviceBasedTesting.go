@@ -3,44 +3,59 @@ package testStrategies
 import (
 	"github.com/xtracdev/automated-perf-test/perfTestUtils"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //Single execution function for all service test.
 //Runs multiple invocations of the test based on num iterations parameter
-func ExecuteServiceTest(testDefinition *TestDefinition, loadPerUser int, remainder int, configurationSettings *perfTestUtils.Config, mode int) int64 {
+func ExecuteServiceTest(testDefinition *TestDefinition, loadPerUser int, remainder int, configurationSettings *perfTestUtils.Config, perfStatsForTest *perfTestUtils.PerfStats, mode int) int64 {
 
 	averageResponseTime := int64(0)
 	responseTimes := make([]int64, 0)
 	subsetOfResponseTimesChan := make(chan perfTestUtils.RspTimes, 1)
 
-	targetHost, targetPort := determineHostandPortforRequest(testDefinition, configurationSettings)
+	targetScheme, targetHost, targetPort := determineTargetForRequest(testDefinition, configurationSettings)
 
 	var wg sync.WaitGroup
 	wg.Add(configurationSettings.ConcurrentUsers)
 	for i := 0; i < configurationSettings.ConcurrentUsers; i++ {
-		go buildAndSendUserRequests(subsetOfResponseTimesChan, loadPerUser, testDefinition, configurationSettings.RequestDelay, targetHost, targetPort)
+		go buildAndSendUserRequests(subsetOfResponseTimesChan, loadPerUser, testDefinition, configurationSettings, i, targetScheme, targetHost, targetPort, perfStatsForTest)
 		go aggregateResponseTimes(&responseTimes, subsetOfResponseTimesChan, &wg)
 	}
 	if remainder > 0 {
 		wg.Add(1)
-		go buildAndSendUserRequests(subsetOfResponseTimesChan, remainder, testDefinition, configurationSettings.RequestDelay, targetHost, targetPort)
+		go buildAndSendUserRequests(subsetOfResponseTimesChan, remainder, testDefinition, configurationSettings, configurationSettings.ConcurrentUsers, targetScheme, targetHost, targetPort, perfStatsForTest)
 		go aggregateResponseTimes(&responseTimes, subsetOfResponseTimesChan, &wg)
 	}
 
-	wg.Wait()
+	waitForUsers(&wg)
 
-	if len(responseTimes) == configurationSettings.NumIterations {
-		averageResponseTime = perfTestUtils.CalcAverageResponseTime(responseTimes, mode)
+	// An interrupted run averages the requests completed so far.
+	mu.Lock()
+	completedResponseTimes := append(perfTestUtils.RspTimes(nil), responseTimes...)
+	mu.Unlock()
+	if len(completedResponseTimes) == configurationSettings.NumIterations || (isTestRunStopped() && len(completedResponseTimes) > 0) {
+		averageResponseTime = perfTestUtils.CalcAverageResponseTime(completedResponseTimes, mode)
 	}
 	return averageResponseTime
 }
 
-func buildAndSendUserRequests(subsetOfResponseTimesChan chan perfTestUtils.RspTimes, loadPerUser int, testDefinition *TestDefinition, delay int, targetHost string, targetPort string) {
+func buildAndSendUserRequests(subsetOfResponseTimesChan chan perfTestUtils.RspTimes, loadPerUser int, testDefinition *TestDefinition, configurationSettings *perfTestUtils.Config, userID int, targetScheme string, targetHost string, targetPort string, perfStatsForTest *perfTestUtils.PerfStats) {
 	responseTimes := make(perfTestUtils.RspTimes, loadPerUser)
+	client := httpClientFor(configurationSettings, userID)
 	loopExecutedToCompletion := true
 
 	for i := 0; i < loadPerUser; i++ {
-		responseTime := testDefinition.BuildAndSendRequest(delay, targetHost, targetPort, "")
+		if isTestRunStopped() {
+			responseTimes = responseTimes[:i]
+			break
+		}
+		responseTime, err := testDefinition.BuildAndSendRequest(client, configurationSettings, perfStatsForTest, targetScheme, targetHost, targetPort, "")
+		recordRequestOutcome(responseTime)
+		if isTimeout(err) {
+			countTimeout(perfStatsForTest, testDefinition.TestName)
+		}
 
 		if responseTime > 0 {
 			responseTimes[i] = responseTime
@@ -60,7 +75,105 @@ func buildAndSendUserRequests(subsetOfResponseTimesChan chan perfTestUtils.RspTi
 func aggregateResponseTimes(responseTimes *[]int64, subsetOfResponseTimesChan chan perfTestUtils.RspTimes, wg *sync.WaitGroup) {
 	subsetOfResponseTimes := <-subsetOfResponseTimesChan
 	if subsetOfResponseTimes != nil {
+		mu.Lock()
 		*responseTimes = append(*responseTimes, subsetOfResponseTimes...)
+		mu.Unlock()
 	}
 	wg.Done()
 }
+
+//ExecuteServiceTestForDuration runs invocations of the test on each concurrent
+//user until the deadline. Each user finishes its request in progress at the
+//deadline. Returns the average response time, or 0 if any request failed.
+func ExecuteServiceTestForDuration(testDefinition *TestDefinition, deadline time.Time, configurationSettings *perfTestUtils.Config, perfStatsForTest *perfTestUtils.PerfStats, mode int) int64 {
+	responseTimes := make(perfTestUtils.RspTimes, 0)
+	subsetOfResponseTimesChan := make(chan perfTestUtils.RspTimes, configurationSettings.ConcurrentUsers)
+
+	targetScheme, targetHost, targetPort := determineTargetForRequest(testDefinition, configurationSettings)
+
+	for i := 0; i < configurationSettings.ConcurrentUsers; i++ {
+		go buildAndSendUserRequestsUntil(subsetOfResponseTimesChan, deadline, testDefinition, configurationSettings, i, targetScheme, targetHost, targetPort, perfStatsForTest)
+	}
+
+	// A user that hit a failed request reports nil. Users still busy when
+	// the grace period of an interrupted run expires are left out.
+	failed := false
+collect:
+	for i := 0; i < configurationSettings.ConcurrentUsers; i++ {
+		select {
+		case subsetOfResponseTimes := <-subsetOfResponseTimesChan:
+			if subsetOfResponseTimes == nil {
+				failed = true
+				continue
+			}
+			responseTimes = append(responseTimes, subsetOfResponseTimes...)
+		case <-gracePeriodExpired():
+			break collect
+		}
+	}
+
+	if failed || len(responseTimes) == 0 {
+		return 0
+	}
+	return perfTestUtils.CalcAverageResponseTime(responseTimes, mode)
+}
+
+func buildAndSendUserRequestsUntil(subsetOfResponseTimesChan chan perfTestUtils.RspTimes, deadline time.Time, testDefinition *TestDefinition, configurationSettings *perfTestUtils.Config, userID int, targetScheme string, targetHost string, targetPort string, perfStatsForTest *perfTestUtils.PerfStats) {
+	responseTimes := make(perfTestUtils.RspTimes, 0)
+	client := httpClientFor(configurationSettings, userID)
+
+	for time.Now().Before(deadline) && !isTestRunStopped() {
+		responseTime, err := testDefinition.BuildAndSendRequest(client, configurationSettings, perfStatsForTest, targetScheme, targetHost, targetPort, "")
+		recordRequestOutcome(responseTime)
+		if isTimeout(err) {
+			countTimeout(perfStatsForTest, testDefinition.TestName)
+		}
+		atomic.AddUint64(&perfStatsForTest.OverAllTransCount, 1)
+
+		if responseTime <= 0 {
+			subsetOfResponseTimesChan <- nil
+			return
+		}
+		responseTimes = append(responseTimes, responseTime)
+	}
+	subsetOfResponseTimesChan <- responseTimes
+}
+
+//ExecuteServiceTestAtArrivalRate runs invocations of the test using the open
+//model ArrivalRate executor. Each arrival is a single request. The test runs
+//NumIterations requests, or until the deadline if one is given. Returns the
+//average response time, or 0 if any request failed.
+func ExecuteServiceTestAtArrivalRate(testDefinition *TestDefinition, deadline time.Time, configurationSettings *perfTestUtils.Config, perfStatsForTest *perfTestUtils.PerfStats, mode int) int64 {
+	var responseTimesMutex sync.Mutex
+	responseTimes := make(perfTestUtils.RspTimes, 0, configurationSettings.NumIterations)
+	failed := false
+
+	targetScheme, targetHost, targetPort := determineTargetForRequest(testDefinition, configurationSettings)
+
+	limit := runLimit{iterations: configurationSettings.NumIterations, deadline: deadline}
+	executeAtArrivalRate(configurationSettings, perfStatsForTest, constantRateSchedule(configurationSettings, limit, time.Now()), nil, func(userID int, iteration int, startDelay time.Duration) {
+		client := httpClientFor(configurationSettings, userID)
+		responseTime, err := testDefinition.BuildAndSendRequest(client, configurationSettings, perfStatsForTest, targetScheme, targetHost, targetPort, "")
+		recordRequestOutcome(responseTime)
+		recordCorrectedResponseTime(perfStatsForTest, testDefinition.TestName, responseTime, startDelay)
+		if isTimeout(err) {
+			countTimeout(perfStatsForTest, testDefinition.TestName)
+		}
+		atomic.AddUint64(&perfStatsForTest.OverAllTransCount, 1)
+
+		responseTimesMutex.Lock()
+		defer responseTimesMutex.Unlock()
+		if responseTime > 0 {
+			responseTimes = append(responseTimes, responseTime)
+		} else {
+			failed = true
+		}
+	})
+
+	responseTimesMutex.Lock()
+	defer responseTimesMutex.Unlock()
+	if failed || len(responseTimes) == 0 {
+		return 0
+	}
+	return perfTestUtils.CalcAverageResponseTime(responseTimes, mode)
+}
@@ -0,0 +1,253 @@
+package testStrategies
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+)
+
+// Names of the setup and teardown phases of a suite, as logged.
+const (
+	SetupPhase        = "setup"
+	TeardownPhase     = "teardown"
+	UserSetupPhase    = "userSetup"
+	UserTeardownPhase = "userTeardown"
+)
+
+// suitePhaseUserID is the virtual user of the suite setup and teardown,
+// which keeps their connections apart from the ones of the virtual users.
+const suitePhaseUserID = -1
+
+// userPhase is the setup state of a virtual user of the suite.
+type userPhase struct {
+	setup  sync.Once
+	values map[string]interface{}
+}
+
+//----- RunSetups --------------------------------------------------------------
+// Run the <setup> of the suite, and of the suites of its workloads, before
+// the test run starts, so that it is not part of the time of the run. On the
+// controller of a distributed run, the agents get the values of the setups
+// with the suite.
+func RunSetups(testSuite *TestSuite, configSettings *perfTestUtils.Config) {
+	for suite, settings := range phaseSuites(testSuite, configSettings) {
+		suite.runSetup(settings)
+	}
+}
+
+//----- RunTeardowns -----------------------------------------------------------
+// Run the <userTeardown> of every virtual user, on the agents of a
+// distributed run, and then the <teardown> of the suite and of the suites of
+// its workloads, once the test run is over.
+func RunTeardowns(testSuite *TestSuite, configSettings *perfTestUtils.Config) {
+	suites := phaseSuites(testSuite, configSettings)
+	if len(configSettings.Agents) > 0 {
+		for suite := range suites {
+			if len(suite.UserTeardown) > 0 {
+				tearDownAgents(configSettings.Agents, configSettings.AgentToken)
+				break
+			}
+		}
+	} else {
+		runUserTeardowns(suites)
+	}
+	for suite, settings := range suites {
+		suite.runTeardown(settings)
+	}
+}
+
+// phaseSuites returns the suite and the suites of its workloads, with the
+// configuration settings each runs with.
+func phaseSuites(testSuite *TestSuite, configSettings *perfTestUtils.Config) map[*TestSuite]*perfTestUtils.Config {
+	suites := map[*TestSuite]*perfTestUtils.Config{testSuite: configSettings}
+	for _, workload := range testSuite.Workloads {
+		suites[workload.TestSuite] = workload.ConfigurationSettings
+	}
+	return suites
+}
+
+// runUserTeardowns runs the <userTeardown> of the virtual users of every
+// suite, at the same time.
+func runUserTeardowns(suites map[*TestSuite]*perfTestUtils.Config) {
+	var teardownWaitGroup sync.WaitGroup
+	for suite, settings := range suites {
+		teardownWaitGroup.Add(1)
+		go func(suite *TestSuite, settings *perfTestUtils.Config) {
+			defer teardownWaitGroup.Done()
+			suite.runUserTeardowns(settings)
+		}(suite, settings)
+	}
+	teardownWaitGroup.Wait()
+}
+
+// hasUserPhases returns whether the virtual users of the suite have a setup
+// or a teardown.
+func (ts *TestSuite) hasUserPhases() bool {
+	return len(ts.UserSetup) > 0 || len(ts.UserTeardown) > 0
+}
+
+// runSetup runs the <setup> of the suite, once per run, and keeps the values
+// it extracts for the iterations. It returns false if the setup already ran
+// elsewhere, as it does on the agents of a distributed run, where the setup
+// runs on the controller and its values are sent with the suite.
+func (ts *TestSuite) runSetup(configurationSettings *perfTestUtils.Config) bool {
+	if ts.SetupDone {
+		return false
+	}
+	ts.SetupValues = runPhase(SetupPhase, ts.Setup, configurationSettings, suitePhaseUserID, ts.runID(SetupPhase), nil)
+	ts.SetupDone = true
+	return true
+}
+
+// runTeardown runs the <teardown> of the suite, with the values of the
+// setup, which the next run of the suite runs again.
+func (ts *TestSuite) runTeardown(configurationSettings *perfTestUtils.Config) {
+	runPhase(TeardownPhase, ts.Teardown, configurationSettings, suitePhaseUserID, ts.runID(TeardownPhase), ts.SetupValues)
+	ts.SetupValues = nil
+	ts.SetupDone = false
+}
+
+// setUpUser runs the <userSetup> of a virtual user, if it has not run yet.
+// The executors run it as a user starts, before its pacing or schedule, so
+// that it is not part of the time of its iterations.
+func (ts *TestSuite) setUpUser(configurationSettings *perfTestUtils.Config, userID int) {
+	ts.userValues(configurationSettings, userID)
+}
+
+// setUpUsers runs the <userSetup> of the first count virtual users, at the
+// same time.
+func (ts *TestSuite) setUpUsers(configurationSettings *perfTestUtils.Config, count int) {
+	if len(ts.UserSetup) == 0 {
+		return
+	}
+	var setupWaitGroup sync.WaitGroup
+	for userID := 0; userID < count; userID++ {
+		setupWaitGroup.Add(1)
+		go func(userID int) {
+			defer setupWaitGroup.Done()
+			ts.setUpUser(configurationSettings, userID)
+		}(userID)
+	}
+	setupWaitGroup.Wait()
+}
+
+// userValues returns the values an iteration of a virtual user starts with:
+// the values of the setup of the suite, and of the user. The <userSetup>
+// runs before the first iteration of the user, if it did not run already.
+func (ts *TestSuite) userValues(configurationSettings *perfTestUtils.Config, userID int) map[string]interface{} {
+	if !ts.hasUserPhases() {
+		return ts.SetupValues
+	}
+	mu.Lock()
+	if ts.users == nil {
+		ts.users = make(map[int]*userPhase)
+	}
+	user := ts.users[userID]
+	if user == nil {
+		user = &userPhase{}
+		ts.users[userID] = user
+	}
+	mu.Unlock()
+
+	user.setup.Do(func() {
+		user.values = runPhase(UserSetupPhase, ts.UserSetup, configurationSettings, userID, ts.runID(fmt.Sprintf("User%dSetup", userID)), ts.SetupValues)
+	})
+	return user.values
+}
+
+// runUserTeardowns runs the <userTeardown> of every virtual user that ran,
+// at the same time, with the values of its setup. The users of the next run
+// of the suite run their setup again.
+func (ts *TestSuite) runUserTeardowns(configurationSettings *perfTestUtils.Config) {
+	mu.Lock()
+	users := ts.users
+	ts.users = nil
+	mu.Unlock()
+	if len(ts.UserTeardown) == 0 {
+		return
+	}
+
+	var teardownWaitGroup sync.WaitGroup
+	for userID, user := range users {
+		teardownWaitGroup.Add(1)
+		go func(userID int, user *userPhase) {
+			defer teardownWaitGroup.Done()
+			runPhase(UserTeardownPhase, ts.UserTeardown, configurationSettings, userID, ts.runID(fmt.Sprintf("User%dTeardown", userID)), user.values)
+		}(userID, user)
+	}
+	teardownWaitGroup.Wait()
+}
+
+// runPhase runs the steps of a setup or teardown phase, starting from the
+// given values, and returns the values it extracted in addition to them. The
+// requests of a phase are not part of the test: they are left out of the
+// response times, counters and abort criteria of the run.
+func runPhase(
+	phase string,
+	steps SuiteSteps,
+	configurationSettings *perfTestUtils.Config,
+	userID int,
+	uniqueTestRunID string,
+	values map[string]interface{},
+) map[string]interface{} {
+	if len(steps) == 0 {
+		return values
+	}
+
+	phaseValues := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		phaseValues[name] = value
+	}
+	mu.Lock()
+	globalsMap[uniqueTestRunID] = phaseValues
+	mu.Unlock()
+
+	var jar http.CookieJar
+	if userID == suitePhaseUserID {
+		jar, _ = cookiejar.New(nil)
+	} else {
+		jar = cookieJarFor(configurationSettings, userID)
+		startRunUser(uniqueTestRunID, userID, 0)
+		defer endRunUser(uniqueTestRunID)
+	}
+	si := &suiteIteration{
+		configurationSettings: configurationSettings,
+		perfStatsForTest: &perfTestUtils.PerfStats{
+			ServiceTransCount: make(map[string]*uint64),
+			ServiceErrorCount: make(map[string]*uint64),
+		},
+		userID:          userID,
+		uniqueTestRunID: uniqueTestRunID,
+		cookieJar:       jar,
+		responseTimes:   make(map[string][]int64),
+		untimed:         true,
+	}
+	log.Infof("Running %s. UniqueRunID: [%s]", phase, uniqueTestRunID)
+	si.executeSteps(steps)
+	if si.serviceErrors > 0 {
+		log.Warnf("The %s had [%d] failed requests out of [%d]. UniqueRunID: [%s]", phase, si.serviceErrors, si.serviceCount, uniqueTestRunID)
+	}
+
+	mu.Lock()
+	phaseValues = globalsMap[uniqueTestRunID]
+	globalsMap[uniqueTestRunID] = nil
+	mu.Unlock()
+	return phaseValues
+}
+
+// startWith sets the given values as the values an iteration starts with.
+func (si *suiteIteration) startWith(values map[string]interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	testRunGlobals := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		testRunGlobals[name] = value
+	}
+	globalsMap[si.uniqueTestRunID] = testRunGlobals
+}
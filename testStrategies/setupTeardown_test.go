@@ -0,0 +1,199 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const xmlSetupTeardownTestSuite = `<testSuite>
+    <name>setupTeardownSuite</name>
+    <testStrategy>SuiteBased</testStrategy>
+    <setup>
+        <testCase>login.xml</testCase>
+    </setup>
+    <userSetup>
+        <testCase>userLogin.xml</testCase>
+    </userSetup>
+    <testCases>
+        <testCase>work.xml</testCase>
+    </testCases>
+    <userTeardown>
+        <testCase>userLogout.xml</testCase>
+    </userTeardown>
+    <teardown>
+        <testCase>cleanup.xml</testCase>
+    </teardown>
+</testSuite>`
+
+func loadSetupTeardownTestSuite(t *testing.T) *TestSuite {
+	ts := new(TestSuite)
+	assert.Nil(t, ts.loadTestSuiteDefinition([]byte(xmlSetupTeardownTestSuite)))
+	loadFile := func(name string) (*TestDefinition, error) {
+		testName := name[:len(name)-len(".xml")]
+		td := &TestDefinition{
+			TestName:           testName,
+			HTTPMethod:         "GET",
+			BaseURI:            "/" + testName,
+			ResponseStatusCode: 200,
+			ResponseValues:     []ResponseValue{{ExtractionKey: "token", Value: "token"}},
+		}
+		switch testName {
+		case "userLogin":
+			td.BaseURI = "/userLogin?user={{$userId}}"
+		case "work":
+			td.BaseURI = "/work?admin={{login.token}}&user={{userLogin.token}}"
+		case "userLogout":
+			td.BaseURI = "/userLogout?user={{userLogin.token}}"
+		case "cleanup":
+			td.BaseURI = "/cleanup?admin={{login.token}}"
+		}
+		return td, nil
+	}
+	ts.resolveTestDefinitions(ts.Steps, loadFile)
+	ts.resolvePhaseDefinitions(loadFile)
+	return ts
+}
+
+func TestLoadSetupTeardown(t *testing.T) {
+	ts := loadSetupTeardownTestSuite(t)
+
+	assert.Equal(t, "login", ts.Setup[0].TestDefinition.TestName)
+	assert.Equal(t, "userLogin", ts.UserSetup[0].TestDefinition.TestName)
+	assert.Equal(t, "userLogout", ts.UserTeardown[0].TestDefinition.TestName)
+	assert.Equal(t, "cleanup", ts.Teardown[0].TestDefinition.TestName)
+
+	// The test cases of the phases are not part of the test.
+	assert.Equal(t, 1, len(ts.TestDefinitions))
+	assert.Equal(t, map[string]float64{"work": 1}, ts.ConfiguredMix())
+
+	ts = new(TestSuite)
+	assert.NotNil(t, ts.loadTestSuiteDefinition([]byte(`<testSuite><setup><branch/></setup><testCases/></testSuite>`)))
+}
+
+func TestExecuteSetupTeardown(t *testing.T) {
+	var m sync.Mutex
+	requests := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], r.URL.RawQuery)
+		m.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte(`{"token":"admin"}`))
+		case "/userLogin":
+			w.Write([]byte(`{"token":"user` + r.URL.Query().Get("user") + `"}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	config.ConcurrentUsers = 2
+	config.NumIterations = 3
+	ts := loadSetupTeardownTestSuite(t)
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	RunSetups(ts, config)
+	assert.Equal(t, 1, len(requests["/login"]))
+	responseTimes := ExecuteTestSuiteWrapper(ts, config, perfStats, time.Now())
+	assert.Equal(t, 0, len(requests["/cleanup"]))
+	assert.Equal(t, 0, len(requests["/userLogout"]))
+	RunTeardowns(ts, config)
+
+	// The suite setup and teardown run once, the ones of the users once per
+	// user, with the values extracted by the setups.
+	assert.Equal(t, []string{"admin=admin"}, requests["/cleanup"])
+	assert.Equal(t, 1, len(requests["/login"]))
+	assert.Equal(t, 2, len(requests["/userLogin"]))
+	sort.Strings(requests["/userLogout"])
+	assert.Equal(t, []string{"user=user0", "user=user1"}, requests["/userLogout"])
+	assert.True(t, len(requests["/work"]) > 0)
+	for _, query := range requests["/work"] {
+		assert.Contains(t, []string{"admin=admin&user=user0", "admin=admin&user=user1"}, query)
+	}
+
+	// Only the requests of the iterations are timed and counted.
+	assert.Equal(t, 1, len(responseTimes))
+	assert.Equal(t, len(requests["/work"]), len(responseTimes["work"]))
+	assert.Equal(t, uint64(len(requests["/work"])), perfStats.OverAllTransCount)
+	assert.Equal(t, 1, len(perfStats.ServiceTransCount))
+	assert.False(t, ts.SetupDone)
+}
+
+func TestSetupRunsOnce(t *testing.T) {
+	ts := &TestSuite{SetupDone: true, SetupValues: map[string]interface{}{"login.token": "admin"}}
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+
+	// On an agent, the setup ran on the controller.
+	assert.False(t, ts.runSetup(config))
+	assert.Equal(t, map[string]interface{}{"login.token": "admin"}, ts.userValues(config, 3))
+}
+
+func TestExecuteOnAgentsSetupTeardown(t *testing.T) {
+	var m sync.Mutex
+	requests := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], r.URL.RawQuery)
+		m.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token":"admin"}`))
+		case "/userLogin":
+			w.Write([]byte(`{"token":"user` + r.URL.Query().Get("user") + `"}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	agent := httptest.NewServer(newAgentHandler("secret"))
+	defer agent.Close()
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	config.ConcurrentUsers = 2
+	config.NumIterations = 1
+	config.Agents = []string{agent.URL}
+	config.AgentToken = "secret"
+	ts := loadSetupTeardownTestSuite(t)
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	RunSetups(ts, config)
+	_, err := ExecuteOnAgents(ts, config, perfStats, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(requests["/userLogout"]))
+	RunTeardowns(ts, config)
+
+	// The suite setup and teardown run on the controller, the ones of the
+	// users on the agent, once the run is over.
+	assert.Equal(t, 1, len(requests["/login"]))
+	assert.Equal(t, []string{"admin=admin"}, requests["/cleanup"])
+	sort.Strings(requests["/userLogout"])
+	assert.Equal(t, []string{"user=user0", "user=user1"}, requests["/userLogout"])
+	assert.Equal(t, uint64(2), perfStats.OverAllTransCount)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,11 +19,60 @@ func ExecuteTestSuiteWrapper(
 	scenarioTimeStart time.Time,
 ) map[string][]int64 {
 	allServicesResponseTimesMap := make(map[string][]int64, 0)
-	testSuiteResponseTimesChan := make(chan []map[string]int64, 1)
-	var suiteWaitGroup sync.WaitGroup
+	limit := newRunLimit(configSettings, scenarioTimeStart)
+
+	// Display the ongoing TPS to log.Info based on period specified in configurationSettings.TPSFreq:
+	// (The suites of a mixed workload share a single display, see
+	// ExecuteWorkloads.)
+	quitShowTPSChan := make(chan bool, 1)
+	if testSuite.workloadStats == nil {
+		perfStatsForTest.ServiceConfiguredMix = testSuite.ConfiguredMix()
+		go showCurrentTPS(quitShowTPSChan, configSettings, scenarioTimeStart, &perfStatsForTest.OverAllTransCount)
+	}
+
+	// The <userSetup> of a virtual user runs before its iterations are
+	// paced or scheduled. The initial users of the ArrivalRate executor set
+	// up before the schedule starts.
+	setUpUser := func(userID int) { testSuite.setUpUser(configSettings, userID) }
+	if configSettings.Executor == perfTestUtils.ArrivalRateExecutor {
+		testSuite.setUpUsers(configSettings, initialArrivalRateUsers(configSettings))
+	}
+
+	if len(configSettings.LoadProfile) > 0 {
+		// The load profile replaces NumIterations, Duration and the
+		// RampUsers/RampDelay settings: the run lasts as long as its stages.
+		lp := newLoadProfile(configSettings)
+		profileStart := time.Now()
+		perfStatsForTest.LoadStageStarts = lp.stageStarts(profileStart)
+		if configSettings.Executor == perfTestUtils.ArrivalRateExecutor {
+			executeAtArrivalRate(configSettings, perfStatsForTest, lp.arrivalSchedule(profileStart), setUpUser,
+				scheduledSuiteIteration(allServicesResponseTimesMap, testSuite, configSettings, perfStatsForTest))
+		} else {
+			newUserPacer := func(userID int) *pacer {
+				testSuite.setUpUser(configSettings, userID)
+				return newPacer(configSettings, perfStatsForTest, time.Time{})
+			}
+			executeLoadProfileUsers(lp, profileStart, newUserPacer, func(userID int, iteration int) map[string][]int64 {
+				testSuiteResponseTimes := executeTestSuiteIteration(testSuite, configSettings, userID, iteration, perfStatsForTest)
+				aggregateSuiteResponseTimes(allServicesResponseTimesMap, testSuiteResponseTimes)
+				return testSuiteResponseTimes
+			})
+		}
+		quitShowTPSChan <- true
+		return snapshotResponseTimes(allServicesResponseTimesMap)
+	}
+
+	if configSettings.Executor == perfTestUtils.ArrivalRateExecutor {
+		// Open model: every arrival runs one full iteration of the suite on
+		// whichever virtual user is free. NumIterations is the total number
+		// of suite iterations across all users.
+		executeAtArrivalRate(configSettings, perfStatsForTest, constantRateSchedule(configSettings, limit, time.Now()), setUpUser,
+			scheduledSuiteIteration(allServicesResponseTimesMap, testSuite, configSettings, perfStatsForTest))
+		quitShowTPSChan <- true
+		return snapshotResponseTimes(allServicesResponseTimesMap)
+	}
 
-	// Set concurrency control:
-	suiteWaitGroup.Add(configSettings.ConcurrentUsers)
+	var suiteWaitGroup sync.WaitGroup
 
 	// Run the test suites concurrently.
 	for i := 0; i < configSettings.ConcurrentUsers; i++ {
@@ -30,168 +80,341 @@ func ExecuteTestSuiteWrapper(
 		// specified delay time. Otherwise, skip the delay and start all
 		// threads simultaneously.
 		if (i != 0) && (configSettings.RampUsers != 0) && (i%configSettings.RampUsers == 0) {
-			time.Sleep(time.Duration(configSettings.RampDelay) * time.Second)
+			select {
+			case <-time.After(time.Duration(configSettings.RampDelay) * time.Second):
+			case <-testRunStopped():
+			}
+		}
+		if isTestRunStopped() {
+			// No more users are started once the run has been stopped.
+			break
 		}
-		go executeTestSuite(testSuiteResponseTimesChan, testSuite, configSettings, i, perfStatsForTest)
-		go aggregateSuiteResponseTimes(testSuiteResponseTimesChan, allServicesResponseTimesMap, &suiteWaitGroup)
+		suiteWaitGroup.Add(1)
+		go executeTestSuite(allServicesResponseTimesMap, testSuite, configSettings, i, perfStatsForTest, limit, &suiteWaitGroup)
 	}
 
-	// Display the ongoing TPS to log.Info based on period specified in configurationSettings.TPSFreq:
-	quitShowTPSChan := make(chan bool)
-	go showCurrentTPS(quitShowTPSChan, configSettings, scenarioTimeStart, &perfStatsForTest.OverAllTransCount)
-
-	suiteWaitGroup.Wait()
+	waitForUsers(&suiteWaitGroup)
 	quitShowTPSChan <- true
 
-	return allServicesResponseTimesMap
+	return snapshotResponseTimes(allServicesResponseTimesMap)
 }
 
 //----- executeTestSuite ------------------------------------------------------
+// Run the test suite for a single user until the run limit is reached. The
+// response times of each iteration are aggregated as soon as it completes,
+// so that an interrupted run keeps the results collected so far.
 func executeTestSuite(
-	testSuiteResponseTimesChan chan []map[string]int64,
+	allServicesResponseTimesMap map[string][]int64,
 	testSuite *TestSuite,
 	configurationSettings *perfTestUtils.Config,
 	userID int,
 	perfStatsForTest *perfTestUtils.PerfStats,
+	limit runLimit,
+	suiteWaitGroup *sync.WaitGroup,
 ) {
+	defer suiteWaitGroup.Done()
 	log.Info("Test Suite started")
 
-	allSuiteResponseTimes := make([]map[string]int64, 0)
-	uniqueTestRunID := ""
-
-	for i := 0; i < configurationSettings.NumIterations; i++ {
-		// Run all services of the test suite NumIterations of times.
-		uniqueTestRunID = fmt.Sprintf("User%dIter%d", userID, i)
-		testSuiteResponseTimes := make(map[string]int64)
-
-		// Set booleans for weighted load tags:
-		// Determine whether "Infrequent" items should run this iteration.
-		// [Currently set at 20% (mod 5)]
-		skipInfrequent := false
-		if i%5 != 0 {
-			skipInfrequent = true
+	testSuite.setUpUser(configurationSettings, userID)
+	pacer := newPacer(configurationSettings, perfStatsForTest, limit.deadline)
+	for i := 0; limit.allows(i, time.Now()); i++ {
+		// Run all services of the test suite NumIterations of times, or
+		// until the deadline of a duration based run. The iteration in
+		// progress at the deadline runs to completion.
+		pacer.iterationStarted(time.Now())
+		testSuiteResponseTimes := executeTestSuiteIteration(testSuite, configurationSettings, userID, i, perfStatsForTest)
+		if testSuiteResponseTimes == nil {
+			// A feeder ran out of rows for this user.
+			break
 		}
-		// Determine whether "Sparse" items should run this iteration.
-		// [Currently set at 3% (mod 30)]
-		skipSparse := false
-		if i%30 != 0 {
-			skipSparse = true
+		aggregateSuiteResponseTimes(allServicesResponseTimesMap, testSuiteResponseTimes)
+		pacer.iterationDone(testSuiteResponseTimes)
+		if limit.allows(i+1, time.Now()) {
+			pacer.waitForWindow(nil)
 		}
+	}
+}
 
-		for _, testDefinition := range testSuite.TestDefinitions {
-			// Execute service based on weighted load:
-			if testDefinition.ExecWeight == "Infrequent" && skipInfrequent {
-				// Skip "Infrequent" items:
-				continue
-			}
-			if testDefinition.ExecWeight == "Sparse" && skipSparse {
-				// Skip "Sparse" items:
+//----- scheduledSuiteIteration -----------------------------------------------
+// Return the function that runs an iteration of the test suite started by a
+// schedule. Besides the response times, it records the response times
+// corrected for the delay of the iteration start.
+func scheduledSuiteIteration(
+	allServicesResponseTimesMap map[string][]int64,
+	testSuite *TestSuite,
+	configSettings *perfTestUtils.Config,
+	perfStatsForTest *perfTestUtils.PerfStats,
+) func(userID int, iteration int, startDelay time.Duration) {
+	return func(userID int, iteration int, startDelay time.Duration) {
+		testSuiteResponseTimes := executeTestSuiteIteration(testSuite, configSettings, userID, iteration, perfStatsForTest)
+		aggregateSuiteResponseTimes(allServicesResponseTimesMap, testSuiteResponseTimes)
+		recordCorrectedResponseTimes(perfStatsForTest, testSuiteResponseTimes, startDelay)
+	}
+}
+
+//----- executeTestSuiteIteration ---------------------------------------------
+// Run the steps of the test suite once for the given user and iteration,
+// updating the concurrent counters of perfStatsForTest, and return the
+// response times of each service, one for every time it ran. The iteration
+// does not run, and nil is returned, if a feeder of the suite ran out of rows
+// for it.
+func executeTestSuiteIteration(
+	testSuite *TestSuite,
+	configurationSettings *perfTestUtils.Config,
+	userID int,
+	i int,
+	perfStatsForTest *perfTestUtils.PerfStats,
+) map[string][]int64 {
+	si := &suiteIteration{
+		configurationSettings: configurationSettings,
+		perfStatsForTest:      perfStatsForTest,
+		workloadStats:         testSuite.workloadStats,
+		userID:                userID,
+		iteration:             i,
+		uniqueTestRunID:       testSuite.runID(fmt.Sprintf("User%dIter%d", userID, i)),
+		cookieJar:             cookieJarFor(configurationSettings, userID),
+		responseTimes:         make(map[string][]int64),
+	}
+	startRunUser(si.uniqueTestRunID, userID, i)
+	defer endRunUser(si.uniqueTestRunID)
+	si.startWith(testSuite.userValues(configurationSettings, userID))
+	if !si.feed(testSuite.Feeders) {
+		return nil
+	}
+	si.executeSteps(testSuite.steps())
+
+	atomic.AddUint64(&perfStatsForTest.IterationCount, 1)
+	if si.workloadStats != nil {
+		atomic.AddUint64(&si.workloadStats.IterationCount, 1)
+	}
+
+	// Variables and properties for this iteration are no longer needed
+	// now that the iteration has completed.
+	mu.Lock()
+	globalsMap[si.uniqueTestRunID] = nil
+	mu.Unlock()
+
+	return si.responseTimes
+}
+
+// suiteIteration holds the state of a single iteration of the test suite by
+// one virtual user while its steps are executed.
+type suiteIteration struct {
+	configurationSettings *perfTestUtils.Config
+	perfStatsForTest      *perfTestUtils.PerfStats
+	workloadStats         *perfTestUtils.WorkloadStats
+	userID                int
+	iteration             int
+	uniqueTestRunID       string
+	cookieJar             http.CookieJar
+	responseTimes         map[string][]int64
+
+	// Totals of the services run so far, for the time of transactions.
+	serviceCount  int
+	serviceErrors int
+	serviceTime   int64
+
+	// untimed is set for the setup and teardown phases of the suite.
+	untimed bool
+}
+
+// executeSteps runs the given steps in order. A test case runs according to
+// its execution weight, a choice runs one of its branches, a transaction runs
+// its steps and records their time, and control flow steps run their steps
+// as their count or condition decides.
+func (si *suiteIteration) executeSteps(steps SuiteSteps) {
+	for _, step := range steps {
+		if step.Choice != nil {
+			branch := step.Choice.pick()
+			if branch == nil {
 				continue
 			}
+			log.Debug("Choice = [", step.Choice.Name, "] branch = [", branch.Name, "]")
+			si.executeSteps(branch.Steps)
+			continue
+		}
+		if step.Transaction != nil {
+			si.executeTransaction(step.Transaction)
+			continue
+		}
+		if step.Repeat != nil {
+			si.executeRepeat(step.Repeat)
+			continue
+		}
+		if step.Loop != nil {
+			si.executeLoop(step.Loop)
+			continue
+		}
+		if step.If != nil {
+			si.executeConditional(step.If)
+			continue
+		}
 
-			if testDefinition.ExecWeight == "Infrequent" {
-				log.Debug("ExecWeight = [", testDefinition.ExecWeight, "] testCase = [", testDefinition.TestName, "]")
-			}
-			if testDefinition.ExecWeight == "Sparse" {
-				log.Debug("ExecWeight = [", testDefinition.ExecWeight, "] testCase = [", testDefinition.TestName, "]")
-			}
+		testDefinition := step.TestDefinition
+		if testDefinition == nil {
+			// The test case file failed to load.
+			continue
+		}
 
-			log.Info("Test case: [", testDefinition.TestName, "] UniqueRunID: [", uniqueTestRunID, "]")
-
-			targetHost, targetPort := determineHostandPortforRequest(testDefinition, configurationSettings)
-			responseTime := testDefinition.BuildAndSendRequest(configurationSettings.RequestDelay, targetHost, targetPort, uniqueTestRunID)
-
-			// NOTE:
-			// Upon error responseTime is set to 0. Rather than drop these
-			// transactions, we record the zero and increment the TransCount.
-			// Otherwise, in the case of a service that fails all attempts,
-			// the stats would show no record of the transaction. Likewise,
-			// since we must keep the zero responseTime in the stats, we must
-			// therefore also increment the TransCount so the average will be
-			// valid in the case of services that fail incrementally.
-
-			// Track responseTime for all attempts, even failures.
-			testSuiteResponseTimes[testDefinition.TestName] = responseTime
-
-			// Increment the concurrent counters for TransCount and ErrorCount.
-			// Overall counters:
-			// TransCount:
-			atomic.AddUint64(&perfStatsForTest.OverAllTransCount, 1)
-			if responseTime == 0 {
-				// ErrorCount
-				atomic.AddUint64(&perfStatsForTest.OverAllErrorCount, 1)
-			}
+		// Execute service based on weighted load:
+		if !shouldExecute(testDefinition.ExecWeight, si.iteration) {
+			continue
+		}
+		if testDefinition.ExecWeight != "" {
+			log.Debug("ExecWeight = [", testDefinition.ExecWeight, "] testCase = [", testDefinition.TestName, "]")
+		}
 
-			// Service-level counters:
-			// Increment ServiceTransCount.
-			// (Create the counters on the fly and increment atomically.)
-			mu.Lock()
-			if perfStatsForTest.ServiceTransCount[testDefinition.TestName] == nil {
-				perfStatsForTest.ServiceTransCount[testDefinition.TestName] = new(uint64)
-				atomic.StoreUint64(
-					perfStatsForTest.ServiceTransCount[testDefinition.TestName],
-					0,
-				)
-				perfStatsForTest.ServiceErrorCount[testDefinition.TestName] = new(uint64)
-				atomic.StoreUint64(
-					perfStatsForTest.ServiceErrorCount[testDefinition.TestName],
-					0,
-				)
-			}
-			mu.Unlock()
-			atomic.AddUint64(
-				perfStatsForTest.ServiceTransCount[testDefinition.TestName],
-				1,
-			)
-			// Increment ServiceErrorCount.
-			if responseTime == 0 {
-				mu.Lock()
-				if perfStatsForTest.ServiceErrorCount[testDefinition.TestName] == nil {
-					perfStatsForTest.ServiceErrorCount[testDefinition.TestName] = new(uint64)
-					atomic.StoreUint64(
-						perfStatsForTest.ServiceErrorCount[testDefinition.TestName],
-						0,
-					)
-				}
-				mu.Unlock()
-				atomic.AddUint64(
-					perfStatsForTest.ServiceErrorCount[testDefinition.TestName],
-					1,
-				)
-			}
+		si.executeTestCase(testDefinition)
+	}
+}
+
+// executeTestCase sends the request of a single test case, or runs its
+// WebSocket step, and records the response times and counters.
+func (si *suiteIteration) executeTestCase(testDefinition *TestDefinition) {
+	configurationSettings := si.configurationSettings
+	perfStatsForTest := si.perfStatsForTest
+
+	log.Info("Test case: [", testDefinition.TestName, "] UniqueRunID: [", si.uniqueTestRunID, "]")
+
+	targetScheme, targetHost, targetPort := determineTargetForRequest(testDefinition, configurationSettings)
+	if testDefinition.WebSocket != nil {
+		serviceTimes, err := testDefinition.runWebSocket(configurationSettings, perfStatsForTest, si.cookieJar, targetScheme, targetHost, targetPort, si.uniqueTestRunID)
+		for _, st := range serviceTimes {
+			si.recordServiceTime(st.name, st.responseTime)
 		}
+		if isTimeout(err) {
+			countTimeout(perfStatsForTest, serviceTimes[len(serviceTimes)-1].name)
+		}
+		return
+	}
 
-		allSuiteResponseTimes = append(allSuiteResponseTimes, testSuiteResponseTimes)
+	client := withCookieJar(httpClientFor(configurationSettings, si.userID), si.cookieJar)
+	responseTime, err := testDefinition.BuildAndSendRequest(client, configurationSettings, perfStatsForTest, targetScheme, targetHost, targetPort, si.uniqueTestRunID)
+	if isTimeout(err) {
+		countTimeout(perfStatsForTest, testDefinition.TestName)
+	}
+	si.recordServiceTime(testDefinition.TestName, responseTime)
+}
+
+// recordServiceTime records the response time of a service of the
+// iteration, or 0 if it failed, and updates its counters.
+func (si *suiteIteration) recordServiceTime(serviceName string, responseTime int64) {
+	perfStatsForTest := si.perfStatsForTest
+	if !si.untimed {
+		recordRequestOutcome(responseTime)
+	}
+
+	// NOTE:
+	// Upon error responseTime is set to 0. Rather than drop these
+	// transactions, we record the zero and increment the TransCount.
+	// Otherwise, in the case of a service that fails all attempts,
+	// the stats would show no record of the transaction. Likewise,
+	// since we must keep the zero responseTime in the stats, we must
+	// therefore also increment the TransCount so the average will be
+	// valid in the case of services that fail incrementally.
+
+	// Track responseTime for all attempts, even failures, and every run of
+	// a test case that runs more than once in the iteration.
+	si.responseTimes[serviceName] = append(si.responseTimes[serviceName], responseTime)
+	si.serviceCount++
+	si.serviceTime += responseTime
+	if responseTime == 0 {
+		si.serviceErrors++
+	}
 
-		// Variables and properties for this iteration are no longer needed
-		// now that the iteration has completed.
+	// Increment the concurrent counters for TransCount and ErrorCount.
+	// Overall counters:
+	// TransCount:
+	atomic.AddUint64(&perfStatsForTest.OverAllTransCount, 1)
+	if responseTime == 0 {
+		// ErrorCount
+		atomic.AddUint64(&perfStatsForTest.OverAllErrorCount, 1)
+	}
+
+	// Service-level counters:
+	// Increment ServiceTransCount.
+	// (Create the counters on the fly and increment atomically.)
+	mu.Lock()
+	if perfStatsForTest.ServiceTransCount[serviceName] == nil {
+		perfStatsForTest.ServiceTransCount[serviceName] = new(uint64)
+		atomic.StoreUint64(
+			perfStatsForTest.ServiceTransCount[serviceName],
+			0,
+		)
+		perfStatsForTest.ServiceErrorCount[serviceName] = new(uint64)
+		atomic.StoreUint64(
+			perfStatsForTest.ServiceErrorCount[serviceName],
+			0,
+		)
+	}
+	mu.Unlock()
+	atomic.AddUint64(
+		perfStatsForTest.ServiceTransCount[serviceName],
+		1,
+	)
+	// Increment ServiceErrorCount.
+	if responseTime == 0 {
 		mu.Lock()
-		globalsMap[uniqueTestRunID] = nil
+		if perfStatsForTest.ServiceErrorCount[serviceName] == nil {
+			perfStatsForTest.ServiceErrorCount[serviceName] = new(uint64)
+			atomic.StoreUint64(
+				perfStatsForTest.ServiceErrorCount[serviceName],
+				0,
+			)
+		}
 		mu.Unlock()
+		atomic.AddUint64(
+			perfStatsForTest.ServiceErrorCount[serviceName],
+			1,
+		)
 	}
 
-	testSuiteResponseTimesChan <- allSuiteResponseTimes
+	// Workload counters, for the suites of a mixed workload.
+	if ws := si.workloadStats; ws != nil {
+		atomic.AddUint64(&ws.TransCount, 1)
+		mu.Lock()
+		if ws.ServiceTransCount[serviceName] == nil {
+			ws.ServiceTransCount[serviceName] = new(uint64)
+			ws.ServiceErrorCount[serviceName] = new(uint64)
+		}
+		mu.Unlock()
+		atomic.AddUint64(ws.ServiceTransCount[serviceName], 1)
+		if responseTime == 0 {
+			atomic.AddUint64(&ws.ErrorCount, 1)
+			atomic.AddUint64(ws.ServiceErrorCount[serviceName], 1)
+		}
+	}
 }
 
 //----- aggregateSuiteResponseTimes -------------------------------------------
+// Append the response times of a single suite iteration to the per-service
+// response times of the whole run.
 func aggregateSuiteResponseTimes(
-	testSuiteResponseTimesChan chan []map[string]int64,
 	allServicesResponseTimesMap map[string][]int64,
-	suiteWaitGroup *sync.WaitGroup,
+	singleSuiteRunResponseTimes map[string][]int64,
 ) {
-	perUserSuiteResponseTimes := <-testSuiteResponseTimesChan
-	for _, singleSuiteRunResponseTimes := range perUserSuiteResponseTimes {
-		for serviceName, serviceResponseTime := range singleSuiteRunResponseTimes {
-			mu.Lock()
-			if allServicesResponseTimesMap[serviceName] == nil {
-				serviceResponseSlice := make([]int64, 0)
-				allServicesResponseTimesMap[serviceName] = serviceResponseSlice
-			}
-			allServicesResponseTimesMap[serviceName] = append(allServicesResponseTimesMap[serviceName], serviceResponseTime)
-			mu.Unlock()
+	for serviceName, serviceResponseTimes := range singleSuiteRunResponseTimes {
+		mu.Lock()
+		if allServicesResponseTimesMap[serviceName] == nil {
+			serviceResponseSlice := make([]int64, 0)
+			allServicesResponseTimesMap[serviceName] = serviceResponseSlice
 		}
+		allServicesResponseTimesMap[serviceName] = append(allServicesResponseTimesMap[serviceName], serviceResponseTimes...)
+		mu.Unlock()
+	}
+}
+
+//----- snapshotResponseTimes -------------------------------------------------
+// Return a copy of the per-service response times. Users still busy after the
+// grace period of an interrupted run may go on adding to the original.
+func snapshotResponseTimes(allServicesResponseTimesMap map[string][]int64) map[string][]int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	snapshot := make(map[string][]int64, len(allServicesResponseTimesMap))
+	for serviceName, serviceResponseTimes := range allServicesResponseTimesMap {
+		snapshot[serviceName] = append([]int64(nil), serviceResponseTimes...)
 	}
-	suiteWaitGroup.Done()
+	return snapshot
 }
 
 //----- showCurrentTPS -------------------------------------------------------------------------------------------------
@@ -0,0 +1,73 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExecuteTestSuiteWrapperStopped(t *testing.T) {
+	defer resetTestRun()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	config.ConcurrentUsers = 4
+	config.NumIterations = 1000000
+	config.RampUsers = 2
+	config.RampDelay = 60
+	testSuite := &TestSuite{
+		TestDefinitions: []*TestDefinition{{TestName: "ping", HTTPMethod: "GET", BaseURI: "/ping"}},
+	}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { StopTestRun("interrupted", time.Second) })
+	start := time.Now()
+	responseTimes := ExecuteTestSuiteWrapper(testSuite, config, perfStats, start)
+
+	// The ramp delay is cut short, the users started so far stop after
+	// their iteration in progress, and every completed iteration is kept.
+	assert.True(t, time.Since(start) < 2*time.Second)
+	assert.True(t, len(responseTimes["ping"]) > 0)
+	assert.Equal(t, int(perfStats.IterationCount), len(responseTimes["ping"]))
+}
+
+func TestScheduledSuiteIteration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	search := &TestDefinition{TestName: "search", HTTPMethod: "GET", BaseURI: "/search", ResponseStatusCode: 200}
+	testSuite := &TestSuite{TestDefinitions: []*TestDefinition{search}}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+	allServicesResponseTimesMap := make(map[string][]int64)
+
+	runIteration := scheduledSuiteIteration(allServicesResponseTimesMap, testSuite, config, perfStats)
+	runIteration(0, 0, time.Second)
+
+	// The response time is measured from the actual send time, the
+	// corrected one from the time the iteration was due.
+	assert.Equal(t, 1, len(allServicesResponseTimesMap["search"]))
+	assert.True(t, allServicesResponseTimesMap["search"][0] < int64(time.Second))
+	assert.Equal(t, allServicesResponseTimesMap["search"][0]+int64(time.Second), perfStats.ServiceCorrectedSamples["search"][0])
+}
@@ -0,0 +1,326 @@
+package testStrategies
+
+import (
+	"encoding/xml"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// ExecWeightInfrequent and ExecWeightSparse are the named execution weights
+// of a <testCase>. They run on every 5th and every 30th iteration
+// respectively. Any other weight is numeric, see parseExecWeight.
+const (
+	ExecWeightInfrequent = "Infrequent"
+	ExecWeightSparse     = "Sparse"
+)
+
+// SuiteStep is a single entry of a <testCases> block in document order:
+// either a <testCase>, a <choice> between weighted alternative branches, a
+// <transaction>, or one of the control flow steps <repeat>, <while>, <until>
+// and <if>.
+// BuildTestSuite resolves the TestDefinition of every <testCase> step.
+type SuiteStep struct {
+	TestCase       *TestCase
+	Choice         *Choice
+	Repeat         *Repeat
+	Loop           *Loop
+	If             *Conditional
+	Transaction    *Transaction
+	TestDefinition *TestDefinition
+}
+
+// SuiteSteps is an ordered list of suite steps. It is unmarshalled from the
+// child elements of <testCases>, <branch>, <transaction> or a control flow
+// step.
+type SuiteSteps []*SuiteStep
+
+// Choice is a <choice> element. Every time the choice is reached exactly one
+// of its branches runs, picked at random according to the branch weights.
+type Choice struct {
+	Name     string   `xml:"name,attr"`
+	Branches []Branch `xml:"branch"`
+}
+
+// Branch is one alternative of a <choice>. Weights are relative to the other
+// branches of the same choice.
+type Branch struct {
+	Name   string
+	Weight float64
+	Steps  SuiteSteps
+}
+
+// UnmarshalXML decodes the child elements of start into suite steps,
+// preserving their order.
+func (ss *SuiteSteps) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			step, err := decodeSuiteStep(d, t, start)
+			if err != nil {
+				return err
+			}
+			*ss = append(*ss, step)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodeSuiteStep decodes the element t, a child of parent, into a suite step.
+func decodeSuiteStep(d *xml.Decoder, t xml.StartElement, parent xml.StartElement) (*SuiteStep, error) {
+	step := &SuiteStep{}
+	var err error
+	switch t.Name.Local {
+	case "testCase":
+		step.TestCase = &TestCase{}
+		err = d.DecodeElement(step.TestCase, &t)
+	case "choice":
+		step.Choice = &Choice{}
+		err = d.DecodeElement(step.Choice, &t)
+	case StepTypeRepeat:
+		step.Repeat = &Repeat{}
+		err = d.DecodeElement(step.Repeat, &t)
+	case StepTypeWhile, StepTypeUntil:
+		step.Loop = &Loop{}
+		err = d.DecodeElement(step.Loop, &t)
+	case StepTypeIf:
+		step.If = &Conditional{}
+		err = d.DecodeElement(step.If, &t)
+	case "transaction":
+		step.Transaction = &Transaction{}
+		err = d.DecodeElement(step.Transaction, &t)
+	default:
+		err = fmt.Errorf("unsupported element <%s> in <%s>", t.Name.Local, parent.Name.Local)
+	}
+	return step, err
+}
+
+// UnmarshalXML decodes the name and weight attributes of a <branch> and its
+// child elements as suite steps.
+func (b *Branch) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	b.Weight = 1
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "name":
+			b.Name = attr.Value
+		case "weight":
+			weight, err := strconv.ParseFloat(strings.TrimSpace(attr.Value), 64)
+			if err != nil || weight < 0 {
+				return fmt.Errorf("invalid branch weight [%s]", attr.Value)
+			}
+			b.Weight = weight
+		}
+	}
+	return b.Steps.UnmarshalXML(d, start)
+}
+
+// pick returns a branch at random according to the branch weights, or nil
+// if the choice has no branch with a positive weight.
+func (c *Choice) pick() *Branch {
+	totalWeight := 0.0
+	for _, branch := range c.Branches {
+		totalWeight += branch.Weight
+	}
+	if totalWeight <= 0 {
+		return nil
+	}
+	r := rand.Float64() * totalWeight
+	for i := range c.Branches {
+		r -= c.Branches[i].Weight
+		if r < 0 {
+			return &c.Branches[i]
+		}
+	}
+	return &c.Branches[len(c.Branches)-1]
+}
+
+// probability returns the chance of the given branch being picked.
+func (c *Choice) probability(branch *Branch) float64 {
+	totalWeight := 0.0
+	for _, b := range c.Branches {
+		totalWeight += b.Weight
+	}
+	if totalWeight <= 0 {
+		return 0
+	}
+	return branch.Weight / totalWeight
+}
+
+// parseExecWeight returns the probability of a test case running in any
+// given iteration. An empty weight always runs. "Infrequent" and "Sparse"
+// run one iteration in 5 and in 30. A number ending in "%" is a percentage
+// up to 100%. Any other number is a probability between 0 and 1.
+func parseExecWeight(execWeight string) (float64, error) {
+	execWeight = strings.TrimSpace(execWeight)
+	switch execWeight {
+	case "":
+		return 1, nil
+	case ExecWeightInfrequent:
+		return 1.0 / 5, nil
+	case ExecWeightSparse:
+		return 1.0 / 30, nil
+	}
+
+	isPercentage := strings.HasSuffix(execWeight, "%")
+	value, err := strconv.ParseFloat(strings.TrimSuffix(execWeight, "%"), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid execWeight [%s]", execWeight)
+	}
+	if isPercentage {
+		value = value / 100
+	}
+	if value > 1 {
+		if isPercentage {
+			return 0, fmt.Errorf("invalid execWeight [%s]: more than 100%%", execWeight)
+		}
+		return 0, fmt.Errorf("invalid execWeight [%s]: a probability is at most 1, use %s%% for a percentage", execWeight, execWeight)
+	}
+	return value, nil
+}
+
+// shouldExecute decides whether a test case with the given execution weight
+// runs in the given iteration. The named weights are deterministic, numeric
+// weights are random.
+func shouldExecute(execWeight string, iteration int) bool {
+	switch execWeight {
+	case "":
+		return true
+	case ExecWeightInfrequent:
+		// [Currently set at 20% (mod 5)]
+		return iteration%5 == 0
+	case ExecWeightSparse:
+		// [Currently set at 3% (mod 30)]
+		return iteration%30 == 0
+	}
+	probability, err := parseExecWeight(execWeight)
+	if err != nil {
+		// Reported when the suite was built. Treat as unweighted.
+		return true
+	}
+	return rand.Float64() < probability
+}
+
+// steps returns the suite steps, or one step per test definition for a suite
+// that was not loaded from a suite definition file.
+func (ts *TestSuite) steps() SuiteSteps {
+	if len(ts.Steps) > 0 {
+		return ts.Steps
+	}
+	steps := make(SuiteSteps, 0, len(ts.TestDefinitions))
+	for _, testDefinition := range ts.TestDefinitions {
+		steps = append(steps, &SuiteStep{TestDefinition: testDefinition})
+	}
+	return steps
+}
+
+// ConfiguredMix returns the expected number of executions of each service per
+// iteration of the suite, as configured by execution weights, choice branch
+// weights and repeat counts. The steps of loops and ifs depend on the values
+// of the run, so they have no configured mix.
+func (ts *TestSuite) ConfiguredMix() map[string]float64 {
+	mix := make(map[string]float64)
+	addConfiguredMix(mix, ts.steps(), 1)
+	return mix
+}
+
+func addConfiguredMix(mix map[string]float64, steps SuiteSteps, probability float64) {
+	for _, step := range steps {
+		if step.TestDefinition != nil {
+			execProbability, err := parseExecWeight(step.TestDefinition.ExecWeight)
+			if err != nil {
+				execProbability = 1
+			}
+			for _, serviceName := range step.TestDefinition.serviceNames() {
+				mix[serviceName] += probability * execProbability
+			}
+		}
+		if step.Choice != nil {
+			for i := range step.Choice.Branches {
+				branch := &step.Choice.Branches[i]
+				addConfiguredMix(mix, branch.Steps, probability*step.Choice.probability(branch))
+			}
+		}
+		if step.Repeat != nil {
+			averageCount := float64(step.Repeat.Min+step.Repeat.Max) / 2
+			addConfiguredMix(mix, step.Repeat.Steps, probability*averageCount)
+		}
+		if step.Transaction != nil {
+			addConfiguredMix(mix, step.Transaction.Steps, probability)
+		}
+	}
+}
+
+// resolveTestDefinitions loads the test definition of every <testCase> step,
+// recursing into choice branches, transactions and control flow steps, and
+// appends them to ts.TestDefinitions.
+// The <testCase> attributes (thinktime, etc) are copied onto the definition.
+func (ts *TestSuite) resolveTestDefinitions(steps SuiteSteps, loadFile func(name string) (*TestDefinition, error)) {
+	for _, step := range steps {
+		if step.Choice != nil {
+			for i := range step.Choice.Branches {
+				ts.resolveTestDefinitions(step.Choice.Branches[i].Steps, loadFile)
+			}
+			continue
+		}
+		if step.Repeat != nil {
+			ts.resolveTestDefinitions(step.Repeat.Steps, loadFile)
+			continue
+		}
+		if step.Transaction != nil {
+			ts.resolveTestDefinitions(step.Transaction.Steps, loadFile)
+			continue
+		}
+		if step.Loop != nil {
+			ts.resolveTestDefinitions(step.Loop.Steps, loadFile)
+			continue
+		}
+		if step.If != nil {
+			ts.resolveTestDefinitions(step.If.Steps, loadFile)
+			ts.resolveTestDefinitions(step.If.Else, loadFile)
+			continue
+		}
+		if step.TestCase == nil {
+			continue
+		}
+
+		testCase := step.TestCase
+		testDefinition, err := loadFile(strings.TrimSpace(testCase.Name))
+		if err != nil {
+			continue
+		}
+
+		// Add the testCase attributes to the TestDefinition (thinktime, etc).
+		// This effectively flattens the fields into TestDefinitions allowing
+		// us to ignore TestCases.
+		testDefinition.PreThinkTime = parseTestCaseThinkTime(testCase.Name, "preThinkTime", testCase.PreThinkTime)
+		testDefinition.PostThinkTime = parseTestCaseThinkTime(testCase.Name, "postThinkTime", testCase.PostThinkTime)
+		testDefinition.ExecWeight = strings.TrimSpace(testCase.ExecWeight)
+		if testDefinition.Retry == nil {
+			// The retry policy of the suite is the default.
+			testDefinition.Retry = ts.Retry
+		}
+		if _, err := parseExecWeight(testDefinition.ExecWeight); err != nil {
+			log.Warnf("Test case [%s]: %v. The test case will run every iteration.", testCase.Name, err)
+		}
+
+		step.TestDefinition = testDefinition
+		ts.TestDefinitions = append(ts.TestDefinitions, testDefinition)
+	}
+}
+
+// resolvePhaseDefinitions loads the test definitions of the setup and
+// teardown phases of the suite. They are not part of the test, so they are
+// left out of ts.TestDefinitions.
+func (ts *TestSuite) resolvePhaseDefinitions(loadFile func(name string) (*TestDefinition, error)) {
+	for _, steps := range []SuiteSteps{ts.Setup, ts.UserSetup, ts.UserTeardown, ts.Teardown} {
+		phase := &TestSuite{Retry: ts.Retry}
+		phase.resolveTestDefinitions(steps, loadFile)
+	}
+}
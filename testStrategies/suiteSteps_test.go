@@ -0,0 +1,151 @@
+package testStrategies
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const xmlWeightedTestSuite = `<testSuite>
+    <name>weightedSuite</name>
+    <testStrategy>SuiteBased</testStrategy>
+    <testCases>
+        <testCase>login.xml</testCase>
+        <choice name="browseOrBuy">
+            <branch name="browse" weight="80">
+                <testCase execWeight="50%">search.xml</testCase>
+            </branch>
+            <branch name="buy" weight="20">
+                <testCase>addToCart.xml</testCase>
+                <testCase execWeight="0.5">checkout.xml</testCase>
+            </branch>
+        </choice>
+        <testCase execWeight="Infrequent">logout.xml</testCase>
+    </testCases>
+</testSuite>`
+
+func loadWeightedTestSuite(t *testing.T) *TestSuite {
+	ts := new(TestSuite)
+	err := ts.loadTestSuiteDefinition([]byte(xmlWeightedTestSuite))
+	assert.Nil(t, err)
+	ts.resolveTestDefinitions(ts.Steps, func(name string) (*TestDefinition, error) {
+		return &TestDefinition{TestName: name}, nil
+	})
+	return ts
+}
+
+func TestLoadTestSuiteDefinitionSteps(t *testing.T) {
+	ts := loadWeightedTestSuite(t)
+
+	// TestCases only holds the top level test cases.
+	assert.Equal(t, 2, len(ts.TestCases))
+
+	assert.Equal(t, 3, len(ts.Steps))
+	assert.Equal(t, "login.xml", ts.Steps[0].TestCase.Name)
+	assert.Equal(t, "logout.xml", ts.Steps[2].TestCase.Name)
+
+	choice := ts.Steps[1].Choice
+	assert.NotNil(t, choice)
+	assert.Equal(t, "browseOrBuy", choice.Name)
+	assert.Equal(t, 2, len(choice.Branches))
+	assert.Equal(t, "browse", choice.Branches[0].Name)
+	assert.Equal(t, 80.0, choice.Branches[0].Weight)
+	assert.Equal(t, "search.xml", choice.Branches[0].Steps[0].TestCase.Name)
+	assert.Equal(t, 2, len(choice.Branches[1].Steps))
+
+	// Test definitions are resolved in document order.
+	names := make([]string, 0)
+	for _, td := range ts.TestDefinitions {
+		names = append(names, td.TestName)
+	}
+	assert.Equal(t, []string{"login.xml", "search.xml", "addToCart.xml", "checkout.xml", "logout.xml"}, names)
+	assert.Equal(t, "50%", ts.TestDefinitions[1].ExecWeight)
+}
+
+func TestLoadTestSuiteDefinitionStepsErr(t *testing.T) {
+	ts := new(TestSuite)
+	err := ts.loadTestSuiteDefinition([]byte(`<testSuite><testCases><loop/></testCases></testSuite>`))
+	assert.NotNil(t, err)
+
+	ts = new(TestSuite)
+	err = ts.loadTestSuiteDefinition([]byte(`<testSuite><testCases><choice><branch weight="x"/></choice></testCases></testSuite>`))
+	assert.NotNil(t, err)
+}
+
+func TestResolveTestDefinitionsSkipsFailures(t *testing.T) {
+	ts := new(TestSuite)
+	err := ts.loadTestSuiteDefinition([]byte(xmlWeightedTestSuite))
+	assert.Nil(t, err)
+	ts.resolveTestDefinitions(ts.Steps, func(name string) (*TestDefinition, error) {
+		if name == "login.xml" {
+			return nil, errors.New("no such file")
+		}
+		return &TestDefinition{TestName: name}, nil
+	})
+	assert.Equal(t, 4, len(ts.TestDefinitions))
+	assert.Nil(t, ts.Steps[0].TestDefinition)
+}
+
+func TestParseExecWeight(t *testing.T) {
+	cases := map[string]float64{
+		"":           1,
+		"Infrequent": 0.2,
+		"Sparse":     1.0 / 30,
+		"25%":        0.25,
+		"0.25":       0.25,
+		"1":          1,
+		"1.5%":       0.015,
+		"100%":       1,
+	}
+	for execWeight, expected := range cases {
+		probability, err := parseExecWeight(execWeight)
+		assert.Nil(t, err, execWeight)
+		assert.InDelta(t, expected, probability, 1e-9, execWeight)
+	}
+
+	for _, execWeight := range []string{"Often", "-1", "150%", "1.5", "25"} {
+		_, err := parseExecWeight(execWeight)
+		assert.NotNil(t, err, execWeight)
+	}
+}
+
+func TestShouldExecute(t *testing.T) {
+	assert.True(t, shouldExecute("", 3))
+	assert.True(t, shouldExecute("Infrequent", 5))
+	assert.False(t, shouldExecute("Infrequent", 6))
+	assert.True(t, shouldExecute("Sparse", 30))
+	assert.False(t, shouldExecute("Sparse", 31))
+	assert.False(t, shouldExecute("0%", 0))
+	assert.True(t, shouldExecute("100%", 0))
+
+	executed := 0
+	for i := 0; i < 10000; i++ {
+		if shouldExecute("30%", i) {
+			executed++
+		}
+	}
+	assert.InDelta(t, 3000, executed, 300)
+}
+
+func TestChoicePick(t *testing.T) {
+	choice := &Choice{Branches: []Branch{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}, {Name: "c", Weight: 0}}}
+	picked := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		picked[choice.pick().Name]++
+	}
+	assert.InDelta(t, 7500, picked["a"], 300)
+	assert.InDelta(t, 2500, picked["b"], 300)
+	assert.Equal(t, 0, picked["c"])
+
+	assert.Nil(t, (&Choice{Branches: []Branch{{Weight: 0}}}).pick())
+}
+
+func TestConfiguredMix(t *testing.T) {
+	ts := loadWeightedTestSuite(t)
+	mix := ts.ConfiguredMix()
+	assert.InDelta(t, 1, mix["login.xml"], 1e-9)
+	assert.InDelta(t, 0.4, mix["search.xml"], 1e-9)
+	assert.InDelta(t, 0.2, mix["addToCart.xml"], 1e-9)
+	assert.InDelta(t, 0.1, mix["checkout.xml"], 1e-9)
+	assert.InDelta(t, 0.2, mix["logout.xml"], 1e-9)
+}
@@ -0,0 +1,72 @@
+package testStrategies
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// preRequestThinkTime returns the think time before a request of the test
+// definition. It is drawn from the preThinkTime of the test case, or else
+// from the thinkTime of the configuration. Without either, it is a random
+// delay of up to RequestDelay milliseconds.
+func (testDefinition *TestDefinition) preRequestThinkTime(configurationSettings *perfTestUtils.Config) time.Duration {
+	if !testDefinition.PreThinkTime.IsZero() {
+		return testDefinition.PreThinkTime.Sample()
+	}
+	if tt := configurationSettings.ThinkTimeDistribution(); !tt.IsZero() {
+		return tt.Sample()
+	}
+	if configurationSettings.RequestDelay < 1 {
+		return 0
+	}
+	return time.Duration(rand.Intn(configurationSettings.RequestDelay)) * time.Millisecond
+}
+
+// pause sleeps for the think time d, and returns the time actually spent.
+func pause(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	log.Debugf("Think time: [%.2f] seconds.", d.Seconds())
+	start := time.Now()
+	time.Sleep(d)
+	return time.Since(start)
+}
+
+// recordThinkTime adds the think time around a request of the given service
+// to the overall and service think time counters.
+func recordThinkTime(perfStatsForTest *perfTestUtils.PerfStats, testName string, thinkTime time.Duration) {
+	atomic.AddUint64(&perfStatsForTest.OverAllThinkTime, uint64(thinkTime))
+	atomic.AddUint64(&perfStatsForTest.OverAllThinkCount, 1)
+
+	mu.Lock()
+	if perfStatsForTest.ServiceThinkTime == nil {
+		perfStatsForTest.ServiceThinkTime = make(map[string]*uint64)
+		perfStatsForTest.ServiceThinkCount = make(map[string]*uint64)
+	}
+	serviceThinkTime := perfStatsForTest.ServiceThinkTime[testName]
+	if serviceThinkTime == nil {
+		serviceThinkTime = new(uint64)
+		perfStatsForTest.ServiceThinkTime[testName] = serviceThinkTime
+		perfStatsForTest.ServiceThinkCount[testName] = new(uint64)
+	}
+	serviceThinkCount := perfStatsForTest.ServiceThinkCount[testName]
+	mu.Unlock()
+	atomic.AddUint64(serviceThinkTime, uint64(thinkTime))
+	atomic.AddUint64(serviceThinkCount, 1)
+}
+
+// parseTestCaseThinkTime returns the think time of a <testCase> attribute.
+// An invalid think time is ignored.
+func parseTestCaseThinkTime(testCaseName string, attr string, spec string) perfTestUtils.ThinkTime {
+	tt, err := perfTestUtils.ParseThinkTime(spec)
+	if err != nil {
+		log.Warnf("Test case [%s]: %s: %v. The test case will have no %s.", strings.TrimSpace(testCaseName), attr, err, attr)
+		return perfTestUtils.ThinkTime{}
+	}
+	return tt
+}
@@ -0,0 +1,94 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPreRequestThinkTime(t *testing.T) {
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.RequestDelay = 10
+	testDefinition := &TestDefinition{TestName: "search"}
+
+	// Without any think time, a random delay of up to RequestDelay.
+	for i := 0; i < 100; i++ {
+		assert.True(t, testDefinition.preRequestThinkTime(config) < 10*time.Millisecond)
+	}
+
+	config.ThinkTime = "constant(250)"
+	assert.Equal(t, 250*time.Millisecond, testDefinition.preRequestThinkTime(config))
+
+	// The preThinkTime of the test case takes precedence.
+	testDefinition.PreThinkTime = perfTestUtils.ThinkTime{Distribution: perfTestUtils.ThinkTimeConstant, Mean: 40}
+	assert.Equal(t, 40*time.Millisecond, testDefinition.preRequestThinkTime(config))
+}
+
+func TestRecordThinkTime(t *testing.T) {
+	perfStats := &perfTestUtils.PerfStats{}
+	recordThinkTime(perfStats, "search", 100*time.Millisecond)
+	recordThinkTime(perfStats, "search", 300*time.Millisecond)
+	recordThinkTime(perfStats, "checkout", 0)
+
+	assert.Equal(t, uint64(3), perfStats.OverAllThinkCount)
+	assert.Equal(t, uint64(400*time.Millisecond), perfStats.OverAllThinkTime)
+	assert.Equal(t, uint64(2), *perfStats.ServiceThinkCount["search"])
+	assert.Equal(t, 200.0, perfStats.GetServiceAverageThinkTime("search"))
+	assert.Equal(t, 0.0, perfStats.GetServiceAverageThinkTime("checkout"))
+}
+
+func TestBuildAndSendRequestThinkTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	testDefinition := &TestDefinition{TestName: "search", HTTPMethod: "GET", BaseURI: "/search", ResponseStatusCode: 200,
+		PreThinkTime:  perfTestUtils.ThinkTime{Distribution: perfTestUtils.ThinkTimeConstant, Mean: 50},
+		PostThinkTime: perfTestUtils.ThinkTime{Distribution: perfTestUtils.ThinkTimeConstant, Mean: 100}}
+	perfStats := &perfTestUtils.PerfStats{}
+
+	start := time.Now()
+	responseTime, err := testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 150*time.Millisecond)
+
+	// The think time is recorded separately from the response time.
+	assert.True(t, responseTime < int64(50*time.Millisecond))
+	assert.Equal(t, uint64(1), perfStats.OverAllThinkCount)
+	assert.True(t, perfStats.GetServiceAverageThinkTime("search") >= 150)
+
+	// The think time before a failed request is recorded too.
+	testDefinition.ResponseStatusCode = 201
+	_, err = testDefinition.BuildAndSendRequest(newHTTPClient(config), config, perfStats, "http", host, port, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(2), perfStats.OverAllThinkCount)
+}
+
+func TestTestCaseThinkTime(t *testing.T) {
+	ts := new(TestSuite)
+	err := ts.loadTestSuiteDefinition([]byte(`<testSuite>
+    <name>thinkTimes</name>
+    <testStrategy>SuiteBased</testStrategy>
+    <testCases>
+        <testCase preThinkTime="normal(500,100)" postThinkTime="250">search.xml</testCase>
+        <testCase preThinkTime="gamma(2,3)">checkout.xml</testCase>
+    </testCases>
+</testSuite>`))
+	assert.Nil(t, err)
+	ts.resolveTestDefinitions(ts.Steps, func(name string) (*TestDefinition, error) {
+		return &TestDefinition{TestName: name}, nil
+	})
+
+	assert.Equal(t, perfTestUtils.ThinkTime{Distribution: perfTestUtils.ThinkTimeNormal, Mean: 500, StdDev: 100}, ts.TestDefinitions[0].PreThinkTime)
+	assert.Equal(t, perfTestUtils.ThinkTime{Distribution: perfTestUtils.ThinkTimeConstant, Mean: 250}, ts.TestDefinitions[0].PostThinkTime)
+	// An invalid think time is ignored.
+	assert.True(t, ts.TestDefinitions[1].PreThinkTime.IsZero())
+	assert.True(t, ts.TestDefinitions[1].PostThinkTime.IsZero())
+}
@@ -0,0 +1,133 @@
+package testStrategies
+
+import (
+	"encoding/xml"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"strings"
+	"time"
+)
+
+// Transaction is a <transaction> element: a named business transaction made
+// up of its steps, measured end to end like a service. ThinkTime, "include"
+// or "exclude", overrides the transactionThinkTime of the configuration for
+// this transaction. A transaction with a MaxResponseTime is asserted against
+// it, instead of the allowed variance from its base time.
+type Transaction struct {
+	Name            string
+	ThinkTime       string
+	MaxResponseTime string
+	Steps           SuiteSteps
+}
+
+// UnmarshalXML decodes the attributes of a <transaction>, and its child
+// elements as suite steps.
+func (tr *Transaction) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	tr.Name = strings.TrimSpace(attrValue(start, "name"))
+	if tr.Name == "" {
+		return fmt.Errorf("<transaction> without a name")
+	}
+	tr.ThinkTime = strings.TrimSpace(attrValue(start, "thinkTime"))
+	if tr.ThinkTime != "" && tr.ThinkTime != perfTestUtils.ExcludeThinkTime && tr.ThinkTime != perfTestUtils.IncludeThinkTime {
+		return fmt.Errorf("transaction [%s]: invalid thinkTime [%s]", tr.Name, tr.ThinkTime)
+	}
+	tr.MaxResponseTime = strings.TrimSpace(attrValue(start, "maxResponseTime"))
+	if tr.MaxResponseTime != "" {
+		if max, err := time.ParseDuration(tr.MaxResponseTime); err != nil || max <= 0 {
+			return fmt.Errorf("transaction [%s]: invalid maxResponseTime [%s]", tr.Name, tr.MaxResponseTime)
+		}
+	}
+	return tr.Steps.UnmarshalXML(d, start)
+}
+
+// includesThinkTime returns whether the time of the transaction includes the
+// think time of its test cases.
+func (tr *Transaction) includesThinkTime(configurationSettings *perfTestUtils.Config) bool {
+	if tr.ThinkTime != "" {
+		return tr.ThinkTime == perfTestUtils.IncludeThinkTime
+	}
+	return configurationSettings.TransactionThinkTime == perfTestUtils.IncludeThinkTime
+}
+
+// threshold returns the maximum response time of the transaction in
+// nanoseconds, or 0 if it has none.
+func (tr *Transaction) threshold() int64 {
+	d, err := time.ParseDuration(tr.MaxResponseTime)
+	if err != nil {
+		return 0
+	}
+	return d.Nanoseconds()
+}
+
+// executeTransaction runs the steps of a transaction and records its time.
+// Excluding think time, the time of the transaction is the sum of the
+// response times of the services it ran. A transaction fails, and records a
+// time of 0, if any of them failed. A transaction that ran no service, all of
+// its test cases being skipped by their execution weight, is not recorded.
+func (si *suiteIteration) executeTransaction(transaction *Transaction) {
+	start := time.Now()
+	serviceCount, serviceErrors, serviceTime := si.serviceCount, si.serviceErrors, si.serviceTime
+
+	si.executeSteps(transaction.Steps)
+
+	if si.serviceCount == serviceCount {
+		return
+	}
+	responseTime := si.serviceTime - serviceTime
+	if transaction.includesThinkTime(si.configurationSettings) {
+		responseTime = time.Since(start).Nanoseconds()
+	}
+	if si.serviceErrors != serviceErrors {
+		log.Warnf("Transaction [%s] failed. UniqueRunID: [%s]", transaction.Name, si.uniqueTestRunID)
+		responseTime = 0
+	}
+	recordTransaction(si.perfStatsForTest, transaction.Name, responseTime, transaction.threshold())
+}
+
+// recordTransaction records the response time of a transaction, or 0 if it
+// failed, and updates its counters.
+func recordTransaction(perfStatsForTest *perfTestUtils.PerfStats, name string, responseTime int64, threshold int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	if perfStatsForTest.TransactionSamples == nil {
+		perfStatsForTest.TransactionSamples = make(map[string][]int64)
+	}
+	perfStatsForTest.TransactionSamples[name] = append(perfStatsForTest.TransactionSamples[name], responseTime)
+
+	if perfStatsForTest.TransactionTransCount == nil {
+		perfStatsForTest.TransactionTransCount = make(map[string]*uint64)
+	}
+	if perfStatsForTest.TransactionErrorCount == nil {
+		perfStatsForTest.TransactionErrorCount = make(map[string]*uint64)
+	}
+	if perfStatsForTest.TransactionTransCount[name] == nil {
+		perfStatsForTest.TransactionTransCount[name] = new(uint64)
+	}
+	if perfStatsForTest.TransactionErrorCount[name] == nil {
+		perfStatsForTest.TransactionErrorCount[name] = new(uint64)
+	}
+	*perfStatsForTest.TransactionTransCount[name]++
+	if responseTime == 0 {
+		*perfStatsForTest.TransactionErrorCount[name]++
+	}
+	if threshold > 0 {
+		if perfStatsForTest.TransactionThresholds == nil {
+			perfStatsForTest.TransactionThresholds = make(map[string]int64)
+		}
+		perfStatsForTest.TransactionThresholds[name] = threshold
+	}
+}
+
+// TransactionSamples returns a copy of the recorded response times of each
+// transaction. Users still busy after the grace period of an interrupted run
+// may go on adding to the original.
+func TransactionSamples(perfStatsForTest *perfTestUtils.PerfStats) map[string][]int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	snapshot := make(map[string][]int64, len(perfStatsForTest.TransactionSamples))
+	for name, samples := range perfStatsForTest.TransactionSamples {
+		snapshot[name] = append([]int64(nil), samples...)
+	}
+	return snapshot
+}
@@ -0,0 +1,121 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const xmlTransactionTestSuite = `<testSuite>
+    <name>transactionSuite</name>
+    <testStrategy>SuiteBased</testStrategy>
+    <testCases>
+        <testCase>login.xml</testCase>
+        <transaction name="checkout" maxResponseTime="2s">
+            <testCase>cart.xml</testCase>
+            <testCase preThinkTime="50">address.xml</testCase>
+            <testCase>pay.xml</testCase>
+        </transaction>
+        <transaction name="browse" thinkTime="include">
+            <testCase execWeight="0">search.xml</testCase>
+        </transaction>
+    </testCases>
+</testSuite>`
+
+func loadTransactionTestSuite(t *testing.T) *TestSuite {
+	ts := new(TestSuite)
+	assert.Nil(t, ts.loadTestSuiteDefinition([]byte(xmlTransactionTestSuite)))
+	ts.resolveTestDefinitions(ts.Steps, func(name string) (*TestDefinition, error) {
+		return &TestDefinition{
+			TestName:           name[:len(name)-len(".xml")],
+			HTTPMethod:         "GET",
+			BaseURI:            "/" + name,
+			ResponseStatusCode: 200,
+		}, nil
+	})
+	return ts
+}
+
+func TestLoadTransactionSteps(t *testing.T) {
+	ts := loadTransactionTestSuite(t)
+
+	assert.Equal(t, 3, len(ts.Steps))
+	checkout := ts.Steps[1].Transaction
+	assert.Equal(t, "checkout", checkout.Name)
+	assert.Equal(t, "", checkout.ThinkTime)
+	assert.Equal(t, int64(2*time.Second), checkout.threshold())
+	assert.Equal(t, 3, len(checkout.Steps))
+	assert.Equal(t, perfTestUtils.IncludeThinkTime, ts.Steps[2].Transaction.ThinkTime)
+	assert.Equal(t, int64(0), ts.Steps[2].Transaction.threshold())
+	assert.Equal(t, 5, len(ts.TestDefinitions))
+
+	// The test cases of a transaction count towards the mix as usual.
+	assert.Equal(t, map[string]float64{"login": 1, "cart": 1, "address": 1, "pay": 1, "search": 0}, ts.ConfiguredMix())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	assert.False(t, checkout.includesThinkTime(config))
+	assert.True(t, ts.Steps[2].Transaction.includesThinkTime(config))
+	config.TransactionThinkTime = perfTestUtils.IncludeThinkTime
+	assert.True(t, checkout.includesThinkTime(config))
+}
+
+func TestLoadTransactionStepsErr(t *testing.T) {
+	for _, testCases := range []string{
+		`<transaction><testCase>a.xml</testCase></transaction>`,
+		`<transaction name="a" thinkTime="sometimes"/>`,
+		`<transaction name="a" maxResponseTime="2"/>`,
+		`<transaction name="a"><loop/></transaction>`,
+	} {
+		ts := new(TestSuite)
+		err := ts.loadTestSuiteDefinition([]byte(`<testSuite><testCases>` + testCases + `</testCases></testSuite>`))
+		assert.NotNil(t, err, testCases)
+	}
+}
+
+func TestExecuteTransaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Path == "/pay.xml" && r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusPaymentRequired)
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	ts := loadTransactionTestSuite(t)
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	// Excluding think time, the transaction time is the sum of the response
+	// times of its test cases.
+	responseTimes := executeTestSuiteIteration(ts, config, 1, 0, perfStats)
+	assert.Equal(t, 1, len(perfStats.TransactionSamples["checkout"]))
+	assert.Equal(t, responseTimes["cart"][0]+responseTimes["address"][0]+responseTimes["pay"][0], perfStats.TransactionSamples["checkout"][0])
+	assert.Equal(t, int64(2*time.Second), perfStats.TransactionThresholds["checkout"])
+
+	// A transaction that runs none of its test cases is not recorded.
+	assert.Nil(t, perfStats.TransactionSamples["browse"])
+
+	// Including think time, it is the time from start to end.
+	config.TransactionThinkTime = perfTestUtils.IncludeThinkTime
+	executeTestSuiteIteration(ts, config, 1, 1, perfStats)
+	assert.True(t, perfStats.TransactionSamples["checkout"][1] >= int64(80*time.Millisecond))
+
+	// A transaction with a failed test case fails.
+	ts.Steps[1].Transaction.Steps[2].TestDefinition.BaseURI = "/pay.xml?fail=true"
+	executeTestSuiteIteration(ts, config, 1, 2, perfStats)
+	assert.Equal(t, int64(0), perfStats.TransactionSamples["checkout"][2])
+	assert.Equal(t, uint64(3), *perfStats.TransactionTransCount["checkout"])
+	assert.Equal(t, uint64(1), *perfStats.TransactionErrorCount["checkout"])
+}
@@ -0,0 +1,499 @@
+package testStrategies
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmespath/go-jmespath"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Opcodes of the WebSocket frames, see RFC 6455 section 5.2.
+const (
+	webSocketContinuation = 0x0
+	webSocketText         = 0x1
+	webSocketBinary       = 0x2
+	webSocketClose        = 0x8
+	webSocketPing         = 0x9
+	webSocketPong         = 0xA
+)
+
+// webSocketGUID is appended to the key of the opening handshake to compute
+// the accept header of the server.
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// defaultWebSocketTimeout is how long a step waits for a matching message
+// when its <waitFor> has no timeout.
+const defaultWebSocketTimeout = 10 * time.Second
+
+// maxWebSocketMessageSize bounds the size of a received message.
+const maxWebSocketMessageSize = 16 << 20
+
+// errWebSocketClosed is returned when the server closes the connection while
+// a step waits for a message.
+var errWebSocketClosed = errors.New("websocket connection closed by the server")
+
+// WebSocketStep turns a test definition into a WebSocket step. The step
+// connects to the baseUri of the test definition, with its headers and auth,
+// then exchanges its messages in order over the connection.
+type WebSocketStep struct {
+	Messages []WebSocketMessage `xml:"message"`
+}
+
+// WebSocketMessage is a single exchange of a WebSocket step: a message sent,
+// a message waited for, or both. Values are extracted from the message that
+// was waited for.
+type WebSocketMessage struct {
+	Name           string          `xml:"name,attr"`
+	Send           string          `xml:"send"`
+	WaitFor        *WebSocketWait  `xml:"waitFor"`
+	ResponseValues []ResponseValue `xml:"responseProperties>value"`
+}
+
+// WebSocketWait is the condition a received message must meet: a JMESPath
+// expression that is true for the JSON message, or a regular expression the
+// message matches. Without either, any message will do. Messages that do not
+// match are skipped.
+type WebSocketWait struct {
+	JMESPath string `xml:"jmesPath,attr"`
+	Regex    string `xml:"regex,attr"`
+	Timeout  string `xml:"timeout,attr"`
+}
+
+// timeout returns how long to wait for a matching message.
+func (wait *WebSocketWait) timeout() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(wait.Timeout))
+	if err != nil || d <= 0 {
+		return defaultWebSocketTimeout
+	}
+	return d
+}
+
+// matcher returns the function that tells if a received message meets the
+// condition.
+func (wait *WebSocketWait) matcher() (func(message []byte) bool, error) {
+	switch {
+	case wait.Regex != "":
+		re, err := regexp.Compile(wait.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid waitFor regex [%s]: %v", wait.Regex, err)
+		}
+		return re.Match, nil
+	case wait.JMESPath != "":
+		jp, err := jmespath.Compile(wait.JMESPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid waitFor jmesPath [%s]: %v", wait.JMESPath, err)
+		}
+		return func(message []byte) bool {
+			var data interface{}
+			if json.Unmarshal(message, &data) != nil {
+				return false
+			}
+			result, err := jp.Search(data)
+			return err == nil && isTruthy(result)
+		}, nil
+	}
+	return func([]byte) bool { return true }, nil
+}
+
+// isTruthy returns false for the values JMESPath considers false: null,
+// false, and empty strings, arrays and objects.
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// serviceTime is the response time of one of the services of a step, or 0
+// if it failed.
+type serviceTime struct {
+	name         string
+	responseTime int64
+}
+
+// serviceNames returns the names of the services a run of the test
+// definition records response times for: the test name for a request, or
+// those of the connection and messages waited for of a WebSocket step.
+func (testDefinition *TestDefinition) serviceNames() []string {
+	if testDefinition.WebSocket == nil {
+		return []string{testDefinition.TestName}
+	}
+	names := []string{testDefinition.TestName + ".connect"}
+	for _, message := range testDefinition.WebSocket.Messages {
+		if message.WaitFor != nil {
+			names = append(names, message.serviceName(testDefinition.TestName))
+		}
+	}
+	return names
+}
+
+// serviceName returns the name of the service of the message in a step of
+// the given test.
+func (message *WebSocketMessage) serviceName(testName string) string {
+	if message.Name == "" {
+		return testName
+	}
+	return testName + "." + message.Name
+}
+
+// runWebSocket runs the WebSocket step of the test definition. The time to
+// connect is the service time of "<testName>.connect". The time from sending
+// a message, or from the previous exchange if there is nothing to send, to
+// the message waited for is the service time of "<testName>.<name>", or
+// "<testName>" for a message without a name. It returns the service times up
+// to the first failure, which has a service time of 0, and the reason of the
+// failure.
+func (testDefinition *TestDefinition) runWebSocket(
+	configurationSettings *perfTestUtils.Config,
+	perfStatsForTest *perfTestUtils.PerfStats,
+	jar http.CookieJar,
+	targetScheme string,
+	targetHost string,
+	targetPort string,
+	uniqueTestRunID string,
+) ([]serviceTime, error) {
+	// Think time is recorded as for any other request.
+	var thinkTime time.Duration
+	defer func() {
+		recordThinkTime(perfStatsForTest, testDefinition.TestName, thinkTime)
+	}()
+	thinkTime += pause(testDefinition.preRequestThinkTime(configurationSettings))
+
+	connectName := testDefinition.TestName + ".connect"
+	failed := func(serviceTimes []serviceTime, name string, err error) ([]serviceTime, error) {
+		log.Errorf("WebSocket step failed [Name:%s]: %v", name, err)
+		return append(serviceTimes, serviceTime{name: name}), err
+	}
+
+	requestBaseURI := substituteRequestValues(&testDefinition.BaseURI, uniqueTestRunID)
+	req, err := http.NewRequest("GET", targetScheme+"://"+targetHost+":"+targetPort+requestBaseURI, nil)
+	if err != nil {
+		return failed(nil, connectName, err)
+	}
+	for _, header := range testDefinition.Headers {
+		req.Header.Add(header.Key, substituteRequestValues(&header.Value, uniqueTestRunID))
+	}
+	if testDefinition.Auth != "" {
+		if err := authorizeRequest(configurationSettings, testDefinition.Auth, req, nil); err != nil {
+			return failed(nil, connectName, err)
+		}
+	}
+	if jar != nil {
+		for _, cookie := range jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	start := time.Now()
+	ws, resp, err := dialWebSocket(configurationSettings, req)
+	if err != nil {
+		return failed(nil, connectName, err)
+	}
+	defer ws.conn.Close()
+	if jar != nil {
+		jar.SetCookies(req.URL, resp.Cookies())
+	}
+	serviceTimes := []serviceTime{{name: connectName, responseTime: time.Since(start).Nanoseconds()}}
+
+	for i := range testDefinition.WebSocket.Messages {
+		message := &testDefinition.WebSocket.Messages[i]
+		name := message.serviceName(testDefinition.TestName)
+
+		start = time.Now()
+		if message.Send != "" {
+			payload := substituteRequestValues(&message.Send, uniqueTestRunID)
+			log.Debugf("WebSocket [%s] send: %s", name, payload)
+			if err := ws.writeMessage(webSocketText, []byte(payload)); err != nil {
+				return failed(serviceTimes, name, err)
+			}
+		}
+		if message.WaitFor == nil {
+			continue
+		}
+
+		received, err := ws.waitForMessage(message.WaitFor)
+		if err != nil {
+			return failed(serviceTimes, name, err)
+		}
+		serviceTimes = append(serviceTimes, serviceTime{name: name, responseTime: time.Since(start).Nanoseconds()})
+		log.Debugf("WebSocket [%s] received: %s", name, received)
+
+		if len(message.ResponseValues) > 0 {
+			contentType := testDefinition.ResponseContentType
+			if contentType == "" && json.Unmarshal(received, new(interface{})) == nil {
+				contentType = "application/json"
+			}
+			extractResponseValues(testDefinition.TestName, received, message.ResponseValues, uniqueTestRunID, detectContentType(nil, received, contentType))
+		}
+	}
+	ws.close()
+
+	//Execute the PostThinkTime, if any.
+	if !testDefinition.PostThinkTime.IsZero() {
+		thinkTime += pause(testDefinition.PostThinkTime.Sample())
+	}
+	return serviceTimes, nil
+}
+
+// webSocketConn is one end of a WebSocket connection. A client masks the
+// frames it sends, a server does not.
+type webSocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	masked bool
+}
+
+// dialWebSocket opens a WebSocket connection with the opening handshake of
+// the given request, an http or https GET request, and returns it with the
+// response of the server. The connect and TLS handshake timeouts of the HTTP
+// client apply, and the response header timeout to the handshake response.
+func dialWebSocket(configurationSettings *perfTestUtils.Config, req *http.Request) (*webSocketConn, *http.Response, error) {
+	clientConfig := configurationSettings.HTTPClient
+	host := req.URL.Host
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		// The URL has no port.
+		hostname = strings.Trim(host, "[]")
+		if req.URL.Scheme == "https" {
+			host = net.JoinHostPort(hostname, "443")
+		} else {
+			host = net.JoinHostPort(hostname, "80")
+		}
+	}
+	dialer := &net.Dialer{Timeout: clientConfig.ConnectTimeoutDuration()}
+	conn, err := dialer.Dial("tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.URL.Scheme == "https" {
+		tlsConfig, err := configurationSettings.TLS.ClientConfig()
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = hostname
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		setDeadline(conn, clientConfig.TLSHandshakeTimeoutDuration())
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		conn = tlsConn
+	}
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", encodedKey)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	setDeadline(conn, clientConfig.ResponseHeaderTimeoutDuration())
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(encodedKey) {
+		conn.Close()
+		return nil, nil, errors.New("invalid Sec-WebSocket-Accept in the handshake response")
+	}
+	conn.SetDeadline(time.Time{})
+	return &webSocketConn{conn: conn, reader: reader, masked: true}, resp, nil
+}
+
+// setDeadline sets the deadline of the connection the timeout from now, or
+// no deadline for a zero timeout.
+func setDeadline(conn net.Conn, timeout time.Duration) {
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	} else {
+		conn.SetDeadline(time.Time{})
+	}
+}
+
+// webSocketAccept returns the accept header of the server for the given
+// Sec-WebSocket-Key.
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+webSocketGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// waitForMessage reads messages until one meets the condition, or until the
+// timeout of the condition. A timeout is a net.Error.
+func (ws *webSocketConn) waitForMessage(wait *WebSocketWait) ([]byte, error) {
+	matches, err := wait.matcher()
+	if err != nil {
+		return nil, err
+	}
+	ws.conn.SetReadDeadline(time.Now().Add(wait.timeout()))
+	defer ws.conn.SetReadDeadline(time.Time{})
+	for {
+		_, message, err := ws.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if matches(message) {
+			return message, nil
+		}
+		log.Debugf("WebSocket message skipped: %s", message)
+	}
+}
+
+// readMessage returns the next text or binary message, answering pings on
+// the way. A close frame ends the connection with errWebSocketClosed.
+func (ws *webSocketConn) readMessage() (byte, []byte, error) {
+	var opcode byte
+	message := []byte{}
+	for {
+		fin, frameOpcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch frameOpcode {
+		case webSocketPing:
+			if err := ws.writeMessage(webSocketPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case webSocketPong:
+			continue
+		case webSocketClose:
+			ws.writeMessage(webSocketClose, payload)
+			return 0, nil, errWebSocketClosed
+		case webSocketContinuation:
+		default:
+			opcode = frameOpcode
+			message = message[:0]
+		}
+		if len(message)+len(payload) > maxWebSocketMessageSize {
+			return 0, nil, fmt.Errorf("websocket message larger than %d bytes", maxWebSocketMessageSize)
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+// readFrame reads a single frame, unmasking its payload.
+func (ws *webSocketConn) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.reader, header); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(ws.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(ws.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxWebSocketMessageSize {
+		return false, 0, nil, fmt.Errorf("websocket frame larger than %d bytes", maxWebSocketMessageSize)
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(ws.reader, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeMessage sends the payload as a single frame of the given opcode.
+func (ws *webSocketConn) writeMessage(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	var maskBit byte
+	if ws.masked {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+	if ws.masked {
+		mask := make([]byte, 4)
+		rand.Read(mask)
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// close sends a normal closure to the other end. The connection itself is
+// closed by the caller.
+func (ws *webSocketConn) close() {
+	ws.writeMessage(webSocketClose, []byte{0x03, 0xE8})
+}
@@ -0,0 +1,212 @@
+package testStrategies
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// echoWebSocketServer accepts WebSocket connections, greets the client with
+// the Authorization header of the handshake, then sends every message back,
+// each one after a message the client is not waiting for.
+func echoWebSocketServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n" +
+			"Set-Cookie: session=abc123\r\n\r\n")
+		rw.Flush()
+
+		ws := &webSocketConn{conn: conn, reader: rw.Reader}
+		ws.writeMessage(webSocketPing, []byte("ping"))
+		ws.writeMessage(webSocketText, []byte("welcome "+r.Header.Get("Authorization")))
+		for {
+			opcode, message, err := ws.readMessage()
+			if err != nil {
+				return
+			}
+			ws.writeMessage(webSocketText, []byte(`{"type":"heartbeat"}`))
+			ws.writeMessage(opcode, message)
+		}
+	}))
+}
+
+func webSocketTestDefinition(messages ...WebSocketMessage) *TestDefinition {
+	return &TestDefinition{
+		TestName:  "quotes",
+		BaseURI:   "/quotes",
+		Headers:   []Header{{Key: "Authorization", Value: "Bearer {{login.token}}"}},
+		WebSocket: &WebSocketStep{Messages: messages},
+	}
+}
+
+func TestRunWebSocket(t *testing.T) {
+	server := echoWebSocketServer()
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	jar, _ := cookiejar.New(nil)
+	mu.Lock()
+	globalsMap["wsUser1Iter0"] = map[string]interface{}{"login.token": "t0k3n", "login.symbol": "ACME"}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		globalsMap["wsUser1Iter0"] = nil
+		mu.Unlock()
+	}()
+
+	testDefinition := webSocketTestDefinition(
+		WebSocketMessage{Name: "welcome", WaitFor: &WebSocketWait{Regex: "^welcome Bearer t0k3n$"}},
+		WebSocketMessage{
+			Send:           `{"type":"quote","symbol":"{{login.symbol}}","price":12.5}`,
+			WaitFor:        &WebSocketWait{JMESPath: "type == 'quote'", Timeout: "1s"},
+			ResponseValues: []ResponseValue{{ExtractionKey: "price", Value: "price"}},
+		},
+		WebSocketMessage{Send: "bye"},
+	)
+	perfStats := &perfTestUtils.PerfStats{}
+	serviceTimes, err := testDefinition.runWebSocket(config, perfStats, jar, "http", host, port, "wsUser1Iter0")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 3, len(serviceTimes))
+	assert.Equal(t, []string{"quotes.connect", "quotes.welcome", "quotes"}, testDefinition.serviceNames())
+	for i, name := range testDefinition.serviceNames() {
+		assert.Equal(t, name, serviceTimes[i].name)
+		assert.True(t, serviceTimes[i].responseTime > 0)
+	}
+
+	// Values are extracted from the message waited for, and cookies of the
+	// handshake are kept.
+	mu.Lock()
+	assert.Equal(t, 12.5, globalsMap["wsUser1Iter0"]["quotes.price"])
+	mu.Unlock()
+	u, _ := url.Parse(server.URL)
+	assert.Equal(t, "abc123", jar.Cookies(u)[0].Value)
+	assert.Equal(t, uint64(1), perfStats.OverAllThinkCount)
+}
+
+func TestRunWebSocketFailures(t *testing.T) {
+	server := echoWebSocketServer()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+
+	// A message that never arrives times out.
+	testDefinition := webSocketTestDefinition(
+		WebSocketMessage{Name: "price", Send: "subscribe", WaitFor: &WebSocketWait{Regex: "^price", Timeout: "50ms"}},
+	)
+	start := time.Now()
+	serviceTimes, err := testDefinition.runWebSocket(config, &perfTestUtils.PerfStats{}, nil, "http", host, port, "")
+	assert.True(t, isTimeout(err))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, 2, len(serviceTimes))
+	assert.Equal(t, serviceTime{name: "quotes.price"}, serviceTimes[1])
+
+	// An invalid condition fails the message.
+	testDefinition = webSocketTestDefinition(WebSocketMessage{WaitFor: &WebSocketWait{JMESPath: "type =="}})
+	serviceTimes, err = testDefinition.runWebSocket(config, &perfTestUtils.PerfStats{}, nil, "http", host, port, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, serviceTime{name: "quotes"}, serviceTimes[1])
+
+	// A failed connection fails the connect service.
+	server.Close()
+	serviceTimes, err = testDefinition.runWebSocket(config, &perfTestUtils.PerfStats{}, nil, "http", host, port, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, []serviceTime{{name: "quotes.connect"}}, serviceTimes)
+
+	// So does a server that does not switch protocols.
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	host, port, _ = net.SplitHostPort(plain.Listener.Addr().String())
+	serviceTimes, err = testDefinition.runWebSocket(config, &perfTestUtils.PerfStats{}, nil, "http", host, port, "")
+	assert.EqualError(t, err, "unexpected status code 200")
+	assert.Equal(t, []serviceTime{{name: "quotes.connect"}}, serviceTimes)
+}
+
+func TestWebSocketFrames(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+	client := &webSocketConn{conn: clientConn, reader: bufio.NewReader(clientConn), masked: true}
+	server := &webSocketConn{conn: serverConn, reader: bufio.NewReader(serverConn)}
+
+	// Payload lengths of each of the three length encodings.
+	for _, length := range []int{0, 125, 126, 65535, 70000} {
+		payload := make([]byte, length)
+		for i := range payload {
+			payload[i] = byte(i)
+		}
+		go client.writeMessage(webSocketBinary, payload)
+		opcode, message, err := server.readMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, byte(webSocketBinary), opcode)
+		assert.Equal(t, payload, message)
+	}
+
+	// A close frame ends the connection, and is sent back.
+	go func() {
+		server.close()
+		server.readFrame()
+	}()
+	_, _, err := client.readMessage()
+	assert.Equal(t, errWebSocketClosed, err)
+}
+
+func TestWebSocketWaitMatcher(t *testing.T) {
+	matches, err := (&WebSocketWait{JMESPath: "items"}).matcher()
+	assert.Nil(t, err)
+	assert.True(t, matches([]byte(`{"items":[1]}`)))
+	assert.False(t, matches([]byte(`{"items":[]}`)))
+	assert.False(t, matches([]byte(`not json`)))
+
+	matches, err = (&WebSocketWait{}).matcher()
+	assert.Nil(t, err)
+	assert.True(t, matches([]byte(`anything`)))
+
+	_, err = (&WebSocketWait{Regex: "("}).matcher()
+	assert.NotNil(t, err)
+	assert.Equal(t, defaultWebSocketTimeout, (&WebSocketWait{Timeout: "soon"}).timeout())
+}
+
+func TestExecuteTestSuiteIterationWebSocket(t *testing.T) {
+	server := echoWebSocketServer()
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	testSuite := &TestSuite{TestDefinitions: []*TestDefinition{webSocketTestDefinition(
+		WebSocketMessage{Name: "echo", Send: "hello", WaitFor: &WebSocketWait{Regex: "^hello$"}},
+		WebSocketMessage{Name: "missing", WaitFor: &WebSocketWait{Regex: "^never$", Timeout: "20ms"}},
+	)}}
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+
+	responseTimes := executeTestSuiteIteration(testSuite, config, 1, 0, perfStats)
+	assert.True(t, responseTimes["quotes.connect"][0] > 0)
+	assert.True(t, responseTimes["quotes.echo"][0] > 0)
+	assert.Equal(t, []int64{0}, responseTimes["quotes.missing"])
+	assert.Equal(t, uint64(3), perfStats.OverAllTransCount)
+	assert.Equal(t, uint64(1), perfStats.OverAllErrorCount)
+	assert.Equal(t, uint64(1), *perfStats.ServiceErrorCount["quotes.missing"])
+	assert.Equal(t, uint64(1), *perfStats.ServiceTimeoutCount["quotes.missing"])
+	assert.Equal(t, map[string]float64{"quotes.connect": 1, "quotes.echo": 1, "quotes.missing": 1}, testSuite.ConfiguredMix())
+}
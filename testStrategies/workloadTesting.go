@@ -0,0 +1,120 @@
+package testStrategies
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"sync"
+	"time"
+)
+
+// MixedWorkloadSuiteName is the name of the test suite that groups the
+// suites of a mixed workload.
+const MixedWorkloadSuiteName = "MixedWorkload"
+
+// Workload is a test suite run as part of a mixed workload, with the
+// configuration settings of that workload.
+type Workload struct {
+	Name                  string
+	TestSuite             *TestSuite
+	ConfigurationSettings *perfTestUtils.Config
+}
+
+// buildWorkloads builds the test suite of every workload. The test
+// definitions of ts are the distinct services of all the workloads, so
+// that the base statistics cover every service exactly once.
+func (ts *TestSuite) buildWorkloads(configurationSettings *perfTestUtils.Config) {
+	ts.Name = MixedWorkloadSuiteName
+	ts.TestStrategy = SuiteBasedTesting
+
+	services := make(map[string]bool)
+	for _, workload := range configurationSettings.Workloads {
+		workloadSettings := configurationSettings.WorkloadConfig(workload)
+		suite := new(TestSuite)
+		suite.BuildTestSuite(workloadSettings)
+		ts.Workloads = append(ts.Workloads, &Workload{
+			Name:                  workload.Name,
+			TestSuite:             suite,
+			ConfigurationSettings: workloadSettings,
+		})
+
+		for _, testDefinition := range suite.TestDefinitions {
+			if !services[testDefinition.TestName] {
+				services[testDefinition.TestName] = true
+				ts.TestDefinitions = append(ts.TestDefinitions, testDefinition)
+			}
+		}
+	}
+}
+
+//----- ExecuteWorkloads ------------------------------------------------------
+// Run the suites of a mixed workload at the same time, each with the users,
+// run length and ramp settings of its workload, and return the response
+// times of every service across all workloads. All workloads update the
+// counters of perfStatsForTest. The results of each workload are also added
+// to perfStatsForTest.Workloads.
+func ExecuteWorkloads(
+	testSuite *TestSuite,
+	configSettings *perfTestUtils.Config,
+	perfStatsForTest *perfTestUtils.PerfStats,
+	scenarioTimeStart time.Time,
+	mode int,
+) map[string][]int64 {
+	allServicesResponseTimesMap := make(map[string][]int64)
+	perfStatsForTest.Workloads = make([]*perfTestUtils.WorkloadStats, 0, len(testSuite.Workloads))
+
+	// Display the ongoing TPS of all workloads together.
+	quitShowTPSChan := make(chan bool)
+	go showCurrentTPS(quitShowTPSChan, configSettings, scenarioTimeStart, &perfStatsForTest.OverAllTransCount)
+
+	var workloadWaitGroup sync.WaitGroup
+	for _, workload := range testSuite.Workloads {
+		workloadStats := perfTestUtils.NewWorkloadStats(
+			workload.Name,
+			workload.ConfigurationSettings.TestSuite,
+			workload.ConfigurationSettings.ConcurrentUsers,
+		)
+		workloadStats.ServiceConfiguredMix = workload.TestSuite.ConfiguredMix()
+		workload.TestSuite.workloadStats = workloadStats
+		perfStatsForTest.Workloads = append(perfStatsForTest.Workloads, workloadStats)
+
+		workloadWaitGroup.Add(1)
+		go func(workload *Workload, workloadStats *perfTestUtils.WorkloadStats) {
+			defer workloadWaitGroup.Done()
+			log.Infof("Workload [%s] started. Suite=[%s] Users=[%d]",
+				workload.Name,
+				workload.TestSuite.Name,
+				workload.ConfigurationSettings.ConcurrentUsers,
+			)
+
+			responseTimes := ExecuteTestSuiteWrapper(workload.TestSuite, workload.ConfigurationSettings, perfStatsForTest, scenarioTimeStart)
+
+			for serviceName, serviceResponseTimes := range responseTimes {
+				workloadStats.ServiceResponseTimes[serviceName] = perfTestUtils.CalcAverageResponseTime(serviceResponseTimes, mode)
+				mu.Lock()
+				allServicesResponseTimesMap[serviceName] = append(allServicesResponseTimesMap[serviceName], serviceResponseTimes...)
+				mu.Unlock()
+			}
+			log.Infof("Workload [%s] finished. Iterations=[%d] Trans=[%d] Errors=[%d]",
+				workload.Name,
+				workloadStats.IterationCount,
+				workloadStats.TransCount,
+				workloadStats.ErrorCount,
+			)
+		}(workload, workloadStats)
+	}
+	workloadWaitGroup.Wait()
+	quitShowTPSChan <- true
+
+	return allServicesResponseTimesMap
+}
+
+// runID returns the unique ID of a run of the suite, such as an iteration or
+// the setup of a virtual user. The suites of a mixed workload run at the same
+// time with the same user IDs, so their IDs start with the workload name to
+// keep the values extracted by each workload apart.
+func (ts *TestSuite) runID(id string) string {
+	if ts.workloadStats != nil {
+		return ts.workloadStats.Name + "/" + id
+	}
+	return id
+}
@@ -0,0 +1,95 @@
+package testStrategies
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xtracdev/automated-perf-test/perfTestUtils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeWorkloadFile(t *testing.T, dir string, name string, content string) {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	assert.Nil(t, err)
+}
+
+func TestExecuteWorkloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	dir, err := ioutil.TempDir("", "workloads")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for _, service := range []string{"search", "checkout"} {
+		writeWorkloadFile(t, dir, service+".xml", `<testDefinition>
+    <testName>`+service+`</testName>
+    <httpMethod>GET</httpMethod>
+    <baseUri>/`+service+`</baseUri>
+    <responseStatusCode>200</responseStatusCode>
+</testDefinition>`)
+	}
+	writeWorkloadFile(t, dir, "browse-suite.xml", `<testSuite><name>browse</name><testCases><testCase>search.xml</testCase></testCases></testSuite>`)
+	writeWorkloadFile(t, dir, "buy-suite.xml", `<testSuite><name>buy</name><testCases><testCase>search.xml</testCase><testCase>checkout.xml</testCase></testCases></testSuite>`)
+
+	config := &perfTestUtils.Config{}
+	config.SetDefaults()
+	config.TargetHost = host
+	config.TargetPort = port
+	config.TestCaseDir = dir
+	config.TestSuiteDir = dir
+	config.Workloads = []perfTestUtils.Workload{
+		{Name: "browse", TestSuite: "browse-suite.xml", Users: 3, Iterations: 4},
+		{Name: "buy", TestSuite: "buy-suite.xml", Users: 1, Iterations: 2},
+	}
+
+	ts := new(TestSuite)
+	ts.BuildTestSuite(config)
+	assert.Equal(t, MixedWorkloadSuiteName, ts.Name)
+	assert.Equal(t, SuiteBasedTesting, ts.TestStrategy)
+	assert.Equal(t, 2, len(ts.Workloads))
+	// The distinct services of all workloads.
+	assert.Equal(t, 2, len(ts.TestDefinitions))
+
+	perfStats := &perfTestUtils.PerfStats{
+		ServiceTransCount: make(map[string]*uint64),
+		ServiceErrorCount: make(map[string]*uint64),
+	}
+	responseTimes := ExecuteWorkloads(ts, config, perfStats, time.Now(), 2)
+
+	assert.Equal(t, 12+2, len(responseTimes["search"]))
+	assert.Equal(t, 2, len(responseTimes["checkout"]))
+	assert.Equal(t, uint64(16), perfStats.OverAllTransCount)
+	assert.Equal(t, uint64(14), perfStats.IterationCount)
+
+	assert.Equal(t, 2, len(perfStats.Workloads))
+	browse, buy := perfStats.Workloads[0], perfStats.Workloads[1]
+	assert.Equal(t, "browse", browse.Name)
+	assert.Equal(t, 3, browse.ConcurrentUsers)
+	assert.Equal(t, uint64(12), browse.IterationCount)
+	assert.Equal(t, uint64(12), browse.TransCount)
+	assert.Equal(t, uint64(2), buy.IterationCount)
+	assert.Equal(t, uint64(4), buy.TransCount)
+	assert.Equal(t, uint64(2), *buy.ServiceTransCount["checkout"])
+	assert.True(t, buy.ServiceResponseTimes["checkout"] > 0)
+	assert.Equal(t, "100.0% / 100.0%", buy.GetServiceMix("checkout"))
+	assert.Equal(t, uint64(0), perfStats.OverAllErrorCount)
+}
+
+func TestWorkloadRunIDs(t *testing.T) {
+	browse := &TestSuite{workloadStats: perfTestUtils.NewWorkloadStats("browse", "browse-suite.xml", 2)}
+	buy := &TestSuite{workloadStats: perfTestUtils.NewWorkloadStats("buy", "buy-suite.xml", 2)}
+
+	// The workloads run with the same user IDs, but their values are kept
+	// apart.
+	assert.Equal(t, "browse/User0Iter3", browse.runID("User0Iter3"))
+	assert.Equal(t, "buy/User0Iter3", buy.runID("User0Iter3"))
+	assert.Equal(t, "User0Iter3", new(TestSuite).runID("User0Iter3"))
+}